@@ -0,0 +1,276 @@
+package cleanpage
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Limits on what Archive inlines. Larger resources keep their remote URL.
+const (
+	maxArchiveResource = 10 << 20
+	maxArchiveSize     = 50 << 20
+	// maxImportDepth limits how deeply @import rules are followed
+	maxImportDepth = 5
+)
+
+var (
+	cssURLRe    = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^)'"]*))\s*\)`)
+	cssImportRe = regexp.MustCompile(`@import\s+(?:url\(\s*)?["']?([^"')\s;]+)["']?\s*\)?\s*([^;]*);`)
+)
+
+// Archive turns the page an article was taken from into a single HTML file
+// that shows it without a network connection. Stylesheets, images and
+// icons are downloaded and inlined, scripts are removed, and the remaining
+// links are made absolute. Resources that cannot be downloaded keep their
+// absolute URL. Articles from documents that are not webpages, such as
+// PDFs, have no page to archive.
+func Archive(ctx context.Context, article *Article, opts Options) (string, error) {
+	if article.Page == "" {
+		return "", fmt.Errorf("%s is not a webpage", article.URL)
+	}
+	base, err := url.Parse(article.URL)
+	if err != nil {
+		return "", err
+	}
+	doc, err := html.Parse(strings.NewReader(article.Page))
+	if err != nil {
+		return "", err
+	}
+	resources, err := NewResources(ctx, article.URL, opts)
+	if err != nil {
+		return "", err
+	}
+
+	a := &archiver{resources: resources, cache: map[string]string{}}
+	a.node(doc, base)
+	if head := findElement(doc, "head"); head != nil {
+		// The page is written out as UTF-8 whatever it declared
+		head.InsertBefore(&html.Node{
+			Type:     html.ElementNode,
+			Data:     "meta",
+			DataAtom: atom.Meta,
+			Attr:     []html.Attribute{{Key: "charset", Val: "utf-8"}},
+		}, head.FirstChild)
+	}
+	if root := findElement(doc, "html"); root != nil {
+		doc.InsertBefore(&html.Node{Type: html.CommentNode, Data: " Archived from " + article.URL + " "}, root)
+	}
+
+	return renderHTML(doc)
+}
+
+// archiver downloads the resources of a page being archived
+type archiver struct {
+	resources *Resources
+	// cache maps resource URLs to what they were inlined as
+	cache map[string]string
+	// size is the number of bytes downloaded so far
+	size int
+}
+
+// node archives n and its children
+func (a *archiver) node(n *html.Node, base *url.URL) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && a.drop(c) {
+			n.RemoveChild(c)
+		} else {
+			a.node(c, base)
+		}
+		c = next
+	}
+	if n.Type != html.ElementNode {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Link:
+		rel := strings.Fields(strings.ToLower(attr(n, "rel")))
+		switch {
+		case slices.Contains(rel, "stylesheet"):
+			if css, ok := a.fetchCSS(resolve(base, attr(n, "href")), 0); ok {
+				style := &html.Node{Type: html.ElementNode, Data: "style", DataAtom: atom.Style}
+				if media := attr(n, "media"); media != "" {
+					style.Attr = []html.Attribute{{Key: "media", Val: media}}
+				}
+				style.AppendChild(&html.Node{Type: html.TextNode, Data: css})
+				n.Parent.InsertBefore(style, n)
+				n.Parent.RemoveChild(n)
+				return
+			}
+		case slices.Contains(rel, "icon"):
+			setAttr(n, "href", a.dataURL(resolve(base, attr(n, "href"))))
+		}
+	case atom.Style:
+		if n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
+			n.FirstChild.Data = a.css(n.FirstChild.Data, base, 0)
+		}
+	case atom.Img:
+		src := attr(n, "src")
+		if src == "" || strings.HasPrefix(src, "data:") {
+			// Lazily loaded images keep their source elsewhere until a
+			// script swaps it in
+			if lazy := attr(n, "data-src"); lazy != "" {
+				src = lazy
+			}
+		}
+		if src != "" {
+			setAttr(n, "src", a.dataURL(resolve(base, src)))
+		}
+		removeAttrs(n, "srcset", "sizes", "loading", "data-src", "data-srcset")
+	}
+
+	for i, at := range n.Attr {
+		switch {
+		case at.Key == "style":
+			n.Attr[i].Val = a.css(at.Val, base, 0)
+		case at.Key == "integrity" || at.Key == "crossorigin":
+			n.Attr[i].Key = ""
+		case strings.HasPrefix(at.Key, "on"):
+			// Event handlers have no scripts left to call
+			n.Attr[i].Key = ""
+		case at.Key == "href" || at.Key == "src" || at.Key == "action" || at.Key == "poster":
+			if !strings.HasPrefix(at.Val, "data:") && !strings.HasPrefix(at.Val, "#") {
+				n.Attr[i].Val = resolve(base, at.Val)
+			}
+		}
+	}
+	removeAttrs(n, "")
+}
+
+// drop reports whether an element is left out of the archive: scripts,
+// hints to load things the archive does not need, and what would stop
+// inlined resources from showing
+func (a *archiver) drop(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Script, atom.Base:
+		return true
+	case atom.Meta:
+		equiv := strings.ToLower(attr(n, "http-equiv"))
+		return attr(n, "charset") != "" || equiv == "content-type" || equiv == "content-security-policy" || equiv == "refresh"
+	case atom.Link:
+		rel := strings.Fields(strings.ToLower(attr(n, "rel")))
+		for _, hint := range []string{"preload", "prefetch", "modulepreload", "preconnect", "dns-prefetch", "manifest"} {
+			if slices.Contains(rel, hint) {
+				return true
+			}
+		}
+	case atom.Source:
+		// Pictures fall back to their inlined img
+		return n.Parent != nil && n.Parent.DataAtom == atom.Picture
+	}
+	return false
+}
+
+// css inlines the imports and url() references of a stylesheet found at
+// base
+func (a *archiver) css(css string, base *url.URL, depth int) string {
+	css = cssImportRe.ReplaceAllStringFunc(css, func(rule string) string {
+		m := cssImportRe.FindStringSubmatch(rule)
+		imported, ok := a.fetchCSS(resolve(base, m[1]), depth+1)
+		if !ok {
+			return rule
+		}
+		if media := strings.TrimSpace(m[2]); media != "" {
+			return "@media " + media + " {\n" + imported + "\n}"
+		}
+		return imported
+	})
+	return cssURLRe.ReplaceAllStringFunc(css, func(ref string) string {
+		m := cssURLRe.FindStringSubmatch(ref)
+		target := strings.TrimSpace(m[1] + m[2] + m[3])
+		if target == "" || strings.HasPrefix(target, "data:") || strings.HasPrefix(target, "#") {
+			return ref
+		}
+		return `url("` + a.dataURL(resolve(base, target)) + `")`
+	})
+}
+
+// fetchCSS downloads a stylesheet and inlines what it references
+func (a *archiver) fetchCSS(u string, depth int) (string, bool) {
+	if depth > maxImportDepth {
+		return "", false
+	}
+	data, _, err := a.fetch(u)
+	if err != nil {
+		return "", false
+	}
+	base, err := url.Parse(u)
+	if err != nil {
+		return "", false
+	}
+	return a.css(string(data), base, depth), true
+}
+
+// dataURL returns the resource at u as a data: URL, or u when it cannot
+// be downloaded
+func (a *archiver) dataURL(u string) string {
+	if inlined, ok := a.cache[u]; ok {
+		return inlined
+	}
+	inlined := u
+	if data, mediaType, err := a.fetch(u); err == nil {
+		inlined = "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	}
+	a.cache[u] = inlined
+	return inlined
+}
+
+// fetch downloads a resource of the page and returns it with its media
+// type
+func (a *archiver) fetch(u string) ([]byte, string, error) {
+	if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		return nil, "", fmt.Errorf("cannot download %s", u)
+	}
+	if a.size >= maxArchiveSize {
+		return nil, "", fmt.Errorf("archive is larger than %d bytes", maxArchiveSize)
+	}
+
+	data, mediaType, err := a.resources.Get(u, maxArchiveResource)
+	if err != nil {
+		return nil, "", err
+	}
+	if a.size+len(data) > maxArchiveSize {
+		return nil, "", fmt.Errorf("%s is too large to archive", u)
+	}
+	a.size += len(data)
+	return data, mediaType, nil
+}
+
+// resolve makes ref absolute against base
+func resolve(base *url.URL, ref string) string {
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+// removeAttrs removes the attributes named keys from n
+func removeAttrs(n *html.Node, keys ...string) {
+	attrs := n.Attr[:0]
+	for _, a := range n.Attr {
+		if !slices.Contains(keys, a.Key) {
+			attrs = append(attrs, a)
+		}
+	}
+	n.Attr = attrs
+}
@@ -0,0 +1,81 @@
+package cleanpage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestArchive(t *testing.T) {
+	pixel := []byte("\x89PNG\r\n\x1a\nfake image data")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/post/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="iso-8859-1">
+<title>A Post</title>
+<link rel="stylesheet" href="../css/site.css">
+<link rel="preload" href="/font.woff2">
+<script src="/app.js"></script>
+</head>
+<body onload="start()">
+<h1 style="background: url('/img/bg.png')">A Post</h1>
+<picture><source srcset="/img/photo.webp"><img src="/img/photo.png" srcset="/img/photo-2x.png 2x" alt="Photo"></picture>
+<img src="/img/gone.png" alt="Gone">
+<p>Read <a href="other">the other post</a> or <a href="#top">go up</a>.</p>
+<script>track()</script>
+</body>
+</html>`))
+	})
+	mux.HandleFunc("/css/site.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		w.Write([]byte(`@import "print.css" print;
+body { background: url(../img/bg.png) }`))
+	})
+	mux.HandleFunc("/css/print.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		w.Write([]byte(`h1 { color: black }`))
+	})
+	serveImage := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pixel)
+	}
+	mux.HandleFunc("/img/bg.png", serveImage)
+	mux.HandleFunc("/img/photo.png", serveImage)
+	mux.HandleFunc("/img/gone.png", http.NotFound)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	article, err := Fetch(context.Background(), ts.URL+"/post/", Options{Strategy: StrategyHTTP})
+	if err != nil {
+		t.Fatal(err)
+	}
+	archived, err := Archive(context.Background(), article, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"<!-- Archived from " + ts.URL + "/post/ -->",
+		`<meta charset="utf-8"/>`,
+		"<style>@media print {\nh1 { color: black }\n}\nbody { background: url(\"data:image/png;base64,",
+		`<h1 style="background: url(&#34;data:image/png;base64,`,
+		`<picture><img src="data:image/png;base64,`,
+		`<img src="` + ts.URL + `/img/gone.png" alt="Gone"/>`,
+		`<a href="` + ts.URL + `/post/other">`,
+		`<a href="#top">`,
+	} {
+		if !strings.Contains(archived, want) {
+			t.Errorf("archive does not contain %q:\n%s", want, archived)
+		}
+	}
+	for _, unwanted := range []string{"<script", "iso-8859-1", "preload", "onload", "srcset", "photo.webp", "site.css"} {
+		if strings.Contains(archived, unwanted) {
+			t.Errorf("archive contains %q:\n%s", unwanted, archived)
+		}
+	}
+}
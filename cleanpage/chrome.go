@@ -0,0 +1,227 @@
+package cleanpage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// ErrNoChrome is returned when a page needs Chrome and it is not installed
+var ErrNoChrome = errors.New("chrome is not installed")
+
+// chromePath returns the Chrome or Chromium executable, or "" when none is
+// installed. It looks where chromedp does, so that Fetch can skip starting
+// a browser that is not there.
+func chromePath() string {
+	var locations []string
+	switch runtime.GOOS {
+	case "darwin":
+		locations = []string{
+			"/Applications/Chromium.app/Contents/MacOS/Chromium",
+			"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+		}
+	case "windows":
+		locations = []string{
+			"chrome",
+			"chrome.exe",
+			`C:\Program Files (x86)\Google\Chrome\Application\chrome.exe`,
+			`C:\Program Files\Google\Chrome\Application\chrome.exe`,
+			filepath.Join(os.Getenv("USERPROFILE"), `AppData\Local\Google\Chrome\Application\chrome.exe`),
+			filepath.Join(os.Getenv("USERPROFILE"), `AppData\Local\Chromium\Application\chrome.exe`),
+		}
+	default:
+		locations = []string{
+			"headless_shell",
+			"headless-shell",
+			"chromium",
+			"chromium-browser",
+			"google-chrome",
+			"google-chrome-stable",
+			"google-chrome-beta",
+			"google-chrome-unstable",
+			"/usr/bin/google-chrome",
+			"/usr/local/bin/chrome",
+			"/snap/bin/chromium",
+			"chrome",
+		}
+	}
+
+	for _, path := range locations {
+		if found, err := exec.LookPath(path); err == nil {
+			return found
+		}
+	}
+	return ""
+}
+
+// HasChrome reports whether Chrome or Chromium is installed
+func HasChrome() bool {
+	return chromePath() != ""
+}
+
+// errNotHTML is returned when Chrome is asked for a document that is not a
+// webpage, which Fetch downloads with HTTP instead
+var errNotHTML = errors.New("not a webpage")
+
+func downloadWithChrome(ctx context.Context, urlStr string, opts Options) (*download, error) {
+	var html, location string
+	var mu sync.Mutex
+	var mimeType string
+	// Chrome shows PDFs and plain text as pages of its own, so the type of
+	// the document is taken from its response
+	listen := func(ev any) {
+		if resp, ok := ev.(*network.EventResponseReceived); ok && resp.Type == network.ResourceTypeDocument {
+			mu.Lock()
+			mimeType = resp.Response.MimeType
+			mu.Unlock()
+		}
+	}
+	err := runChrome(ctx, urlStr, opts, listen,
+		// Extract the HTML and where redirects led
+		chromedp.OuterHTML("html", &html),
+		chromedp.Location(&location),
+	)
+	mu.Lock()
+	defer mu.Unlock()
+	if mimeType != "" && mimeType != typeHTML && mimeType != "application/xhtml+xml" {
+		return nil, errNotHTML
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &download{
+		body:        []byte(html),
+		contentType: "text/html; charset=utf-8",
+		url:         location,
+	}, nil
+}
+
+// Screenshot loads a page in headless Chrome and captures all of it, not
+// only the part in view, as a PNG image. It returns ErrNoChrome when Chrome
+// is not installed.
+func Screenshot(ctx context.Context, urlStr string, opts Options) ([]byte, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultTimeout
+	}
+	var png []byte
+	if err := runChrome(ctx, urlStr, opts, nil, chromedp.FullScreenshot(&png, 100)); err != nil {
+		return nil, err
+	}
+	return png, nil
+}
+
+// runChrome loads a page in headless Chrome as opts ask and runs actions
+// on it once it is ready. listen, when not nil, is called with the events
+// of the page.
+func runChrome(ctx context.Context, urlStr string, opts Options, listen func(ev any), actions ...chromedp.Action) error {
+	path := chromePath()
+	if path == "" {
+		return ErrNoChrome
+	}
+
+	// Create context
+	allocOpts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.ExecPath(path))
+	if opts.UserAgent != "" {
+		allocOpts = append(allocOpts, chromedp.UserAgent(opts.UserAgent))
+	}
+	if opts.Proxy != "" {
+		allocOpts = append(allocOpts, chromedp.ProxyServer(opts.Proxy))
+	}
+	ctx, cancel := chromedp.NewExecAllocator(ctx, allocOpts...)
+	defer cancel()
+	ctx, cancel = chromedp.NewContext(ctx)
+	defer cancel()
+	if listen != nil {
+		chromedp.ListenTarget(ctx, listen)
+	}
+	page, err := url.Parse(urlStr)
+	if err != nil {
+		return err
+	}
+	if len(opts.Header) > 0 {
+		// Requests are paused so that the extra headers are only added to
+		// those for the page's host
+		chromedp.ListenTarget(ctx, func(ev any) {
+			paused, ok := ev.(*fetch.EventRequestPaused)
+			if !ok {
+				return
+			}
+			go func() {
+				c := chromedp.FromContext(ctx)
+				cont := fetch.ContinueRequest(paused.RequestID)
+				if headers := chromeHeaders(paused.Request, page, opts); headers != nil {
+					cont = cont.WithHeaders(headers)
+				}
+				cont.Do(cdp.WithExecutor(ctx, c.Target))
+			}()
+		})
+	}
+
+	// Create a timeout
+	ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	return chromedp.Run(ctx, append([]chromedp.Action{
+		// Send the extra headers and cookies
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			if len(opts.Header) > 0 {
+				if err := fetch.Enable().Do(ctx); err != nil {
+					return err
+				}
+			}
+			for _, cookie := range opts.Cookies {
+				set := network.SetCookie(cookie.Name, cookie.Value).
+					WithDomain(cookie.Domain).
+					WithPath(cookie.Path).
+					WithSecure(cookie.Secure).
+					WithHTTPOnly(cookie.HttpOnly)
+				if !cookie.Expires.IsZero() {
+					expires := cdp.TimeSinceEpoch(cookie.Expires)
+					set = set.WithExpires(&expires)
+				}
+				if err := set.Do(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+		// Navigate to the page
+		chromedp.Navigate(urlStr),
+		// Wait for the page to load
+		chromedp.WaitReady("body"),
+	}, actions...)...)
+}
+
+// chromeHeaders returns the headers of a request Chrome paused with the
+// extra headers of opts added, or nil when the request is not for the host
+// of page and goes on unchanged
+func chromeHeaders(req *network.Request, page *url.URL, opts Options) []*fetch.HeaderEntry {
+	u, err := url.Parse(req.URL)
+	if err != nil || !sameHost(u, page) {
+		return nil
+	}
+	var headers []*fetch.HeaderEntry
+	for name, value := range req.Headers {
+		if opts.Header.Get(name) == "" {
+			headers = append(headers, &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(value)})
+		}
+	}
+	for name, values := range opts.Header {
+		for _, value := range values {
+			headers = append(headers, &fetch.HeaderEntry{Name: name, Value: value})
+		}
+	}
+	return headers
+}
@@ -0,0 +1,30 @@
+package cleanpage
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestChromeHeaders(t *testing.T) {
+	page, _ := url.Parse("https://intranet.example.com/wiki/page")
+	opts := Options{Header: http.Header{"Authorization": {"Bearer secret"}}}
+
+	got := chromeHeaders(&network.Request{
+		URL:     "https://intranet.example.com/style.css",
+		Headers: network.Headers{"Accept": "text/css", "authorization": "old"},
+	}, page, opts)
+	headers := map[string]string{}
+	for _, h := range got {
+		headers[h.Name] = h.Value
+	}
+	if len(headers) != 2 || headers["Accept"] != "text/css" || headers["Authorization"] != "Bearer secret" {
+		t.Errorf("same host headers = %v", headers)
+	}
+
+	if got := chromeHeaders(&network.Request{URL: "https://cdn.example.net/logo.png"}, page, opts); got != nil {
+		t.Errorf("another host got headers %v, want the request unchanged", got)
+	}
+}
@@ -2,86 +2,353 @@ package cleanpage
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"strings"
 	"time"
 
-	"github.com/chromedp/chromedp"
 	"github.com/go-shiori/go-readability"
+	"golang.org/x/net/html"
 )
 
-// CrawlPage downloads a webpage and extracts its main content
+// Article is the main content of a webpage and what is known about it
+type Article struct {
+	Title    string
+	Byline   string
+	SiteName string
+	Excerpt  string
+	// Published is nil when the page does not say
+	Published *time.Time
+	// Image is the URL of the lead image
+	Image    string
+	Language string
+
+	// HTML is the cleaned up article content
+	HTML string
+	// Markdown is the content converted to markdown
+	Markdown string
+	// Text is the content as plain text with whitespace normalized
+	Text string
+
+	// Page is the whole page as downloaded, which Archive saves. It is
+	// empty for documents that are not webpages.
+	Page string
+	// ContentType is the media type of the document: text/html,
+	// application/pdf, text/plain or text/markdown
+	ContentType string
+
+	// URL is where the page was found after following redirects
+	URL string
+	// Canonical is the page's preferred URL from its canonical link, if it
+	// has one
+	Canonical string
+}
+
+// Strategies for downloading pages
+const (
+	// StrategyAuto loads pages in Chrome when it is installed and falls
+	// back to HTTP when it is not or fails
+	StrategyAuto = "auto"
+	// StrategyChrome only loads pages in Chrome, so scripts can render
+	// them
+	StrategyChrome = "chrome"
+	// StrategyHTTP only downloads pages with plain HTTP requests
+	StrategyHTTP = "http"
+)
+
+// Strategies lists the download strategies
+var Strategies = []string{StrategyAuto, StrategyChrome, StrategyHTTP}
+
+// Options controls how Fetch downloads a page
+type Options struct {
+	// Strategy is one of the Strategy constants; empty means StrategyAuto
+	Strategy string
+	// Timeout limits each download attempt; zero means 30 seconds
+	Timeout time.Duration
+	// UserAgent replaces the default user agent when set
+	UserAgent string
+	// Proxy is the URL of the proxy to use. Without it, plain HTTP
+	// requests use the proxy set in the environment.
+	Proxy string
+	// Header holds extra request headers. They are only sent to the host
+	// of the page, never to the other sites its resources come from.
+	Header http.Header
+	// Cookies are sent to the sites whose domain they name
+	Cookies []*http.Cookie
+	// Wait, when set, is called with the URL of each resource of a page,
+	// such as the images and stylesheets Archive inlines, before it is
+	// downloaded, so that callers can pace their requests to a site. It
+	// is not called for the pages themselves, nor for what Chrome loads.
+	Wait func(url string)
+
+	// Rules pick the content of pages on the sites they name, by where
+	// the page was found after redirects
+	Rules Rules
+	// Selector picks the content of the page instead of readability,
+	// replacing the include selectors of Rules
+	Selector string
+}
+
+const defaultTimeout = 30 * time.Second
+
+// StatusError is returned when the server answers with an error status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP status %d", e.StatusCode)
+}
+
+// CrawlPage downloads a webpage or document and extracts its main content
 func CrawlPage(urlStr string) (string, error) {
-	// Try Chrome first
-	html, err := downloadWithChrome(urlStr)
+	article, err := Fetch(context.Background(), urlStr, Options{})
 	if err != nil {
-		// Fall back to HTTP client
-		html, err = downloadWithHTTP(urlStr)
+		return "", err
+	}
+	return article.Text, nil
+}
+
+// Fetch downloads a webpage and extracts its main content and metadata.
+// By default pages are loaded in headless Chrome when it is installed so
+// scripts can render them, and with a plain HTTP request otherwise;
+// opts.Strategy picks one of the two. PDFs, plain text and markdown are
+// downloaded with HTTP and read as they are. Text in other encodings than
+// UTF-8 is decoded by its declared charset, or a guess when it has none.
+func Fetch(ctx context.Context, urlStr string, opts Options) (*Article, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultTimeout
+	}
+
+	var dl *download
+	var err error
+	switch opts.Strategy {
+	case "", StrategyAuto:
+		err = ErrNoChrome
+		if chromePath() != "" {
+			dl, err = downloadWithChrome(ctx, urlStr, opts)
+		}
 		if err != nil {
-			return "", err
+			// Fall back to HTTP client
+			dl, err = downloadWithHTTP(ctx, urlStr, opts)
 		}
+	case StrategyChrome:
+		dl, err = downloadWithChrome(ctx, urlStr, opts)
+		if errors.Is(err, errNotHTML) {
+			// Chrome shows documents that are not pages without their text
+			dl, err = downloadWithHTTP(ctx, urlStr, opts)
+		}
+	case StrategyHTTP:
+		dl, err = downloadWithHTTP(ctx, urlStr, opts)
+	default:
+		return nil, fmt.Errorf("unknown download strategy %q", opts.Strategy)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, err := contentType(dl)
+	if err != nil {
+		return nil, err
+	}
+	switch mediaType {
+	case typePDF:
+		return pdfArticle(dl.body, dl.url)
+	case typeText, typeMarkdown:
+		return textArticle(decodeText(dl.body, dl.contentType, false), dl.url, mediaType), nil
 	}
+	return htmlArticle(decodeText(dl.body, dl.contentType, true), dl.url, opts)
+}
+
+// Download gets a document with a plain HTTP request as opts ask, without
+// extracting anything from it. It returns the body and the URL redirects
+// led to.
+func Download(ctx context.Context, urlStr string, opts Options) ([]byte, string, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultTimeout
+	}
+	dl, err := downloadWithHTTP(ctx, urlStr, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	return dl.body, dl.url, nil
+}
 
+// htmlArticle extracts the main content of a webpage
+func htmlArticle(html, finalURL string, opts Options) (*Article, error) {
 	// Parse URL string into *url.URL
-	parsedURL, err := url.Parse(urlStr)
+	parsedURL, err := url.Parse(finalURL)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	// Selectors remove parts of the page and may pick the content
+	page, content, err := selectContent(html, parsedURL, opts)
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse the content using go-readability
-	article, err := readability.FromReader(strings.NewReader(html), parsedURL)
+	parsed, err := readability.FromReader(strings.NewReader(page), parsedURL)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Normalize whitespace in the text content
-	fields := strings.Fields(article.TextContent)
-	return strings.Join(fields, " "), nil
+	fields := strings.Fields(parsed.TextContent)
+	article := &Article{
+		Title:       strings.TrimSpace(parsed.Title),
+		Byline:      strings.TrimSpace(parsed.Byline),
+		SiteName:    strings.TrimSpace(parsed.SiteName),
+		Excerpt:     strings.TrimSpace(parsed.Excerpt),
+		Published:   parsed.PublishedTime,
+		Image:       parsed.Image,
+		Language:    parsed.Language,
+		HTML:        parsed.Content,
+		Text:        strings.Join(fields, " "),
+		Page:        html,
+		URL:         finalURL,
+		ContentType: typeHTML,
+	}
+	// Metadata may point at the lead image with a relative URL
+	if image, err := parsedURL.Parse(parsed.Image); err == nil && parsed.Image != "" {
+		article.Image = image.String()
+	}
+	if content != nil {
+		if article.HTML, err = renderHTML(content); err != nil {
+			return nil, err
+		}
+		article.Text = strings.Join(strings.Fields(textContent(content)), " ")
+		article.Markdown = nodeToMarkdown(content)
+	} else if parsed.Node != nil {
+		article.Markdown = nodeToMarkdown(parsed.Node)
+	}
+	article.Canonical = canonicalURL(html, parsedURL)
+	return article, nil
 }
 
-func downloadWithHTTP(urlStr string) (string, error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+// canonicalURL returns the absolute URL of the canonical link of a page,
+// or "" when it has none
+func canonicalURL(page string, base *url.URL) string {
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return ""
+	}
+	var find func(n *html.Node) string
+	find = func(n *html.Node) string {
+		if n.Type == html.ElementNode && n.Data == "link" && strings.EqualFold(attr(n, "rel"), "canonical") {
+			if href, err := base.Parse(strings.TrimSpace(attr(n, "href"))); err == nil && attr(n, "href") != "" {
+				return href.String()
+			}
+		}
+		if n.Type == html.ElementNode && n.Data == "body" {
+			return ""
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if found := find(c); found != "" {
+				return found
+			}
+		}
+		return ""
 	}
+	return find(doc)
+}
 
-	resp, err := client.Get(urlStr)
+func downloadWithHTTP(ctx context.Context, urlStr string, opts Options) (*download, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	client, err := httpClient(opts, req.URL)
+	if err != nil {
+		return nil, err
+	}
+	setHeaders(req, opts, req.URL)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	return &download{
+		body:        body,
+		contentType: resp.Header.Get("Content-Type"),
+		url:         resp.Request.URL.String(),
+	}, nil
+}
+
+// setHeaders sets the user agent of opts on req and, when req is for the
+// host of page, its extra headers
+func setHeaders(req *http.Request, opts Options, page *url.URL) {
+	if sameHost(req.URL, page) {
+		for name, values := range opts.Header {
+			for _, value := range values {
+				req.Header.Add(name, value)
+			}
+		}
+	}
+	if opts.UserAgent != "" {
+		req.Header.Set("User-Agent", opts.UserAgent)
 	}
+}
 
-	return string(body), nil
+// sameHost reports whether u is on the host of page
+func sameHost(u, page *url.URL) bool {
+	return page != nil && strings.EqualFold(u.Host, page.Host)
 }
 
-func downloadWithChrome(urlStr string) (string, error) {
-	// Create context
-	ctx, cancel := chromedp.NewContext(context.Background())
-	defer cancel()
-
-	// Create a timeout
-	ctx, cancel = context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	var html string
-	err := chromedp.Run(ctx,
-		// Navigate to the page
-		chromedp.Navigate(urlStr),
-		// Wait for the page to load
-		chromedp.WaitReady("body"),
-		// Extract the HTML
-		chromedp.OuterHTML("html", &html),
-	)
+// httpClient returns a client that uses the proxy and cookies of opts.
+// Redirects away from the host of page drop the extra headers of opts.
+func httpClient(opts Options, page *url.URL) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.Proxy != "" {
+		proxy, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
 
+	jar, err := cookiejar.New(nil)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	for _, cookie := range opts.Cookies {
+		scheme := "http"
+		if cookie.Secure {
+			scheme = "https"
+		}
+		host := strings.TrimPrefix(cookie.Domain, ".")
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: host, Path: cookie.Path}, []*http.Cookie{cookie})
 	}
 
-	return html, nil
+	return &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: transport,
+		Jar:       jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if !sameHost(req.URL, page) {
+				for name := range opts.Header {
+					req.Header.Del(name)
+				}
+			}
+			return nil
+		},
+	}, nil
 }
@@ -1,10 +1,13 @@
 package cleanpage
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestCrawlPage(t *testing.T) {
@@ -70,3 +73,123 @@ func TestCrawlPage(t *testing.T) {
 		})
 	}
 }
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/post", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html>
+<html lang="de">
+<head>
+<title>Ein Artikel</title>
+<meta name="author" content="Grace Hopper">
+<meta property="og:site_name" content="Example News">
+<meta property="og:description" content="A short description.">
+<meta property="og:image" content="/lead.jpg">
+<meta property="article:published_time" content="2026-09-30T12:00:00Z">
+<link rel="canonical" href="/articles/ein-artikel">
+</head>
+<body>
+<article>
+<h2>Heading</h2>
+<p>Some <strong>bold</strong> text that is long enough to be kept as the content of the article by readability.</p>
+</article>
+</body>
+</html>`))
+	})
+	mux.Handle("/short", http.RedirectHandler("/post", http.StatusFound))
+	mux.HandleFunc("/missing", http.NotFound)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	article, err := Fetch(context.Background(), ts.URL+"/short", Options{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	checks := []struct {
+		field, got, want string
+	}{
+		{"Title", article.Title, "Ein Artikel"},
+		{"Byline", article.Byline, "Grace Hopper"},
+		{"SiteName", article.SiteName, "Example News"},
+		{"Excerpt", article.Excerpt, "A short description."},
+		{"Image", article.Image, ts.URL + "/lead.jpg"},
+		{"Language", article.Language, "de"},
+		{"URL", article.URL, ts.URL + "/post"},
+		{"Canonical", article.Canonical, ts.URL + "/articles/ein-artikel"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %q, want %q", c.field, c.got, c.want)
+		}
+	}
+	if article.Published == nil || !article.Published.Equal(time.Date(2026, 9, 30, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Published = %v, want 2026-09-30 12:00 UTC", article.Published)
+	}
+	if !strings.Contains(article.HTML, "<strong>bold</strong>") {
+		t.Errorf("HTML does not contain the article: %q", article.HTML)
+	}
+	if !strings.Contains(article.Markdown, "## Heading\n\nSome **bold** text") {
+		t.Errorf("Markdown = %q", article.Markdown)
+	}
+	if !strings.HasPrefix(article.Text, "Heading Some bold text") {
+		t.Errorf("Text = %q", article.Text)
+	}
+
+	var statusErr *StatusError
+	if _, err := Fetch(context.Background(), ts.URL+"/missing", Options{}); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Fetch() of a missing page error = %v, want HTTP status 404", err)
+	}
+}
+
+func TestFetchOptions(t *testing.T) {
+	var got *http.Request
+	page := `<html><head><title>Intranet</title></head><body><article><p>Only signed in users with the right headers and cookies see this text, which is long enough for readability.</p></article></body></html>`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer ts.Close()
+
+	opts := Options{
+		Strategy:  StrategyHTTP,
+		UserAgent: "ned-test/1.0",
+		Header:    http.Header{"X-Team": {"notes"}},
+		Cookies:   []*http.Cookie{{Name: "sso", Value: "xyz", Domain: "127.0.0.1", Path: "/"}},
+	}
+	if _, err := Fetch(context.Background(), ts.URL+"/wiki", opts); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if ua := got.Header.Get("User-Agent"); ua != "ned-test/1.0" {
+		t.Errorf("User-Agent = %q", ua)
+	}
+	if team := got.Header.Get("X-Team"); team != "notes" {
+		t.Errorf("X-Team = %q", team)
+	}
+	if cookie, err := got.Cookie("sso"); err != nil || cookie.Value != "xyz" {
+		t.Errorf("sso cookie = %v, %v", cookie, err)
+	}
+
+	// Requests go to the proxy, which serves the page here
+	opts = Options{Strategy: StrategyHTTP, Proxy: ts.URL}
+	if _, err := Fetch(context.Background(), "http://intranet.invalid/wiki", opts); err != nil {
+		t.Fatalf("Fetch() through a proxy error = %v", err)
+	}
+	if got.URL.Host != "intranet.invalid" {
+		t.Errorf("proxy got a request for %q, want intranet.invalid", got.URL.Host)
+	}
+
+	if _, err := Fetch(context.Background(), ts.URL, Options{Strategy: "wget"}); err == nil {
+		t.Error("Fetch() with an unknown strategy succeeded")
+	}
+	if chromePath() == "" {
+		if _, err := Fetch(context.Background(), ts.URL, Options{Strategy: StrategyChrome}); !errors.Is(err, ErrNoChrome) {
+			t.Errorf("Fetch() with Chrome missing error = %v, want %v", err, ErrNoChrome)
+		}
+		if _, err := Screenshot(context.Background(), ts.URL, Options{}); !errors.Is(err, ErrNoChrome) {
+			t.Errorf("Screenshot() with Chrome missing error = %v, want %v", err, ErrNoChrome)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package cleanpage
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gogs/chardet"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// download is a page as it was downloaded
+type download struct {
+	body []byte
+	// contentType is the Content-Type header, "" when there was none
+	contentType string
+	// url is where the page was found after following redirects
+	url string
+}
+
+// Media types of the content Fetch extracts articles from
+const (
+	typeHTML     = "text/html"
+	typePDF      = "application/pdf"
+	typeText     = "text/plain"
+	typeMarkdown = "text/markdown"
+)
+
+// contentType returns the media type of a download, one of the type
+// constants. The Content-Type header is trusted unless the content says
+// otherwise or it is missing or generic, and markdown served as plain text
+// is recognized by its file extension.
+func contentType(dl *download) (string, error) {
+	header, _, _ := mime.ParseMediaType(dl.contentType)
+	sniffed := mimetype.Detect(dl.body)
+	generic := header == "" || header == "application/octet-stream" || header == "binary/octet-stream"
+
+	switch {
+	case header == typePDF || sniffed.Is(typePDF):
+		return typePDF, nil
+	case header == typeMarkdown || header == "text/x-markdown":
+		return typeMarkdown, nil
+	case (header == typeText || generic) && isMarkdownPath(dl.url) && !sniffed.Is(typeHTML):
+		return typeMarkdown, nil
+	case header == typeHTML || header == "application/xhtml+xml":
+		return typeHTML, nil
+	case header == typeText:
+		return typeText, nil
+	case generic && sniffed.Is(typeHTML):
+		return typeHTML, nil
+	case generic && sniffed.Is(typeText):
+		return typeText, nil
+	}
+	if generic {
+		header = sniffed.String()
+	}
+	return "", fmt.Errorf("unsupported content type %s", header)
+}
+
+func isMarkdownPath(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(path.Ext(u.Path)) {
+	case ".md", ".markdown", ".mdown", ".mkd":
+		return true
+	}
+	return false
+}
+
+// decodeText converts text to UTF-8. The encoding is taken from the charset
+// of the Content-Type header, a byte order mark or, for HTML, a meta tag.
+// Without any of these, valid UTF-8 is kept and other text is given to
+// chardet to guess its encoding.
+func decodeText(body []byte, contentType string, isHTML bool) string {
+	var enc encoding.Encoding
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		enc, _ = htmlindex.Get(params["charset"])
+	}
+	if enc == nil {
+		if e, name, certain := charset.DetermineEncoding(body, ""); certain || (isHTML && metaCharset(body)) {
+			if name == "utf-8" {
+				return string(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")))
+			}
+			enc = e
+		}
+	}
+	if enc == nil {
+		if utf8.Valid(body) {
+			return string(body)
+		}
+		enc = detectEncoding(body, isHTML)
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return string(body)
+	}
+	return string(decoded)
+}
+
+// metaCharset reports whether an HTML page declares its encoding in a meta
+// tag. charset.DetermineEncoding finds these, but only counts the ones in
+// the Content-Type header and byte order marks as certain.
+func metaCharset(body []byte) bool {
+	head := bytes.ToLower(body[:min(len(body), 1024)])
+	return bytes.Contains(head, []byte("charset="))
+}
+
+// chardetLabels maps names chardet uses to encoding labels it does not
+// share with the encoding standard
+var chardetLabels = map[string]string{
+	"GB-18030":     "gb18030",
+	"ISO-8859-8-I": "iso-8859-8-i",
+}
+
+// detectEncoding guesses the encoding of text with chardet, falling back
+// to Windows-1252
+func detectEncoding(body []byte, isHTML bool) encoding.Encoding {
+	detector := chardet.NewTextDetector()
+	if isHTML {
+		detector = chardet.NewHtmlDetector()
+	}
+	if result, err := detector.DetectBest(body); err == nil {
+		name := result.Charset
+		if label, ok := chardetLabels[name]; ok {
+			name = label
+		}
+		if enc, err := htmlindex.Get(name); err == nil {
+			return enc
+		}
+	}
+	enc, _ := htmlindex.Get("windows-1252")
+	return enc
+}
+
+// textArticle makes an article of plain text or markdown. Markdown is kept
+// as it is; plain text is escaped so it reads the same as markdown.
+func textArticle(text, pageURL, mediaType string) *Article {
+	text = strings.TrimPrefix(strings.ReplaceAll(text, "\r\n", "\n"), "\ufeff")
+	article := &Article{
+		Text:        strings.Join(strings.Fields(text), " "),
+		URL:         pageURL,
+		ContentType: mediaType,
+	}
+	if mediaType == typeMarkdown {
+		article.Markdown = strings.TrimSpace(text) + "\n"
+		article.Title = markdownTitle(text)
+	} else {
+		article.Markdown = textMarkdown(strings.Split(text, "\n\n"))
+		article.Title = firstLine(text)
+	}
+	if article.Title == "" {
+		article.Title = fileTitle(pageURL)
+	}
+	return article
+}
+
+// pdfArticle makes an article of the text of a PDF. Its title comes from
+// the document information, or else the first line of text.
+func pdfArticle(body []byte, pageURL string) (*Article, error) {
+	title, pages, err := extractPDF(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	text := strings.Join(pages, "\n\n")
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("PDF has no text, it may be scanned images")
+	}
+	if title == "" {
+		title = firstLine(text)
+	}
+	if title == "" {
+		title = fileTitle(pageURL)
+	}
+	return &Article{
+		Title:       title,
+		Text:        strings.Join(strings.Fields(text), " "),
+		Markdown:    textMarkdown(strings.Split(text, "\n\n")),
+		URL:         pageURL,
+		ContentType: typePDF,
+	}, nil
+}
+
+// textMarkdown turns paragraphs of plain text into markdown, escaping what
+// markdown would read as formatting
+func textMarkdown(paragraphs []string) string {
+	var blocks []string
+	for _, p := range paragraphs {
+		var lines []string
+		for _, line := range strings.Split(p, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, escapeText(line))
+			}
+		}
+		if len(lines) > 0 {
+			blocks = append(blocks, escapeBlockStart(strings.Join(lines, "\n")))
+		}
+	}
+	if len(blocks) == 0 {
+		return ""
+	}
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+// markdownTitle returns the text of the first level one heading
+func markdownTitle(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if title, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok {
+			return strings.TrimSpace(strings.TrimRight(title, "#"))
+		}
+	}
+	return ""
+}
+
+// firstLine returns the first line of text that is not blank, if it is
+// short enough to be a title
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			if utf8.RuneCountInString(line) > 120 {
+				return ""
+			}
+			return line
+		}
+	}
+	return ""
+}
+
+// fileTitle names a document after the file in its URL
+func fileTitle(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return u.Hostname()
+	}
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	return strings.TrimSuffix(name, path.Ext(name))
+}
@@ -0,0 +1,198 @@
+package cleanpage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestContentType(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n1 0 obj\n<< >>\nendobj\n")
+	page := []byte("<!DOCTYPE html><html><head><title>T</title></head><body><p>Hi</p></body></html>")
+	text := []byte("Just some notes.\n")
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		url         string
+		want        string
+	}{
+		{"html", page, "text/html; charset=utf-8", "https://example.com/", typeHTML},
+		{"xhtml", page, "application/xhtml+xml", "https://example.com/", typeHTML},
+		{"pdf", pdf, "application/pdf", "https://example.com/a.pdf", typePDF},
+		{"pdf served as html", pdf, "text/html", "https://example.com/a", typePDF},
+		{"pdf without header", pdf, "", "https://example.com/a", typePDF},
+		{"text", text, "text/plain; charset=utf-8", "https://example.com/a.txt", typeText},
+		{"markdown", text, "text/markdown", "https://example.com/a", typeMarkdown},
+		{"markdown by extension", text, "text/plain", "https://example.com/README.md", typeMarkdown},
+		{"markdown as download", text, "application/octet-stream", "https://example.com/notes.markdown?raw=1", typeMarkdown},
+		{"html without header", page, "", "https://example.com/", typeHTML},
+		{"text without header", text, "application/octet-stream", "https://example.com/a", typeText},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := contentType(&download{body: tt.body, contentType: tt.contentType, url: tt.url})
+			if err != nil || got != tt.want {
+				t.Errorf("contentType() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	if _, err := contentType(&download{body: png, contentType: "image/png"}); err == nil || !strings.Contains(err.Error(), "image/png") {
+		t.Errorf("image: err = %v, want unsupported content type", err)
+	}
+	if _, err := contentType(&download{body: png}); err == nil || !strings.Contains(err.Error(), "image/png") {
+		t.Errorf("image without header: err = %v, want unsupported content type", err)
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	big5 := "<html><head><title>新聞</title></head><body><p>今天天氣很好，我們去公園散步。</p></body></html>"
+	sjis := "今日はとても良い天気です。公園へ散歩に行きましょう。日本語の文章を読むのは楽しいです。"
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		html        bool
+		want        string
+	}{
+		{"utf-8", []byte("café"), "", false, "café"},
+		{"utf-8 with bom", []byte("\xef\xbb\xbfcafé"), "", false, "café"},
+		{"header charset", encode(t, traditionalchinese.Big5, big5), "text/html; charset=big5", true, big5},
+		{"meta charset", append([]byte(`<meta charset="Big5">`), encode(t, traditionalchinese.Big5, big5)...), "text/html", true, `<meta charset="Big5">` + big5},
+		{"detected shift-jis", encode(t, japanese.ShiftJIS, sjis), "text/plain", false, sjis},
+		{"latin-1 header", []byte("caf\xe9"), "text/plain; charset=iso-8859-1", false, "café"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeText(tt.body, tt.contentType, tt.html); got != tt.want {
+				t.Errorf("decodeText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchDocuments(t *testing.T) {
+	pdf := testPDF(t, "")
+	big5 := `<!DOCTYPE html>
+<html>
+<head><meta charset="big5"><title>天氣報告</title></head>
+<body>
+<article>
+<h1>天氣報告</h1>
+<p>今天天氣很好，陽光普照，氣溫適中。我們決定去公園散步，欣賞盛開的花朵和綠油油的草地。</p>
+<p>下午可能會有陣雨，所以出門時請記得帶傘。晚上的氣溫會下降，請多穿一件外套。</p>
+</article>
+</body>
+</html>`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/report.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write(pdf)
+		case "/news":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write(encode(t, traditionalchinese.Big5, big5))
+		case "/notes.txt":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte("Shopping list\n\n* eggs\n* milk\n\n1. Call the bank\n"))
+		case "/README.md":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte("Intro text.\n\n# Project\n\nSome *emphasis*.\n"))
+		case "/photo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG\r\n\x1a\n"))
+		}
+	}))
+	defer ts.Close()
+	fetch := func(path string) *Article {
+		t.Helper()
+		article, err := Fetch(context.Background(), ts.URL+path, Options{Strategy: StrategyHTTP})
+		if err != nil {
+			t.Fatalf("Fetch(%s): %v", path, err)
+		}
+		return article
+	}
+
+	t.Run("pdf", func(t *testing.T) {
+		article := fetch("/report.pdf")
+		if article.Title != "Quarterly Report" || !strings.Contains(article.Text, "Sales grew by 10%") {
+			t.Errorf("Title = %q, Text = %q", article.Title, article.Text)
+		}
+		if article.ContentType != "application/pdf" || article.Page != "" {
+			t.Errorf("ContentType = %q, Page = %q", article.ContentType, article.Page)
+		}
+	})
+
+	t.Run("big5 page", func(t *testing.T) {
+		article := fetch("/news")
+		if article.Title != "天氣報告" || !strings.Contains(article.Text, "請記得帶傘") {
+			t.Errorf("Title = %q, Text = %q", article.Title, article.Text)
+		}
+		if !strings.Contains(article.Markdown, "陽光普照") || !strings.Contains(article.Page, "天氣報告") {
+			t.Errorf("Markdown = %q", article.Markdown)
+		}
+		if article.ContentType != "text/html" {
+			t.Errorf("ContentType = %q", article.ContentType)
+		}
+	})
+
+	t.Run("plain text", func(t *testing.T) {
+		article := fetch("/notes.txt")
+		if article.Title != "Shopping list" {
+			t.Errorf("Title = %q", article.Title)
+		}
+		if want := "Shopping list\n\n\\* eggs\n\\* milk\n\n1\\. Call the bank\n"; article.Markdown != want {
+			t.Errorf("Markdown = %q, want %q", article.Markdown, want)
+		}
+		if article.Text != "Shopping list * eggs * milk 1. Call the bank" {
+			t.Errorf("Text = %q", article.Text)
+		}
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		article := fetch("/README.md")
+		if article.Title != "Project" || article.ContentType != "text/markdown" {
+			t.Errorf("Title = %q, ContentType = %q", article.Title, article.ContentType)
+		}
+		if article.Markdown != "Intro text.\n\n# Project\n\nSome *emphasis*.\n" {
+			t.Errorf("Markdown = %q, want it unchanged", article.Markdown)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := Fetch(context.Background(), ts.URL+"/photo.png", Options{Strategy: StrategyHTTP})
+		if err == nil || !strings.Contains(err.Error(), "unsupported content type image/png") {
+			t.Errorf("err = %v", err)
+		}
+	})
+}
+
+func TestFileTitle(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/docs/Annual%20Report.pdf": "Annual Report",
+		"https://example.com/notes.txt?download=1":     "notes",
+		"https://example.com/":                         "example.com",
+	}
+	for url, want := range tests {
+		if got := fileTitle(url); got != want {
+			t.Errorf("fileTitle(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
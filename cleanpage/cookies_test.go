@@ -0,0 +1,40 @@
+package cleanpage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadCookies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	content := "# Netscape HTTP Cookie File\n\n" +
+		".example.com\tTRUE\t/\tTRUE\t0\tsession\tabc\n" +
+		"#HttpOnly_intranet.example.com\tFALSE\t/wiki\tFALSE\t4102444800\tsso\txyz\n" +
+		"example.com\tFALSE\t/\tFALSE\t946684800\told\tgone\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cookies, err := LoadCookies(path)
+	if err != nil {
+		t.Fatalf("LoadCookies() error = %v", err)
+	}
+	if len(cookies) != 2 {
+		t.Fatalf("LoadCookies() returned %d cookies, want 2 without the expired one", len(cookies))
+	}
+	if c := cookies[0]; c.Domain != ".example.com" || c.Path != "/" || !c.Secure || c.Name != "session" || c.Value != "abc" || !c.Expires.IsZero() {
+		t.Errorf("session cookie = %+v", c)
+	}
+	if c := cookies[1]; c.Domain != "intranet.example.com" || c.Path != "/wiki" || c.Secure || !c.HttpOnly || !c.Expires.Equal(time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("sso cookie = %+v", c)
+	}
+
+	if err := os.WriteFile(path, []byte("example.com\tFALSE\t/\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCookies(path); err == nil {
+		t.Error("LoadCookies() of a malformed line succeeded")
+	}
+}
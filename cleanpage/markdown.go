@@ -0,0 +1,458 @@
+package cleanpage
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// HTMLToMarkdown converts an HTML document or fragment, such as the content
+// readability extracts from a page, into markdown. Headings, paragraphs,
+// lists, links, images, emphasis, code, quotes and tables keep their
+// structure; scripts, styles and form controls are dropped.
+func HTMLToMarkdown(content string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return "", err
+	}
+	root := findElement(doc, "body")
+	if root == nil {
+		root = doc
+	}
+	return nodeToMarkdown(root), nil
+}
+
+// nodeToMarkdown converts the children of n into markdown
+func nodeToMarkdown(n *html.Node) string {
+	md := strings.Join(blocks(n), "\n\n")
+	md = strings.TrimSpace(extraBlankLines.ReplaceAllString(md, "\n\n"))
+	if md == "" {
+		return ""
+	}
+	return md + "\n"
+}
+
+var extraBlankLines = regexp.MustCompile(`\n{3,}`)
+
+// blockTags are elements that start a block of their own
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"dd": true, "details": true, "div": true, "dl": true, "dt": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "li": true, "main": true, "nav": true,
+	"ol": true, "p": true, "pre": true, "section": true, "summary": true,
+	"table": true, "ul": true,
+}
+
+// skippedTags are elements whose content is never part of the text
+var skippedTags = map[string]bool{
+	"button": true, "canvas": true, "head": true, "iframe": true,
+	"input": true, "noscript": true, "object": true, "script": true,
+	"select": true, "style": true, "svg": true, "template": true,
+	"textarea": true,
+}
+
+func isBlock(n *html.Node) bool {
+	return n.Type == html.ElementNode && blockTags[n.Data]
+}
+
+// blocks converts the children of n into markdown blocks. Runs of inline
+// content between block elements become paragraphs.
+func blocks(n *html.Node) []string {
+	var out []string
+	var run strings.Builder
+	flush := func() {
+		if text := strings.TrimSpace(run.String()); text != "" {
+			out = append(out, escapeBlockStart(text))
+		}
+		run.Reset()
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && skippedTags[c.Data] {
+			continue
+		}
+		if !isBlock(c) {
+			run.WriteString(inline(c))
+			continue
+		}
+		flush()
+		out = append(out, block(c)...)
+	}
+	flush()
+	return out
+}
+
+// block converts a block element into markdown blocks
+func block(n *html.Node) []string {
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := strings.Join(strings.Fields(inlineChildren(n)), " ")
+		if text == "" {
+			return nil
+		}
+		level := int(n.Data[1] - '0')
+		return []string{strings.Repeat("#", level) + " " + text}
+	case "ul", "ol":
+		if list := listBlock(n); list != "" {
+			return []string{list}
+		}
+		return nil
+	case "pre":
+		return []string{codeBlock(n)}
+	case "blockquote":
+		inner := strings.Join(blocks(n), "\n\n")
+		if inner == "" {
+			return nil
+		}
+		lines := strings.Split(inner, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return []string{strings.Join(lines, "\n")}
+	case "hr":
+		return []string{"---"}
+	case "table":
+		if table := tableBlock(n); table != "" {
+			return []string{table}
+		}
+		return nil
+	case "dt":
+		if text := strings.TrimSpace(inlineChildren(n)); text != "" {
+			return []string{"**" + text + "**"}
+		}
+		return nil
+	}
+	return blocks(n)
+}
+
+// listBlock converts a ul or ol into a list. Items holding paragraphs are
+// separated by blank lines; nested lists are indented below their item.
+func listBlock(n *html.Node) string {
+	ordered := n.Data == "ol"
+	number := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+		number = start
+	}
+
+	var items []string
+	loose := false
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.Data != "li" {
+			continue
+		}
+		sep := "\n"
+		if findElement(c, "p") != nil {
+			sep = "\n\n"
+			loose = true
+		}
+		content := strings.Join(blocks(c), sep)
+
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+		indent := strings.Repeat(" ", len(marker))
+		lines := strings.Split(content, "\n")
+		for i := 1; i < len(lines); i++ {
+			if lines[i] != "" {
+				lines[i] = indent + lines[i]
+			}
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	}
+	if loose {
+		return strings.Join(items, "\n\n")
+	}
+	return strings.Join(items, "\n")
+}
+
+// codeBlock converts a pre element into a fenced code block, taking the
+// language from a language-x or lang-x class
+func codeBlock(n *html.Node) string {
+	code := textContent(n)
+	lang := ""
+	for _, el := range []*html.Node{findElement(n, "code"), n} {
+		if el == nil {
+			continue
+		}
+		for _, class := range strings.Fields(attr(el, "class")) {
+			for _, prefix := range []string{"language-", "lang-"} {
+				if strings.HasPrefix(class, prefix) && lang == "" {
+					lang = strings.TrimPrefix(class, prefix)
+				}
+			}
+		}
+	}
+
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + strings.Trim(code, "\n") + "\n" + fence
+}
+
+// tableBlock converts a table into a GitHub flavored markdown table. The
+// first row is the header.
+func tableBlock(n *html.Node) string {
+	var rows [][]string
+	width := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "tr":
+				var row []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+						text := strings.Join(strings.Fields(inlineChildren(cell)), " ")
+						row = append(row, strings.ReplaceAll(text, "|", `\|`))
+					}
+				}
+				rows = append(rows, row)
+				width = max(width, len(row))
+			case "thead", "tbody", "tfoot":
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 || width == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	writeRow := func(row []string) {
+		b.WriteString("|")
+		for i := 0; i < width; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+	writeRow(rows[0])
+	b.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// inline converts an inline node, and everything in it, into markdown
+func inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return escapeText(collapseSpace(n.Data))
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	if skippedTags[n.Data] {
+		return ""
+	}
+
+	switch n.Data {
+	case "br":
+		return "  \n"
+	case "strong", "b":
+		return wrap("**", inlineChildren(n))
+	case "em", "i", "cite":
+		return wrap("*", inlineChildren(n))
+	case "del", "s", "strike":
+		return wrap("~~", inlineChildren(n))
+	case "code", "kbd", "samp", "tt":
+		return codeSpan(textContent(n))
+	case "img":
+		src := attr(n, "src")
+		if src == "" || strings.HasPrefix(src, "data:") || isPixel(n) {
+			return ""
+		}
+		return "![" + escapeText(collapseSpace(attr(n, "alt"))) + "](" + linkTarget(src) + ")"
+	case "a":
+		text := inlineChildren(n)
+		href := attr(n, "href")
+		if strings.TrimSpace(text) == "" || href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			return text
+		}
+		lead, trimmed, trail := splitSpace(text)
+		return lead + "[" + trimmed + "](" + linkTarget(href) + ")" + trail
+	}
+	if isBlock(n) {
+		// Block elements nested in inline ones, like a div in a link,
+		// only get some space around them
+		return " " + inlineChildren(n) + " "
+	}
+	return inlineChildren(n)
+}
+
+func inlineChildren(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(inline(c))
+	}
+	return b.String()
+}
+
+// wrap puts markers around text, keeping its surrounding space outside of
+// them so the markdown stays valid
+func wrap(marker, text string) string {
+	lead, trimmed, trail := splitSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	return lead + marker + trimmed + marker + trail
+}
+
+func splitSpace(text string) (string, string, string) {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text, "", ""
+	}
+	start := strings.Index(text, trimmed)
+	return text[:start], trimmed, text[start+len(trimmed):]
+}
+
+// codeSpan wraps code in enough backticks that the ones inside it do not
+// end the span
+func codeSpan(code string) string {
+	code = collapseSpace(code)
+	if strings.TrimSpace(code) == "" {
+		return code
+	}
+	fence := "`"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+	return fence + code + fence
+}
+
+// linkTarget returns a URL for a link or image, in angle brackets when it
+// contains characters that would end it early
+func linkTarget(url string) string {
+	url = strings.TrimSpace(url)
+	if strings.ContainsAny(url, " ()") {
+		return "<" + strings.ReplaceAll(url, ">", "%3E") + ">"
+	}
+	return url
+}
+
+var spaceRe = regexp.MustCompile(`\s+`)
+
+func collapseSpace(s string) string {
+	return spaceRe.ReplaceAllString(s, " ")
+}
+
+// escapeText escapes characters that markdown would read as formatting.
+// Underscores inside words are left alone since they cannot start
+// emphasis there.
+func escapeText(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		switch r {
+		case '\\', '*', '`', '[', ']':
+			b.WriteRune('\\')
+		case '_':
+			inWord := i > 0 && i < len(runes)-1 && isWordRune(runes[i-1]) && isWordRune(runes[i+1])
+			if !inWord {
+				b.WriteRune('\\')
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+var blockStartRe = regexp.MustCompile(`^(#{1,6}|>|[-+]|\d+[.)])(\s|$)`)
+
+// escapeBlockStart keeps the lines of a paragraph from being read as
+// headings, quotes or list items
+func escapeBlockStart(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		m := blockStartRe.FindStringSubmatch(trimmed)
+		if m == nil {
+			continue
+		}
+		marker := m[1]
+		if last := marker[len(marker)-1]; last == '.' || last == ')' {
+			marker = marker[:len(marker)-1] + `\` + string(last)
+		} else {
+			marker = `\` + marker
+		}
+		lines[i] = marker + trimmed[len(m[1]):]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// textContent returns the text in n without any formatting
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "br" {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+// findElement returns the first element named tag in n, n included
+func findElement(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// isPixel reports whether an image is declared 1x1 or smaller, the size of
+// tracking pixels
+func isPixel(n *html.Node) bool {
+	width, err := strconv.Atoi(strings.TrimSuffix(attr(n, "width"), "px"))
+	if err != nil {
+		return false
+	}
+	height, err := strconv.Atoi(strings.TrimSuffix(attr(n, "height"), "px"))
+	if err != nil {
+		return false
+	}
+	return width <= 1 && height <= 1
+}
@@ -0,0 +1,76 @@
+package cleanpage
+
+import (
+	"testing"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "headings and paragraphs",
+			html: "<h1>Title</h1><p>First   paragraph\nwraps.</p><h3>Part <em>one</em></h3><p>Second.</p>",
+			want: "# Title\n\nFirst paragraph wraps.\n\n### Part *one*\n\nSecond.\n",
+		},
+		{
+			name: "inline formatting",
+			html: `<p><strong>bold</strong>, <em>italic </em>text, <code>a * b</code>, <del>gone</del> and <a href="https://example.com/a">a link</a>.</p>`,
+			want: "**bold**, *italic* text, `a * b`, ~~gone~~ and [a link](https://example.com/a).\n",
+		},
+		{
+			name: "images and odd links",
+			html: `<p><img src="https://example.com/i.png" alt="A chart"> <a href="#top">top</a> <a href="https://example.com/a (b)">paren</a> <img src="data:image/png;base64,xx"><img src="https://t.example.com/p.gif" width="1" height="1"></p>`,
+			want: "![A chart](https://example.com/i.png) top [paren](<https://example.com/a (b)>)\n",
+		},
+		{
+			name: "nested lists",
+			html: "<ul><li>one</li><li>two<ol start=\"3\"><li>three</li><li>four</li></ol></li></ul>",
+			want: "- one\n- two\n  3. three\n  4. four\n",
+		},
+		{
+			name: "loose list",
+			html: "<ol><li><p>first</p><p>more</p></li><li><p>second</p></li></ol>",
+			want: "1. first\n\n   more\n\n2. second\n",
+		},
+		{
+			name: "code block",
+			html: "<pre><code class=\"language-go\">func main() {\n\tfmt.Println(\"```\")\n}\n</code></pre>",
+			want: "````go\nfunc main() {\n\tfmt.Println(\"```\")\n}\n````\n",
+		},
+		{
+			name: "blockquote and rule",
+			html: "<blockquote><p>Quoted</p><p>twice</p></blockquote><hr><p>after</p>",
+			want: "> Quoted\n>\n> twice\n\n---\n\nafter\n",
+		},
+		{
+			name: "table",
+			html: "<table><thead><tr><th>Name</th><th>Value</th></tr></thead><tbody><tr><td>a|b</td><td><b>1</b></td></tr><tr><td>c</td></tr></tbody></table>",
+			want: "| Name | Value |\n| --- | --- |\n| a\\|b | **1** |\n| c |  |\n",
+		},
+		{
+			name: "escaping",
+			html: "<p>1. not a list</p><p># not a heading, *stars*, [brackets] and snake_case but _this_</p>",
+			want: "1\\. not a list\n\n\\# not a heading, \\*stars\\*, \\[brackets\\] and snake_case but \\_this\\_\n",
+		},
+		{
+			name: "dropped elements and mixed content",
+			html: "<div>Loose text<script>alert(1)</script><style>p{}</style><p>para</p>tail<br>next</div>",
+			want: "Loose text\n\npara\n\ntail  \nnext\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HTMLToMarkdown(tt.html)
+			if err != nil {
+				t.Fatalf("HTMLToMarkdown() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("HTMLToMarkdown() mismatch\nwant: %q\ngot:  %q", tt.want, got)
+			}
+		})
+	}
+}
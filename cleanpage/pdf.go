@@ -0,0 +1,1039 @@
+package cleanpage
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// PDFs are read without a PDF library: objects are found by scanning the
+// file, which also copes with damaged cross-reference tables, and the text
+// is taken from the content streams of the pages in the order of the page
+// tree. Fonts' ToUnicode maps are followed where present; simple fonts
+// without one are read as WinAnsi. Only the common stream filters are
+// supported, and encrypted files are refused.
+
+const (
+	// maxFormDepth limits how deeply form XObjects are followed
+	maxFormDepth = 5
+	// Limits on decoded stream data, so that small files which inflate
+	// to a lot of data are refused
+	maxPDFStream  = 10 << 20
+	maxPDFDecoded = 50 << 20
+	// maxPDFNesting limits how deeply arrays and dictionaries nest, as they
+	// are read recursively
+	maxPDFNesting = 256
+	// maxCMapMappings limits the codes a ToUnicode CMap maps, counting each
+	// code of a bfrange
+	maxCMapMappings = 1 << 17
+)
+
+// errEncryptedPDF is returned for PDFs whose content is encrypted
+var errEncryptedPDF = errors.New("encrypted PDFs are not supported")
+
+// errPDFNesting is returned for arrays and dictionaries nested deeper than
+// maxPDFNesting
+var errPDFNesting = fmt.Errorf("PDF objects are nested more than %d levels deep", maxPDFNesting)
+
+type (
+	pdfName    string
+	pdfKeyword string
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		data []byte
+	}
+)
+
+// pdfLexer reads PDF objects and content stream operators
+type pdfLexer struct {
+	data []byte
+	pos  int
+	// depth is the number of arrays and dictionaries being read
+	depth int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		} else if isPDFSpace(c) {
+			l.pos++
+		} else {
+			return
+		}
+	}
+}
+
+// next returns the next object or keyword, and io.EOF at the end
+func (l *pdfLexer) next() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+	switch c := l.data[l.pos]; {
+	case c == '/':
+		return l.name(), nil
+	case c == '(':
+		return l.literal(), nil
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		if l.depth >= maxPDFNesting {
+			return nil, errPDFNesting
+		}
+		l.pos += 2
+		l.depth++
+		defer func() { l.depth-- }()
+		return l.dict()
+	case c == '<':
+		return l.hexString(), nil
+	case c == '[':
+		if l.depth >= maxPDFNesting {
+			return nil, errPDFNesting
+		}
+		l.pos++
+		l.depth++
+		defer func() { l.depth-- }()
+		return l.array()
+	case c == ']' || c == '>' || c == '{' || c == '}' || c == ')':
+		l.pos++
+		if c == '>' && l.pos < len(l.data) && l.data[l.pos] == '>' {
+			l.pos++
+			return pdfKeyword(">>"), nil
+		}
+		return pdfKeyword(string(c)), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return l.number()
+	default:
+		start := l.pos
+		for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+			l.pos++
+		}
+		if l.pos == start {
+			l.pos++
+		}
+		switch word := string(l.data[start:l.pos]); word {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		default:
+			return pdfKeyword(word), nil
+		}
+	}
+}
+
+// object reads an object, combining "num gen R" into a reference
+func (l *pdfLexer) object() (any, error) {
+	v, err := l.next()
+	if err != nil {
+		return nil, err
+	}
+	num, ok := v.(float64)
+	if !ok || num != math.Trunc(num) {
+		return v, nil
+	}
+	save := l.pos
+	if gen, err := l.next(); err == nil {
+		if g, ok := gen.(float64); ok {
+			if r, err := l.next(); err == nil && r == pdfKeyword("R") {
+				return pdfRef{int(num), int(g)}, nil
+			}
+		}
+	}
+	l.pos = save
+	return v, nil
+}
+
+func (l *pdfLexer) number() (any, error) {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.data) && (l.data[l.pos] == '.' || (l.data[l.pos] >= '0' && l.data[l.pos] <= '9')) {
+		l.pos++
+	}
+	f, err := strconv.ParseFloat(string(l.data[start:l.pos]), 64)
+	if err != nil {
+		// Producers write oddities like "--5" or "."; read them as zero
+		return 0.0, nil
+	}
+	return f, nil
+}
+
+func (l *pdfLexer) name() pdfName {
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		b.WriteByte(c)
+		l.pos++
+	}
+	return pdfName(b.String())
+}
+
+func (l *pdfLexer) literal() string {
+	l.pos++
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return string(b)
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return string(b)
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// A line continuation
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
+
+func (l *pdfLexer) hexString() string {
+	l.pos++
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b, _ := hex.DecodeString(string(digits))
+	return string(b)
+}
+
+func (l *pdfLexer) array() ([]any, error) {
+	var arr []any
+	for {
+		v, err := l.object()
+		if err != nil {
+			return arr, err
+		}
+		if v == pdfKeyword("]") {
+			return arr, nil
+		}
+		arr = append(arr, v)
+	}
+}
+
+func (l *pdfLexer) dict() (pdfDict, error) {
+	d := pdfDict{}
+	for {
+		k, err := l.next()
+		if err != nil {
+			return d, err
+		}
+		if k == pdfKeyword(">>") {
+			return d, nil
+		}
+		key, ok := k.(pdfName)
+		if !ok {
+			continue
+		}
+		v, err := l.object()
+		if err != nil {
+			return d, err
+		}
+		d[key] = v
+	}
+}
+
+// pdfDoc is the objects of a PDF by number
+type pdfDoc struct {
+	objects map[int]any
+	trailer pdfDict
+	// decoded caches the data of streams already decoded, which pages and
+	// forms often share, and decodedSize is its total
+	decoded     map[*pdfStream]decodedStream
+	decodedSize int
+	// interpreted is the size of the content streams run so far, forms
+	// each time they are drawn included
+	interpreted int
+}
+
+type decodedStream struct {
+	data []byte
+	err  error
+}
+
+var pdfObjRe = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// parsePDF finds the objects and the trailer of a PDF
+func parsePDF(data []byte) (*pdfDoc, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return nil, errors.New("not a PDF file")
+	}
+	doc := &pdfDoc{objects: map[int]any{}, trailer: pdfDict{}, decoded: map[*pdfStream]decodedStream{}}
+
+	var objStreams []*pdfStream
+	for _, m := range pdfObjRe.FindAllSubmatchIndex(data, -1) {
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		l := &pdfLexer{data: data, pos: m[1]}
+		v, err := l.object()
+		if err != nil {
+			continue
+		}
+		if d, ok := v.(pdfDict); ok {
+			if kw, err := l.next(); err == nil && kw == pdfKeyword("stream") {
+				v = &pdfStream{dict: d, data: streamData(data, l.pos, d)}
+			}
+			if d["Type"] == pdfName("XRef") {
+				mergeTrailer(doc.trailer, d)
+			}
+		}
+		if s, ok := v.(*pdfStream); ok && s.dict["Type"] == pdfName("ObjStm") {
+			objStreams = append(objStreams, s)
+		}
+		doc.objects[num] = v
+	}
+
+	// Objects in object streams, unless a later update replaced them
+	for _, s := range objStreams {
+		decoded, err := doc.decodeStream(s)
+		if err != nil {
+			continue
+		}
+		n, _ := doc.resolve(s.dict["N"]).(float64)
+		first, _ := doc.resolve(s.dict["First"]).(float64)
+		header := &pdfLexer{data: decoded}
+		for i := 0; i < int(n); i++ {
+			num, err1 := header.next()
+			offset, err2 := header.next()
+			if err1 != nil || err2 != nil {
+				break
+			}
+			objNum, _ := num.(float64)
+			off, _ := offset.(float64)
+			pos := int(first + off)
+			if _, ok := doc.objects[int(objNum)]; ok || pos < 0 || pos >= len(decoded) {
+				continue
+			}
+			l := &pdfLexer{data: decoded, pos: pos}
+			if v, err := l.object(); err == nil {
+				doc.objects[int(objNum)] = v
+			}
+		}
+	}
+
+	// Classic trailers; XRef stream dictionaries were merged above
+	for i := 0; ; {
+		j := bytes.Index(data[i:], []byte("trailer"))
+		if j < 0 {
+			break
+		}
+		l := &pdfLexer{data: data, pos: i + j + len("trailer")}
+		if v, err := l.next(); err == nil {
+			if d, ok := v.(pdfDict); ok {
+				mergeTrailer(doc.trailer, d)
+			}
+		}
+		i += j + len("trailer")
+	}
+	if _, ok := doc.trailer["Encrypt"]; ok {
+		return nil, errEncryptedPDF
+	}
+	return doc, nil
+}
+
+// mergeTrailer copies the trailer entries of d into trailer. Later
+// updates come later in the file and win.
+func mergeTrailer(trailer, d pdfDict) {
+	for _, key := range []pdfName{"Root", "Info", "Encrypt"} {
+		if v, ok := d[key]; ok {
+			trailer[key] = v
+		}
+	}
+}
+
+// streamData returns the raw data of a stream starting at pos, just after
+// the stream keyword
+func streamData(data []byte, pos int, d pdfDict) []byte {
+	if pos < len(data) && data[pos] == '\r' {
+		pos++
+	}
+	if pos < len(data) && data[pos] == '\n' {
+		pos++
+	}
+	if length, ok := d["Length"].(float64); ok {
+		end := pos + int(length)
+		if end <= len(data) && bytes.HasPrefix(bytes.TrimLeft(data[end:], " \r\n"), []byte("endstream")) {
+			return data[pos:end]
+		}
+	}
+	end := bytes.Index(data[pos:], []byte("endstream"))
+	if end < 0 {
+		return data[pos:]
+	}
+	return bytes.TrimRight(data[pos:pos+end], "\r\n")
+}
+
+// resolve follows references
+func (doc *pdfDoc) resolve(v any) any {
+	for i := 0; i < 10; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = doc.objects[ref.num]
+	}
+	return nil
+}
+
+func (doc *pdfDoc) dict(v any) pdfDict {
+	switch v := doc.resolve(v).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// decodeStream applies the filters of a stream, once per stream
+func (doc *pdfDoc) decodeStream(s *pdfStream) ([]byte, error) {
+	if d, ok := doc.decoded[s]; ok {
+		return d.data, d.err
+	}
+	if doc.decodedSize >= maxPDFDecoded {
+		return nil, fmt.Errorf("PDF streams are larger than %d bytes", maxPDFDecoded)
+	}
+	data, err := decodeFilters(s)
+	if err == nil && doc.decodedSize+len(data) > maxPDFDecoded {
+		data, err = nil, fmt.Errorf("PDF streams are larger than %d bytes", maxPDFDecoded)
+	}
+	doc.decoded[s] = decodedStream{data: data, err: err}
+	doc.decodedSize += len(data)
+	return data, err
+}
+
+// decodeFilters applies the filters of a stream
+func decodeFilters(s *pdfStream) ([]byte, error) {
+	var filters []any
+	switch f := s.dict["Filter"].(type) {
+	case pdfName:
+		filters = []any{f}
+	case []any:
+		filters = f
+	}
+	data := s.data
+	for _, f := range filters {
+		var err error
+		switch f {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			var r io.ReadCloser
+			if r, err = zlib.NewReader(bytes.NewReader(data)); err == nil {
+				// Keep what could be read of a damaged stream
+				data, err = io.ReadAll(io.LimitReader(r, maxPDFStream+1))
+				if errors.Is(err, io.ErrUnexpectedEOF) && len(data) > 0 {
+					err = nil
+				}
+				if len(data) > maxPDFStream {
+					err = fmt.Errorf("PDF stream is larger than %d bytes", maxPDFStream)
+				}
+			}
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			l := &pdfLexer{data: append(append([]byte("<"), bytes.TrimSuffix(bytes.TrimSpace(data), []byte(">"))...), '>')}
+			data = []byte(l.hexString())
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			trimmed := bytes.TrimSuffix(bytes.TrimSpace(data), []byte("~>"))
+			trimmed = bytes.TrimPrefix(trimmed, []byte("<~"))
+			out := make([]byte, len(trimmed)*4/5+4)
+			var n int
+			n, _, err = ascii85.Decode(out, trimmed, true)
+			data = out[:n]
+		default:
+			err = fmt.Errorf("unsupported PDF filter %v", f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// pdfPage is a page's content and the resources it uses
+type pdfPage struct {
+	contents  [][]byte
+	resources pdfDict
+}
+
+// maxPageTreeDepth limits how deeply the page tree is followed
+const maxPageTreeDepth = 64
+
+// pages returns the pages below node in the page tree, in order. Objects
+// in visited are skipped, so that broken trees whose kids refer back to
+// their ancestors, or share kids, yield each page once.
+func (doc *pdfDoc) pages(v any, resources pdfDict, visited map[int]bool, depth int) []pdfPage {
+	if ref, ok := v.(pdfRef); ok {
+		if visited[ref.num] {
+			return nil
+		}
+		visited[ref.num] = true
+	}
+	node := doc.dict(v)
+	if node == nil || depth > maxPageTreeDepth {
+		return nil
+	}
+	if r := doc.dict(node["Resources"]); r != nil {
+		resources = r
+	}
+	if kids, ok := doc.resolve(node["Kids"]).([]any); ok {
+		var pages []pdfPage
+		for _, kid := range kids {
+			pages = append(pages, doc.pages(kid, resources, visited, depth+1)...)
+		}
+		return pages
+	}
+
+	page := pdfPage{resources: resources}
+	contents := doc.resolve(node["Contents"])
+	if arr, ok := contents.([]any); ok {
+		for _, c := range arr {
+			if s, ok := doc.resolve(c).(*pdfStream); ok {
+				if data, err := doc.decodeStream(s); err == nil {
+					page.contents = append(page.contents, data)
+				}
+			}
+		}
+	} else if s, ok := contents.(*pdfStream); ok {
+		if data, err := doc.decodeStream(s); err == nil {
+			page.contents = append(page.contents, data)
+		}
+	}
+	return []pdfPage{page}
+}
+
+// pdfFont maps the character codes of a font to text
+type pdfFont struct {
+	// codeLen is the number of bytes in a character code
+	codeLen   int
+	toUnicode map[string]string
+	// differences maps codes of simple fonts to text, from the glyph
+	// names of their encoding
+	differences map[byte]string
+}
+
+func (doc *pdfDoc) font(d pdfDict) *pdfFont {
+	f := &pdfFont{codeLen: 1}
+	if d["Subtype"] == pdfName("Type0") {
+		f.codeLen = 2
+	}
+	if s, ok := doc.resolve(d["ToUnicode"]).(*pdfStream); ok {
+		if data, err := doc.decodeStream(s); err == nil {
+			f.parseCMap(data)
+		}
+	}
+	if enc := doc.dict(d["Encoding"]); enc != nil {
+		if diffs, ok := doc.resolve(enc["Differences"]).([]any); ok {
+			f.differences = map[byte]string{}
+			code := 0
+			for _, v := range diffs {
+				switch v := v.(type) {
+				case float64:
+					code = int(v)
+				case pdfName:
+					if text := glyphText(string(v)); text != "" && code < 256 {
+						f.differences[byte(code)] = text
+					}
+					code++
+				}
+			}
+		}
+	}
+	return f
+}
+
+// parseCMap reads the code space and the bfchar and bfrange mappings of a
+// ToUnicode CMap, stopping after maxCMapMappings codes
+func (f *pdfFont) parseCMap(data []byte) {
+	f.toUnicode = map[string]string{}
+	l := &pdfLexer{data: data}
+	var operands []any
+	mappings := 0
+	for {
+		v, err := l.object()
+		if err != nil {
+			return
+		}
+		kw, ok := v.(pdfKeyword)
+		if !ok {
+			operands = append(operands, v)
+			continue
+		}
+		switch kw {
+		case "endcodespacerange":
+			if len(operands) > 0 {
+				if lo, ok := operands[0].(string); ok && len(lo) > 0 {
+					f.codeLen = len(lo)
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				if mappings++; mappings > maxCMapMappings {
+					return
+				}
+				src, ok1 := operands[i].(string)
+				dst, ok2 := operands[i+1].(string)
+				if ok1 && ok2 {
+					f.toUnicode[src] = utf16Text(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(string)
+				hi, ok2 := operands[i+1].(string)
+				if !ok1 || !ok2 || len(lo) != len(hi) || len(lo) == 0 || len(lo) > 4 {
+					continue
+				}
+				start, end := codeValue(lo), codeValue(hi)
+				if end < start || end-start > 0xffff {
+					continue
+				}
+				for code := start; code <= end; code++ {
+					if mappings++; mappings > maxCMapMappings {
+						return
+					}
+					src := codeString(code, len(lo))
+					switch dst := operands[i+2].(type) {
+					case string:
+						// The last byte of the destination counts up
+						if b := []byte(dst); len(b) > 0 {
+							b[len(b)-1] += byte(code - start)
+							f.toUnicode[src] = utf16Text(string(b))
+						}
+					case []any:
+						if k := code - start; k < len(dst) {
+							if s, ok := dst[k].(string); ok {
+								f.toUnicode[src] = utf16Text(s)
+							}
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func codeValue(s string) int {
+	v := 0
+	for i := 0; i < len(s); i++ {
+		v = v<<8 | int(s[i])
+	}
+	return v
+}
+
+func codeString(v, n int) string {
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return string(b)
+}
+
+// utf16Text decodes the UTF-16BE text of a CMap destination
+func utf16Text(s string) string {
+	b := []byte(s)
+	if len(b)%2 == 1 {
+		return string(b)
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+// glyphNames are the glyph names of encodings that are not the character
+// they name
+var glyphNames = map[string]string{
+	"space": " ", "exclam": "!", "quotedbl": "\"", "numbersign": "#",
+	"dollar": "$", "percent": "%", "ampersand": "&", "quotesingle": "'",
+	"quoteright": "’", "quoteleft": "‘", "quotedblleft": "“", "quotedblright": "”",
+	"parenleft": "(", "parenright": ")", "asterisk": "*", "plus": "+",
+	"comma": ",", "hyphen": "-", "period": ".", "slash": "/", "colon": ":",
+	"semicolon": ";", "less": "<", "equal": "=", "greater": ">", "question": "?",
+	"at": "@", "bracketleft": "[", "backslash": "\\", "bracketright": "]",
+	"underscore": "_", "braceleft": "{", "bar": "|", "braceright": "}",
+	"zero": "0", "one": "1", "two": "2", "three": "3", "four": "4",
+	"five": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9",
+	"endash": "–", "emdash": "—", "bullet": "•", "ellipsis": "…",
+	"fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi", "ffl": "ffl",
+	"dieresis": "¨", "acute": "´", "grave": "`", "circumflex": "^", "tilde": "~",
+	"adieresis": "ä", "odieresis": "ö", "udieresis": "ü", "Adieresis": "Ä",
+	"Odieresis": "Ö", "Udieresis": "Ü", "germandbls": "ß", "eacute": "é",
+	"egrave": "è", "aacute": "á", "agrave": "à", "ccedilla": "ç", "ntilde": "ñ",
+	"copyright": "©", "registered": "®", "degree": "°", "section": "§",
+}
+
+// glyphText returns the text of a glyph name, or "" when it is unknown
+func glyphText(name string) string {
+	if text, ok := glyphNames[name]; ok {
+		return text
+	}
+	if utf8.RuneCountInString(name) == 1 {
+		return name
+	}
+	if hexCode, ok := strings.CutPrefix(name, "uni"); ok && len(hexCode) == 4 {
+		if v, err := strconv.ParseUint(hexCode, 16, 16); err == nil {
+			return string(rune(v))
+		}
+	}
+	return ""
+}
+
+// decode turns a shown string into text
+func (f *pdfFont) decode(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		n := min(f.codeLen, len(s))
+		code := s[:n]
+		s = s[n:]
+		if text, ok := f.toUnicode[code]; ok {
+			b.WriteString(text)
+		} else if f.codeLen == 1 {
+			if text, ok := f.differences[code[0]]; ok {
+				b.WriteString(text)
+			} else if code[0] >= ' ' || code[0] == '\t' {
+				b.WriteRune(charmap.Windows1252.DecodeByte(code[0]))
+			}
+		}
+	}
+	return b.String()
+}
+
+// pdfText collects the text of content streams, breaking lines and
+// paragraphs where the text moves down the page
+type pdfText struct {
+	doc *pdfDoc
+	b   strings.Builder
+	// fonts caches the fonts referenced by the pages
+	fonts map[pdfRef]*pdfFont
+	// The font in use and the text position
+	font     *pdfFont
+	size     float64
+	scale    float64
+	leading  float64
+	y        float64
+	lineY    float64
+	hasLineY bool
+}
+
+func (t *pdfText) fontFor(resources pdfDict, name pdfName) *pdfFont {
+	raw := t.doc.dict(resources["Font"])[name]
+	ref, isRef := raw.(pdfRef)
+	if f, ok := t.fonts[ref]; isRef && ok {
+		return f
+	}
+	d := t.doc.dict(raw)
+	if d == nil {
+		return &pdfFont{codeLen: 1}
+	}
+	f := t.doc.font(d)
+	if isRef {
+		t.fonts[ref] = f
+	}
+	return f
+}
+
+// moveTo notes that text continues at height y
+func (t *pdfText) moveTo(y float64) {
+	t.y = y
+	if !t.hasLineY {
+		t.lineY, t.hasLineY = y, true
+		return
+	}
+	height := t.size * t.scale
+	if height <= 0 {
+		height = 10
+	}
+	switch gap := math.Abs(t.lineY - y); {
+	case gap > 1.6*height:
+		t.paragraph()
+	case gap > 0.3*height:
+		t.newline()
+	default:
+		t.space()
+	}
+	t.lineY = y
+}
+
+func (t *pdfText) newline() {
+	if s := t.b.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		t.b.WriteString("\n")
+	}
+}
+
+func (t *pdfText) paragraph() {
+	t.newline()
+	if s := t.b.String(); s != "" && !strings.HasSuffix(s, "\n\n") {
+		t.b.WriteString("\n")
+	}
+}
+
+func (t *pdfText) space() {
+	if s := t.b.String(); s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+		t.b.WriteString(" ")
+	}
+}
+
+func (t *pdfText) show(s string) {
+	if t.font == nil {
+		t.font = &pdfFont{codeLen: 1}
+	}
+	t.b.WriteString(t.font.decode(s))
+}
+
+// run interprets a content stream
+func (t *pdfText) run(content []byte, resources pdfDict, depth int) {
+	// Forms drawn many times by forms drawn many times add up quickly
+	if t.doc.interpreted += len(content); t.doc.interpreted > maxPDFDecoded {
+		return
+	}
+	l := &pdfLexer{data: content}
+	var operands []any
+	number := func(i int) float64 {
+		if i < len(operands) {
+			if f, ok := operands[i].(float64); ok {
+				return f
+			}
+		}
+		return 0
+	}
+	for {
+		v, err := l.next()
+		if err != nil {
+			return
+		}
+		op, ok := v.(pdfKeyword)
+		if !ok {
+			operands = append(operands, v)
+			continue
+		}
+		switch op {
+		case "BT":
+			// Text objects start at the origin
+			t.y, t.scale = 0, 1
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[0].(pdfName); ok {
+					t.font = t.fontFor(resources, name)
+					t.size = number(1)
+				}
+			}
+		case "TL":
+			t.leading = number(0)
+		case "Td", "TD":
+			if op == "TD" {
+				t.leading = -number(1)
+			}
+			if number(1) != 0 {
+				t.moveTo(t.y + number(1)*t.scale)
+			} else if number(0) > 0 {
+				t.space()
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				t.scale = math.Abs(number(3))
+				if t.scale == 0 {
+					t.scale = 1
+				}
+				t.moveTo(number(5))
+			}
+		case "T*":
+			t.moveTo(t.y - t.leading*t.scale)
+		case "Tj":
+			if s, ok := lastString(operands); ok {
+				t.show(s)
+			}
+		case "'", "\"":
+			t.moveTo(t.y - t.leading*t.scale)
+			if s, ok := lastString(operands); ok {
+				t.show(s)
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				arr, _ := operands[len(operands)-1].([]any)
+				for _, item := range arr {
+					switch item := item.(type) {
+					case string:
+						t.show(item)
+					case float64:
+						// Large negative adjustments space words apart
+						if item < -250 {
+							t.space()
+						}
+					}
+				}
+			}
+		case "Do":
+			if name, ok := lastName(operands); ok && depth < maxFormDepth {
+				xobjects := t.doc.dict(resources["XObject"])
+				if s, ok := t.doc.resolve(xobjects[name]).(*pdfStream); ok && s.dict["Subtype"] == pdfName("Form") {
+					formResources := t.doc.dict(s.dict["Resources"])
+					if formResources == nil {
+						formResources = resources
+					}
+					if data, err := t.doc.decodeStream(s); err == nil {
+						t.run(data, formResources, depth+1)
+					}
+				}
+			}
+		case "ID":
+			// Skip the data of an inline image
+			if end := bytes.Index(content[l.pos:], []byte("EI")); end >= 0 {
+				l.pos += end + 2
+			} else {
+				return
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func lastString(operands []any) (string, bool) {
+	if len(operands) == 0 {
+		return "", false
+	}
+	s, ok := operands[len(operands)-1].(string)
+	return s, ok
+}
+
+func lastName(operands []any) (pdfName, bool) {
+	if len(operands) == 0 {
+		return "", false
+	}
+	n, ok := operands[len(operands)-1].(pdfName)
+	return n, ok
+}
+
+// pdfTextString decodes a text string of the document, such as its title
+func pdfTextString(s string) string {
+	if strings.HasPrefix(s, "\xfe\xff") {
+		return utf16Text(s[2:])
+	}
+	if utf8.ValidString(s) {
+		return s
+	}
+	text, _ := charmap.Windows1252.NewDecoder().String(s)
+	return text
+}
+
+// extractPDF returns the title of a PDF from its document information,
+// if it has one, and the text of each of its pages
+func extractPDF(data []byte) (string, []string, error) {
+	doc, err := parsePDF(data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var title string
+	if info := doc.dict(doc.trailer["Info"]); info != nil {
+		if s, ok := doc.resolve(info["Title"]).(string); ok {
+			title = strings.TrimSpace(pdfTextString(s))
+		}
+	}
+
+	root := doc.dict(doc.trailer["Root"])
+	if root == nil {
+		// Without a trailer, look for the catalog itself
+		for _, v := range doc.objects {
+			if d, ok := v.(pdfDict); ok && d["Type"] == pdfName("Catalog") {
+				root = d
+				break
+			}
+		}
+	}
+	if root == nil {
+		return "", nil, errors.New("PDF has no pages")
+	}
+
+	var texts []string
+	fonts := map[pdfRef]*pdfFont{}
+	for _, page := range doc.pages(root["Pages"], nil, map[int]bool{}, 0) {
+		t := &pdfText{doc: doc, fonts: fonts, scale: 1}
+		for _, content := range page.contents {
+			t.run(content, page.resources, 0)
+		}
+		texts = append(texts, strings.TrimSpace(t.b.String()))
+	}
+	if len(texts) == 0 {
+		return "", nil, errors.New("PDF has no pages")
+	}
+	return title, texts, nil
+}
@@ -0,0 +1,250 @@
+package cleanpage
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF writes a PDF of objects, numbered from 1, with an xref table.
+// Object 1 must be the catalog.
+func buildPDF(t testing.TB, info string, objects ...string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R%s >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, info, xref)
+	return b.Bytes()
+}
+
+func pdfStreamObject(t testing.TB, content string, compress bool) string {
+	t.Helper()
+	if !compress {
+		return fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content)
+	}
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	if _, err := w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", b.Len(), b.String())
+}
+
+// testPDF is a two page report. The first page uses a standard font, the
+// second a composite font with a ToUnicode CMap and compressed content.
+func testPDF(t testing.TB, info string) []byte {
+	t.Helper()
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0001> <65E5>
+<0002> <672C>
+endbfchar
+1 beginbfrange
+<0010> <0012> <0041>
+endbfrange
+endcmap
+end
+end`
+	return buildPDF(t, info,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 7 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 8 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding << /Differences [150 /endash] >> >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Mincho /Encoding /Identity-H /ToUnicode 9 0 R >>",
+		pdfStreamObject(t, `BT
+/F1 24 Tf 72 720 Td (Quarterly Report) Tj
+/F1 12 Tf 0 -48 Td [(Sales gr) 20 (ew) -300 (by 10%) ( \(mostly\) in) -400 (May)] TJ
+0 -14 Td (Costs fell \226 again.) Tj
+ET`, false),
+		pdfStreamObject(t, `BT /F2 12 Tf 72 720 Td <00010002> Tj 0 -14 Td <001000110012> Tj ET`, true),
+		pdfStreamObject(t, cmap, false),
+	)
+}
+
+func TestExtractPDF(t *testing.T) {
+	title, pages, err := extractPDF(testPDF(t, " /Info 10 0 R"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "" {
+		t.Errorf("title = %q without an Info dictionary", title)
+	}
+	want := []string{
+		"Quarterly Report\n\nSales grew by 10% (mostly) in May\nCosts fell – again.",
+		"日本\nABC",
+	}
+	if len(pages) != len(want) {
+		t.Fatalf("got %d pages, want %d: %q", len(pages), len(want), pages)
+	}
+	for i := range want {
+		if pages[i] != want[i] {
+			t.Errorf("page %d = %q, want %q", i+1, pages[i], want[i])
+		}
+	}
+
+	withInfo := append(testPDF(t, " /Info 10 0 R"), "10 0 obj\n<< /Title (Q3 Report) >>\nendobj\n"...)
+	if title, _, err := extractPDF(withInfo); err != nil || title != "Q3 Report" {
+		t.Errorf("title = %q, %v, want the Info title", title, err)
+	}
+
+	encrypted := buildPDF(t, " /Encrypt 2 0 R", "<< /Type /Catalog >>", "<< /Filter /Standard >>")
+	if _, _, err := extractPDF(encrypted); err != errEncryptedPDF {
+		t.Errorf("encrypted PDF: err = %v, want %v", err, errEncryptedPDF)
+	}
+	if _, _, err := extractPDF([]byte("%PDF-1.4\ngarbage")); err == nil {
+		t.Error("broken PDF: no error")
+	}
+}
+
+func TestExtractPDFPageTreeLoops(t *testing.T) {
+	// The second kid of the root is the root itself and the third repeats
+	// the first; each page is read once
+	data := buildPDF(t, "",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 2 0 R 3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 5 0 R >>",
+		"<< /Type /Pages /Parent 2 0 R /Kids [2 0 R 4 0 R 3 0 R] /Count 1 >>",
+		pdfStreamObject(t, "BT /F1 12 Tf (Only once) Tj ET", false),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	_, pages, err := extractPDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 || pages[0] != "Only once" {
+		t.Errorf("pages = %q, want the page once", pages)
+	}
+}
+
+func TestExtractPDFLimits(t *testing.T) {
+	// Ten megabytes of content compress to a few kilobytes
+	big := pdfStreamObject(t, strings.Repeat(" ", maxPDFStream+1), true)
+	data := buildPDF(t, "",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		big,
+	)
+	doc, err := parsePDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, _ := doc.objects[4].(*pdfStream)
+	if _, err := doc.decodeStream(stream); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("large stream: err = %v", err)
+	}
+
+	// Each stream is within the limit, together they are not
+	objects := []string{"<< /Type /Catalog /Pages 2 0 R >>", "<< /Type /Pages /Kids [3 0 R 5 0 R 7 0 R 9 0 R 11 0 R 13 0 R] >>"}
+	for i := 0; i < 6; i++ {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Contents %d 0 R >>", 4+2*i),
+			pdfStreamObject(t, strings.Repeat(" ", maxPDFStream), true))
+	}
+	doc, err = parsePDF(buildPDF(t, "", objects...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := doc.pages(doc.dict(doc.trailer["Root"])["Pages"], nil, map[int]bool{}, 0)
+	if len(pages) != 6 {
+		t.Fatalf("got %d pages, want 6", len(pages))
+	}
+	if len(pages[4].contents) != 1 || len(pages[5].contents) != 0 {
+		t.Errorf("the content of the last page should be over the limit")
+	}
+
+	// Nesting is refused instead of overflowing the stack
+	for _, open := range []string{"[", "<< /A "} {
+		data := buildPDF(t, "",
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+			"<< /Type /Page /Parent 2 0 R /Nested "+strings.Repeat(open, 1<<20)+" >>",
+		)
+		extractPDF(data)
+		l := &pdfLexer{data: []byte(strings.Repeat(open, maxPDFNesting+1))}
+		if _, err := l.object(); !errors.Is(err, errPDFNesting) {
+			t.Errorf("nested %q: err = %v, want errPDFNesting", open, err)
+		}
+	}
+
+	// Every bfrange counts towards the mappings of a CMap
+	var cmap strings.Builder
+	for i := 0; i < 10; i++ {
+		cmap.WriteString("1 beginbfrange <0000> <ffff> <0041> endbfrange\n")
+	}
+	var f pdfFont
+	f.parseCMap([]byte(cmap.String()))
+	if len(f.toUnicode) != 0x10000 {
+		t.Errorf("CMap mapped %d codes, want 65536", len(f.toUnicode))
+	}
+	cmap.WriteString("1 beginbfchar <0001> <0043> endbfchar\n")
+	f.parseCMap([]byte(cmap.String()))
+	if got := f.toUnicode["\x00\x01"]; got == "C" {
+		t.Errorf("CMap mapping past the limit was read")
+	}
+}
+
+func FuzzExtractPDF(f *testing.F) {
+	f.Add(testPDF(f, ""))
+	f.Add(testPDF(f, " /Info 10 0 R"))
+	f.Add(buildPDF(f, "",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [2 0 R 3 0 R] >>",
+		"<< /Type /Page /Contents 4 0 R /Resources << /XObject << /X 4 0 R >> >> >>",
+		"<< /Type /XObject /Subtype /Form /Length 12 >>\nstream\n/X Do /X Do\nendstream",
+	))
+	f.Add([]byte("%PDF-1.5\n1 0 obj\n<< /Type /ObjStm /N 1 /First 4 /Length 9 >>\nstream\n2 -9 <<>>\nendstream\nendobj\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Must not panic or hang; errors are fine
+		extractPDF(data)
+	})
+}
+
+func TestPDFArticle(t *testing.T) {
+	article, err := pdfArticle(testPDF(t, ""), "https://example.com/files/q3-report.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article.Title != "Quarterly Report" {
+		t.Errorf("Title = %q, want the first line", article.Title)
+	}
+	if article.Markdown != "Quarterly Report\n\nSales grew by 10% (mostly) in May\nCosts fell – again.\n\n日本\nABC\n" {
+		t.Errorf("Markdown = %q", article.Markdown)
+	}
+	if article.Text != "Quarterly Report Sales grew by 10% (mostly) in May Costs fell – again. 日本 ABC" {
+		t.Errorf("Text = %q", article.Text)
+	}
+	if article.Page != "" || article.ContentType != "application/pdf" {
+		t.Errorf("Page = %q, ContentType = %q", article.Page, article.ContentType)
+	}
+
+	empty := buildPDF(t, "",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+	)
+	if _, err := pdfArticle(empty, "https://example.com/scan.pdf"); err == nil || !strings.Contains(err.Error(), "no text") {
+		t.Errorf("PDF without text: err = %v", err)
+	}
+}
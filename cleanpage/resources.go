@@ -0,0 +1,77 @@
+package cleanpage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+// Resources downloads the resources of a page, such as its images and
+// stylesheets, as opts ask: through its proxy, with its cookies, user agent
+// and timeout. The extra headers are only sent to the page's own host. One
+// client is shared by all the downloads.
+type Resources struct {
+	ctx    context.Context
+	client *http.Client
+	page   *url.URL
+	opts   Options
+}
+
+// NewResources returns a downloader for the resources of the page at
+// pageURL. Downloads stop when ctx is done.
+func NewResources(ctx context.Context, pageURL string, opts Options) (*Resources, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultTimeout
+	}
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	client, err := httpClient(opts, page)
+	if err != nil {
+		return nil, err
+	}
+	return &Resources{ctx: ctx, client: client, page: page, opts: opts}, nil
+}
+
+// Get downloads the resource at u, which must be at most limit bytes, and
+// returns it with its media type. The type is sniffed when the server does
+// not give one.
+func (r *Resources) Get(u string, limit int64) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	setHeaders(req, r.opts, r.page)
+	if r.opts.Wait != nil {
+		r.opts.Wait(u)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, "", &StatusError{StatusCode: resp.StatusCode}
+	}
+	if resp.ContentLength > limit {
+		return nil, "", fmt.Errorf("%s is larger than %d bytes", u, limit)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > limit {
+		return nil, "", fmt.Errorf("%s is larger than %d bytes", u, limit)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	return data, mediaType, nil
+}
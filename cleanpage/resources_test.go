@@ -0,0 +1,100 @@
+package cleanpage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResourcesGet(t *testing.T) {
+	var got, gotCDN *http.Request
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	// cdn is another site, which must not see the extra headers
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotCDN = r
+		w.Write(png)
+	}))
+	defer cdn.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		switch r.URL.Path {
+		case "/style.css":
+			w.Header().Set("Content-Type", "text/css; charset=utf-8")
+			w.Write([]byte("body { color: red }"))
+		case "/logo":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(png)
+		case "/big":
+			w.Write([]byte(strings.Repeat("x", 100)))
+		case "/cdn":
+			http.Redirect(w, r, cdn.URL+"/logo.png", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	var waited []string
+	resources, err := NewResources(context.Background(), ts.URL+"/article", Options{
+		UserAgent: "ned-test/1.0",
+		Header:    http.Header{"X-Team": {"notes"}},
+		Cookies:   []*http.Cookie{{Name: "sso", Value: "xyz", Domain: "127.0.0.1", Path: "/"}},
+		Wait:      func(url string) { waited = append(waited, url) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, mediaType, err := resources.Get(ts.URL+"/style.css", 1024)
+	if err != nil || string(data) != "body { color: red }" || mediaType != "text/css" {
+		t.Errorf("Get(style.css) = %q, %q, %v", data, mediaType, err)
+	}
+	if ua := got.Header.Get("User-Agent"); ua != "ned-test/1.0" {
+		t.Errorf("User-Agent = %q", ua)
+	}
+	if team := got.Header.Get("X-Team"); team != "notes" {
+		t.Errorf("X-Team = %q", team)
+	}
+	if cookie, err := got.Cookie("sso"); err != nil || cookie.Value != "xyz" {
+		t.Errorf("sso cookie = %v, %v", cookie, err)
+	}
+
+	if _, mediaType, err := resources.Get(ts.URL+"/logo", 1024); err != nil || mediaType != "image/png" {
+		t.Errorf("Get(logo) = %q, %v, want the type sniffed", mediaType, err)
+	}
+	if _, _, err := resources.Get(ts.URL+"/big", 99); err == nil || !strings.Contains(err.Error(), "larger than 99 bytes") {
+		t.Errorf("Get(big) error = %v", err)
+	}
+	var statusErr *StatusError
+	if _, _, err := resources.Get(ts.URL+"/missing", 1024); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Get(missing) error = %v, want HTTP status 404", err)
+	}
+	for _, u := range []string{cdn.URL + "/logo.png", ts.URL + "/cdn"} {
+		gotCDN = nil
+		if _, _, err := resources.Get(u, 1024); err != nil {
+			t.Fatalf("Get(%s) error = %v", u, err)
+		}
+		if team := gotCDN.Header.Get("X-Team"); team != "" {
+			t.Errorf("Get(%s) sent X-Team = %q to another host", u, team)
+		}
+		if ua := gotCDN.Header.Get("User-Agent"); ua != "ned-test/1.0" {
+			t.Errorf("Get(%s) User-Agent = %q", u, ua)
+		}
+	}
+	if len(waited) != 6 || waited[0] != ts.URL+"/style.css" {
+		t.Errorf("waited for %q, want every request", waited)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resources, err = NewResources(ctx, ts.URL+"/article", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := resources.Get(ts.URL+"/style.css", 1024); !errors.Is(err, context.Canceled) {
+		t.Errorf("Get() after cancel error = %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package cleanpage
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// Rule picks the content of a site's pages by CSS selectors, for sites
+// where readability finds the wrong part of the page
+type Rule struct {
+	// Include selects the content. Readability is not used to find it
+	// when this matches.
+	Include []string `toml:"include"`
+	// Exclude selects elements removed from the page before its content
+	// is extracted
+	Exclude []string `toml:"exclude"`
+}
+
+// Rules maps domains to the rule for their pages. A domain's rule applies
+// to its subdomains too; the most specific domain wins.
+type Rules map[string]Rule
+
+// For returns the rule for pages on host
+func (r Rules) For(host string) Rule {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	var rule Rule
+	best := -1
+	for domain, candidate := range r {
+		domain = strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(domain), "."), ".")
+		if (host == domain || strings.HasSuffix(host, "."+domain)) && len(domain) > best {
+			rule, best = candidate, len(domain)
+		}
+	}
+	return rule
+}
+
+// Validate checks the selectors of every rule
+func (r Rules) Validate() error {
+	for domain, rule := range r {
+		for _, selector := range append(rule.Include, rule.Exclude...) {
+			if err := ValidateSelector(selector); err != nil {
+				return fmt.Errorf("%s: %w", domain, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateSelector checks that selector is a CSS selector cascadia
+// understands
+func ValidateSelector(selector string) error {
+	if _, err := cascadia.ParseGroup(selector); err != nil {
+		return fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+	return nil
+}
+
+// selectContent applies opts.Selector or the rule for the page's site to
+// page. Elements matching the exclude selectors are removed from the page
+// before anything is extracted from it. The elements matching the include
+// selectors are returned as the content; it is nil when readability is left
+// to find it. opts.Selector must match, a rule falls back to readability.
+func selectContent(page string, base *url.URL, opts Options) (string, *html.Node, error) {
+	rule := opts.Rules.For(base.Hostname())
+	include := rule.Include
+	if opts.Selector != "" {
+		include = []string{opts.Selector}
+	}
+	if len(include) == 0 && len(rule.Exclude) == 0 {
+		return page, nil, nil
+	}
+
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return "", nil, err
+	}
+	for _, selector := range rule.Exclude {
+		matches, err := queryAll(doc, selector)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, n := range matches {
+			if n.Parent != nil {
+				n.Parent.RemoveChild(n)
+			}
+		}
+	}
+	if page, err = renderHTML(doc); err != nil {
+		return "", nil, err
+	}
+
+	var selected []*html.Node
+	for _, selector := range include {
+		matches, err := queryAll(doc, selector)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, n := range matches {
+			// Elements inside ones already selected are in the content
+			if !insideAny(n, selected) {
+				selected = slices.DeleteFunc(selected, func(s *html.Node) bool { return insideAny(s, []*html.Node{n}) })
+				selected = append(selected, n)
+			}
+		}
+	}
+	if len(selected) == 0 {
+		if opts.Selector != "" {
+			return "", nil, fmt.Errorf("selector %q matches nothing on the page", opts.Selector)
+		}
+		return page, nil, nil
+	}
+
+	content := &html.Node{Type: html.ElementNode, Data: "div"}
+	for _, n := range selected {
+		n.Parent.RemoveChild(n)
+		absolutize(n, base)
+		content.AppendChild(n)
+	}
+	return page, content, nil
+}
+
+func renderHTML(n *html.Node) (string, error) {
+	var b strings.Builder
+	if err := html.Render(&b, n); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func queryAll(doc *html.Node, selector string) ([]*html.Node, error) {
+	group, err := cascadia.ParseGroup(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+	return cascadia.QueryAll(doc, group), nil
+}
+
+// insideAny reports whether n is one of nodes or inside one of them
+func insideAny(n *html.Node, nodes []*html.Node) bool {
+	for p := n; p != nil; p = p.Parent {
+		for _, node := range nodes {
+			if p == node {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// absolutize makes the links and image sources in n absolute, as
+// readability does for the content it finds
+func absolutize(n *html.Node, base *url.URL) {
+	if n.Type == html.ElementNode {
+		for i, a := range n.Attr {
+			if (a.Key == "href" || a.Key == "src") && !strings.HasPrefix(a.Val, "#") {
+				n.Attr[i].Val = resolve(base, a.Val)
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		absolutize(c, base)
+	}
+}
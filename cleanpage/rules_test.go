@@ -0,0 +1,134 @@
+package cleanpage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRulesFor(t *testing.T) {
+	rules := Rules{
+		"example.com":      {Include: []string{"main"}},
+		"docs.example.com": {Include: []string{".content"}},
+		"wiki":             {Exclude: []string{".edit"}},
+	}
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"example.com", "main"},
+		{"www.example.com", "main"},
+		{"docs.example.com", ".content"},
+		{"api.docs.example.com", ".content"},
+		{"DOCS.Example.com", ".content"},
+		{"notexample.com", ""},
+		{"wiki", ""},
+	}
+	for _, tt := range tests {
+		got := strings.Join(rules.For(tt.host).Include, ",")
+		if got != tt.want {
+			t.Errorf("For(%q) includes %q, want %q", tt.host, got, tt.want)
+		}
+	}
+	if rule := rules.For("wiki"); len(rule.Exclude) != 1 {
+		t.Errorf("For(wiki) = %+v", rule)
+	}
+}
+
+func TestValidateSelector(t *testing.T) {
+	for _, selector := range []string{"main .content", "article, .post > p", "#id:not(.x)"} {
+		if err := ValidateSelector(selector); err != nil {
+			t.Errorf("ValidateSelector(%q) = %v", selector, err)
+		}
+	}
+	for _, selector := range []string{"main[", "..content", ""} {
+		if err := ValidateSelector(selector); err == nil {
+			t.Errorf("ValidateSelector(%q) succeeded", selector)
+		}
+	}
+	if err := (Rules{"example.com": {Exclude: []string{"a["}}}).Validate(); err == nil || !strings.Contains(err.Error(), "example.com") {
+		t.Errorf("Validate() = %v, want an error naming the domain", err)
+	}
+}
+
+func TestFetchRules(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html>
+<html>
+<head><title>Install Guide</title></head>
+<body>
+<nav><a href="/">Docs home</a></nav>
+<main>
+<div class="content">
+<h1>Install</h1>
+<p>Download the <a href="/files/tool.tar.gz">release</a> and unpack it.</p>
+<div class="feedback">Was this page helpful?</div>
+</div>
+<aside class="related"><p>Related: configuring the tool, upgrading the tool and removing the tool from your system.</p></aside>
+</main>
+</body>
+</html>`))
+	}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+	host = host[:strings.Index(host, ":")]
+
+	t.Run("selector", func(t *testing.T) {
+		article, err := Fetch(context.Background(), ts.URL, Options{Strategy: StrategyHTTP, Selector: "main .content"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "# Install\n\nDownload the [release](" + ts.URL + "/files/tool.tar.gz) and unpack it.\n\nWas this page helpful?\n"
+		if article.Markdown != want {
+			t.Errorf("Markdown = %q, want %q", article.Markdown, want)
+		}
+		if article.Title != "Install Guide" {
+			t.Errorf("Title = %q, metadata still comes from the page", article.Title)
+		}
+		if article.Text != "Install Download the release and unpack it. Was this page helpful?" {
+			t.Errorf("Text = %q", article.Text)
+		}
+	})
+
+	t.Run("rule for the site", func(t *testing.T) {
+		rules := Rules{host: {Include: []string{".content", ".related"}, Exclude: []string{".feedback"}}}
+		article, err := Fetch(context.Background(), ts.URL, Options{Strategy: StrategyHTTP, Rules: rules})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(article.Markdown, "helpful") || !strings.Contains(article.Markdown, "Related:") || strings.Contains(article.Markdown, "Docs home") {
+			t.Errorf("Markdown = %q", article.Markdown)
+		}
+	})
+
+	t.Run("selector replaces the rule's include", func(t *testing.T) {
+		rules := Rules{host: {Include: []string{".related"}, Exclude: []string{".feedback"}}}
+		article, err := Fetch(context.Background(), ts.URL, Options{Strategy: StrategyHTTP, Rules: rules, Selector: "h1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if article.Markdown != "# Install\n" {
+			t.Errorf("Markdown = %q", article.Markdown)
+		}
+	})
+
+	t.Run("rule that matches nothing", func(t *testing.T) {
+		rules := Rules{host: {Include: []string{"#wiki-body"}}}
+		article, err := Fetch(context.Background(), ts.URL, Options{Strategy: StrategyHTTP, Rules: rules})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(article.Markdown, "unpack it") {
+			t.Errorf("readability is used when a rule matches nothing: %q", article.Markdown)
+		}
+	})
+
+	t.Run("selector that matches nothing", func(t *testing.T) {
+		if _, err := Fetch(context.Background(), ts.URL, Options{Strategy: StrategyHTTP, Selector: "#wiki-body"}); err == nil {
+			t.Error("Fetch() succeeded")
+		}
+	})
+}
@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	addTo        string
+	addUnder     string
+	addTimestamp bool
+)
+
+// defaultInbox is the note that add and append write to when neither a note
+// nor the INBOX config key is given
+const defaultInbox = "inbox"
+
+// addResult is the structured output of the add and append commands
+type addResult struct {
+	Note string `json:"note" yaml:"note"`
+	Path string `json:"path" yaml:"path"`
+	Line int    `json:"line" yaml:"line"`
+}
+
+var addCmd = &cobra.Command{
+	Use:   "add [text]",
+	Short: "Add a line of text to a note",
+	Long: `Quickly capture a thought without opening an editor. The text is appended to
+the inbox note, or to the note given with --to. With --under, it is added at
+the end of the section below that heading, which is created when missing.
+
+The inbox note is set by the INBOX config key and defaults to "inbox". Notes
+that do not exist yet are created. With --timestamp, or ADD_TIMESTAMP=true
+in the config, the text is added as a bullet starting with the current time.
+
+Examples:
+  ned add "Call the plumber"
+  ned add "Try a CRDT for sync" --to projects/app --under "## Ideas"`,
+	Aliases: []string{"a"},
+	Args:    cobra.MinimumNArgs(1),
+	RunE:    runAdd,
+}
+
+var appendCmd = &cobra.Command{
+	Use:   "append [note]",
+	Short: "Append text from stdin to a note",
+	Long: `Append the text piped into ned to a note, the inbox note by default. Unlike
+piping into edit, the note's existing content is kept.
+
+Example:
+  pbpaste | ned append reading-list --under "## To read"`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runAppend,
+}
+
+func init() {
+	for _, c := range []*cobra.Command{addCmd, appendCmd} {
+		c.Flags().StringVarP(&addUnder, "under", "u", "", `Add the text under this heading, e.g. "## Ideas"`)
+		c.Flags().BoolVarP(&addTimestamp, "timestamp", "T", false, "Prefix the text with a timestamp bullet")
+	}
+	addCmd.Flags().StringVar(&addTo, "to", "", "Note to add the text to instead of the inbox")
+	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(appendCmd)
+}
+
+func runAdd(cmd *cobra.Command, args []string) error {
+	return addText(cmd, addTo, strings.Join(args, " "))
+}
+
+func runAppend(cmd *cobra.Command, args []string) error {
+	if stdinIsTerminal() {
+		return newCmdError(codeUsage, "pipe the text to append into ned, e.g. echo text | ned append")
+	}
+	text, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read stdin: %w", err)
+	}
+
+	note := ""
+	if len(args) > 0 {
+		note = args[0]
+	}
+	return addText(cmd, note, string(text))
+}
+
+// addText inserts text into a note, the inbox when note is empty, and
+// reports where it went
+func addText(cmd *cobra.Command, note, text string) error {
+	text = strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if strings.TrimSpace(text) == "" {
+		return newCmdError(codeUsage, "nothing to add")
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return newCmdError(codeConfig, "failed to load config: %w", err)
+	}
+	if note == "" {
+		note = config.Values["INBOX"]
+	}
+	if note == "" {
+		note = defaultInbox
+	}
+
+	timestamp := config.Values["ADD_TIMESTAMP"] == "true"
+	if cmd.Flags().Changed("timestamp") {
+		timestamp = addTimestamp
+	}
+	if timestamp {
+		text = timestampBullet(text, time.Now())
+	}
+
+	notePath := noteArgPath(note)
+	if _, err := checkInNotesDir(notePath); err != nil {
+		return err
+	}
+	lock, err := lockNote(notePath, "add")
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	if _, err := os.Stat(notePath); os.IsNotExist(err) {
+		if err := createNote(notePath); err != nil {
+			return err
+		}
+	}
+
+	content, err := os.ReadFile(notePath)
+	if err != nil {
+		return fmt.Errorf("failed to read note: %w", err)
+	}
+	updated, line := insertText(string(content), text, addUnder)
+
+	if err := writeFileAtomic(notePath, []byte(updated), filePerm(notePath, 0644), true); err != nil {
+		return fmt.Errorf("failed to write note: %w", err)
+	}
+
+	name := strings.TrimSuffix(filepath.ToSlash(note), ".md")
+	if structuredOutput() {
+		return printResult(addResult{Note: name, Path: notePath, Line: line})
+	}
+	fmt.Printf("Added to %s:%d\n", name, line)
+	return nil
+}
+
+// timestampBullet turns text into a list item starting with the time.
+// Continuation lines are indented to stay inside the item.
+func timestampBullet(text string, now time.Time) string {
+	return "- " + now.Format("2006-01-02 15:04") + " " + strings.ReplaceAll(text, "\n", "\n  ")
+}
+
+// insertText adds text to the end of content, or to the end of the section
+// below heading. A heading given with its #s must match exactly; a bare
+// heading matches any level, ignoring case. A missing section is appended
+// as a level 2 heading. It returns the new content and the line the text
+// starts on.
+func insertText(content, text, heading string) (string, int) {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	end := len(lines)
+	if heading != "" {
+		start, level := findHeading(lines, heading)
+		if start < 0 {
+			if !strings.HasPrefix(heading, "#") {
+				heading = "## " + heading
+			}
+			lines = appendBlock(lines, []string{heading})
+			end = len(lines)
+		} else {
+			end = sectionEnd(lines, start, level)
+		}
+	}
+
+	// Insert after the section's last non-blank line
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	before := append([]string{}, lines[:end]...)
+	after := lines[end:]
+
+	before = appendBlock(before, strings.Split(text, "\n"))
+	line := len(before) - strings.Count(text, "\n")
+	if len(after) > 0 {
+		before = append(before, "")
+		for len(after) > 0 && strings.TrimSpace(after[0]) == "" {
+			after = after[1:]
+		}
+	}
+	return strings.Join(append(before, after...), "\n") + "\n", line
+}
+
+// appendBlock appends block to lines, separated by a blank line unless both
+// the last line and the block are list items
+func appendBlock(lines, block []string) []string {
+	if len(lines) > 0 && !(isListItem(lines[len(lines)-1]) && isListItem(block[0])) {
+		lines = append(lines, "")
+	}
+	return append(lines, block...)
+}
+
+func isListItem(line string) bool {
+	trimmed := strings.TrimLeft(line, " \t")
+	for _, marker := range []string{"- ", "* ", "+ "} {
+		if strings.HasPrefix(trimmed, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// findHeading returns the line index and level of heading, or -1. Headings
+// inside code fences are ignored.
+func findHeading(lines []string, heading string) (int, int) {
+	wantLevel := len(heading) - len(strings.TrimLeft(heading, "#"))
+	wantText := strings.TrimSpace(heading[wantLevel:])
+
+	inFence := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		m := headingRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		level := len(line) - len(strings.TrimLeft(line, "#"))
+		if wantLevel > 0 {
+			if level == wantLevel && m[1] == wantText {
+				return i, level
+			}
+		} else if strings.EqualFold(m[1], wantText) {
+			return i, level
+		}
+	}
+	return -1, 0
+}
+
+// sectionEnd returns the index of the first line after the section that
+// starts at start: the next heading of the same or a higher level
+func sectionEnd(lines []string, start, level int) int {
+	inFence := false
+	for i := start + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || headingRe.FindStringSubmatch(lines[i]) == nil {
+			continue
+		}
+		if l := len(lines[i]) - len(strings.TrimLeft(lines[i], "#")); l <= level {
+			return i
+		}
+	}
+	return len(lines)
+}
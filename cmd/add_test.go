@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertText(t *testing.T) {
+	note := "# Project\n\nIntro.\n\n## Ideas\n\n- first\n- second\n\n## Done\n\n- shipped\n"
+
+	tests := []struct {
+		name     string
+		content  string
+		text     string
+		heading  string
+		want     string
+		wantLine int
+	}{
+		{
+			name:     "empty note",
+			content:  "",
+			text:     "hello",
+			want:     "hello\n",
+			wantLine: 1,
+		},
+		{
+			name:     "paragraph at the end",
+			content:  "# Inbox\n\nsome text\n\n\n",
+			text:     "more text",
+			want:     "# Inbox\n\nsome text\n\nmore text\n",
+			wantLine: 5,
+		},
+		{
+			name:     "list item continues a list",
+			content:  "# Inbox\n\n- one\n",
+			text:     "- two",
+			want:     "# Inbox\n\n- one\n- two\n",
+			wantLine: 4,
+		},
+		{
+			name:     "under heading with level",
+			content:  note,
+			text:     "- third",
+			heading:  "## Ideas",
+			want:     "# Project\n\nIntro.\n\n## Ideas\n\n- first\n- second\n- third\n\n## Done\n\n- shipped\n",
+			wantLine: 9,
+		},
+		{
+			name:     "under bare heading ignoring case",
+			content:  note,
+			text:     "Later.",
+			heading:  "done",
+			want:     "# Project\n\nIntro.\n\n## Ideas\n\n- first\n- second\n\n## Done\n\n- shipped\n\nLater.\n",
+			wantLine: 14,
+		},
+		{
+			name:     "section includes subsections",
+			content:  "# A\n\n## B\n\n### C\n\ntext\n\n## D\n",
+			text:     "- x",
+			heading:  "## B",
+			want:     "# A\n\n## B\n\n### C\n\ntext\n\n- x\n\n## D\n",
+			wantLine: 9,
+		},
+		{
+			name:     "missing heading is created",
+			content:  "# Inbox\n\n- one\n",
+			text:     "- idea",
+			heading:  "Ideas",
+			want:     "# Inbox\n\n- one\n\n## Ideas\n\n- idea\n",
+			wantLine: 7,
+		},
+		{
+			name:     "headings in code fences are ignored",
+			content:  "# A\n\n```\n## Ideas\n```\n",
+			text:     "x",
+			heading:  "## Ideas",
+			want:     "# A\n\n```\n## Ideas\n```\n\n## Ideas\n\nx\n",
+			wantLine: 9,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, line := insertText(tt.content, tt.text, tt.heading)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantLine, line)
+		})
+	}
+}
+
+func TestTimestampBullet(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 5, 0, 0, time.Local)
+	assert.Equal(t, "- 2026-10-18 09:05 call Bob", timestampBullet("call Bob", now))
+	assert.Equal(t, "- 2026-10-18 09:05 line one\n  line two", timestampBullet("line one\nline two", now))
+}
+
+func TestAddAndAppend(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, map[string]string{"INBOX": "capture/inbox"})
+
+	defer func() { addTo, addUnder = "", "" }()
+
+	_, err := captureStdout(t, func() error { return runAdd(addCmd, []string{"buy", "milk"}) })
+	require.NoError(t, err)
+
+	inbox := filepath.Join(tmpDir, "capture", "inbox.md")
+	content, err := os.ReadFile(inbox)
+	require.NoError(t, err)
+	assert.Equal(t, "# inbox\n\nbuy milk\n", string(content), "the configured inbox is created")
+
+	addTo, addUnder = "work", "## Ideas"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "work.md"), []byte("# Work\n\n## Ideas\n\n- a\n\n## Log\n"), 0644))
+	require.NoError(t, addCmd.Flags().Set("timestamp", "true"))
+	defer func() {
+		addCmd.Flags().Set("timestamp", "false")
+		addCmd.Flags().Lookup("timestamp").Changed = false
+	}()
+
+	out, err := captureStdout(t, func() error { return runAdd(addCmd, []string{"b"}) })
+	require.NoError(t, err)
+	assert.Equal(t, "Added to work:6\n", out)
+	content, err = os.ReadFile(filepath.Join(tmpDir, "work.md"))
+	require.NoError(t, err)
+	assert.Regexp(t, `^# Work\n\n## Ideas\n\n- a\n- \d{4}-\d{2}-\d{2} \d{2}:\d{2} b\n\n## Log\n$`, string(content))
+
+	t.Run("append reads stdin", func(t *testing.T) {
+		addUnder = ""
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		oldStdin := os.Stdin
+		os.Stdin = r
+		defer func() { os.Stdin = oldStdin }()
+		w.WriteString("piped\ntext\n")
+		w.Close()
+
+		_, err = captureStdout(t, func() error { return runAppend(appendCmd, nil) })
+		require.NoError(t, err)
+		content, err := os.ReadFile(inbox)
+		require.NoError(t, err)
+		assert.Equal(t, "# inbox\n\nbuy milk\n\npiped\ntext\n", string(content), "existing content is kept")
+	})
+
+	t.Run("append needs piped input", func(t *testing.T) {
+		setupTestPrompter(t)
+		err := runAppend(appendCmd, []string{"work"})
+		assert.Equal(t, codeUsage, errorCode(err))
+	})
+}
+
+func TestAddOutsideNotesDir(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, nil)
+	defer func() { addTo = "" }()
+
+	outside := filepath.Join(t.TempDir(), "foo.md")
+	require.NoError(t, os.WriteFile(outside, []byte("# Foo\n"), 0644))
+
+	for _, to := range []string{outside, "../foo", "a/../../foo"} {
+		addTo = to
+		err := runAdd(addCmd, []string{"text"})
+		assert.Equal(t, codeInvalidPath, errorCode(err), to)
+	}
+	content, err := os.ReadFile(outside)
+	require.NoError(t, err)
+	assert.Equal(t, "# Foo\n", string(content))
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(tmpDir), "foo.md"))
+
+	t.Run("append", func(t *testing.T) {
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		oldStdin := os.Stdin
+		os.Stdin = r
+		defer func() { os.Stdin = oldStdin }()
+		w.WriteString("text\n")
+		w.Close()
+
+		err = runAppend(appendCmd, []string{"../../x"})
+		assert.Equal(t, codeInvalidPath, errorCode(err))
+		assert.NoFileExists(t, filepath.Join(tmpDir, "..", "..", "x.md"))
+	})
+
+	t.Run("absolute path inside the notes directory", func(t *testing.T) {
+		addTo = filepath.Join(tmpDir, "inside")
+		_, err := captureStdout(t, func() error { return runAdd(addCmd, []string{"text"}) })
+		require.NoError(t, err)
+		assert.FileExists(t, filepath.Join(tmpDir, "inside.md"))
+	})
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic writes data to path so that readers, and a crash or a
+// full disk, never see a partially written file. Without overwrite it
+// fails with an error matching fs.ErrExist when path already exists.
+func writeFileAtomic(path string, data []byte, perm os.FileMode, overwrite bool) error {
+	return writeAtomic(path, perm, overwrite, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeAtomic streams a file's content from write into a temporary file in
+// the target's directory, syncs it to disk and moves it into place. A
+// symlinked target has the file it points to replaced.
+func writeAtomic(path string, perm os.FileMode, overwrite bool, write func(w io.Writer) error) (err error) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if !overwrite {
+		if _, err := os.Lstat(path); err == nil {
+			return fmt.Errorf("%s: %w", path, fs.ErrExist)
+		}
+	}
+
+	dir, base := filepath.Split(path)
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpName)
+		}
+	}()
+
+	if err := write(tmp); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+
+	if overwrite {
+		if err := os.Rename(tmpName, path); err != nil {
+			return err
+		}
+	} else if err := moveNoReplace(tmpName, path); err != nil {
+		return err
+	}
+
+	syncDir(dir)
+	return nil
+}
+
+// moveNoReplace moves src to dst unless dst exists. A hard link makes the
+// check and the move one step; filesystems without hard links fall back to
+// checking first.
+func moveNoReplace(src, dst string) error {
+	err := os.Link(src, dst)
+	if err == nil {
+		return os.Remove(src)
+	}
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s: %w", dst, fs.ErrExist)
+	}
+	if _, statErr := os.Lstat(dst); statErr == nil {
+		return fmt.Errorf("%s: %w", dst, fs.ErrExist)
+	}
+	return os.Rename(src, dst)
+}
+
+// syncDir flushes a directory entry change to disk. Not every platform can
+// sync a directory, and the file itself is already safe, so errors are
+// ignored.
+func syncDir(dir string) {
+	if dir == "" {
+		dir = "."
+	}
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// filePerm returns the permissions of an existing file, or perm for a new one
+func filePerm(path string, perm os.FileMode) os.FileMode {
+	if info, err := os.Stat(path); err == nil {
+		return info.Mode().Perm()
+	}
+	return perm
+}
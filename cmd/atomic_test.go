@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "note.md")
+
+	t.Run("creates a new file", func(t *testing.T) {
+		require.NoError(t, writeFileAtomic(path, []byte("first\n"), 0644, false))
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "first\n", string(content))
+	})
+
+	t.Run("refuses to replace without overwrite", func(t *testing.T) {
+		err := writeFileAtomic(path, []byte("second\n"), 0644, false)
+		assert.True(t, errors.Is(err, fs.ErrExist), "got %v", err)
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "first\n", string(content))
+	})
+
+	t.Run("overwrite keeps the mode", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("file modes are not kept on Windows")
+		}
+		require.NoError(t, os.Chmod(path, 0600))
+		require.NoError(t, writeFileAtomic(path, []byte("third\n"), filePerm(path, 0644), true))
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "third\n", string(content))
+	})
+
+	t.Run("symlinks are kept", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("symlinks need privileges on Windows")
+		}
+		link := filepath.Join(dir, "link.md")
+		require.NoError(t, os.Symlink(path, link))
+		require.NoError(t, writeFileAtomic(link, []byte("via link\n"), 0644, true))
+
+		info, err := os.Lstat(link)
+		require.NoError(t, err)
+		assert.NotZero(t, info.Mode()&os.ModeSymlink, "link was replaced by a file")
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "via link\n", string(content))
+	})
+
+	t.Run("failed writes leave nothing behind", func(t *testing.T) {
+		err := writeAtomic(filepath.Join(dir, "broken.md"), 0644, false, func(w io.Writer) error {
+			w.Write([]byte("partial"))
+			return errors.New("disk on fire")
+		})
+		assert.EqualError(t, err, "disk on fire")
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		assert.ElementsMatch(t, []string{"note.md", "link.md"}, names)
+	})
+}
@@ -1,36 +1,202 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/http"
+	neturl "net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"ned/ainote"
 	"ned/cleanpage"
 
+	"github.com/BurntSushi/toml"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
+var (
+	clipForce      bool
+	clipAppend     bool
+	clipMode       string
+	clipImages     bool
+	clipArchive    bool
+	clipScreenshot bool
+	clipNew        bool
+	clipFrom       string
+	clipCombine    bool
+	clipJobs       int
+	clipRate       time.Duration
+	clipList       bool
+	clipRefresh    bool
+	clipAgain      bool
+
+	// How pages are downloaded, overriding the CLIP_* config keys
+	clipFetcher   string
+	clipUserAgent string
+	clipTimeout   time.Duration
+	clipProxy     string
+	clipHeaders   []string
+	clipCookies   string
+	clipSelector  string
+)
+
+// Clip modes: the full article as markdown, an AI summary, or both
+const (
+	clipModeFull    = "full"
+	clipModeSummary = "summary"
+	clipModeBoth    = "both"
+)
+
+var clipModes = []string{clipModeFull, clipModeSummary, clipModeBoth}
+
+// clipResult is the structured output of the clip command
+type clipResult struct {
+	Note   string   `json:"note" yaml:"note"`
+	Path   string   `json:"path" yaml:"path"`
+	URL    string   `json:"url" yaml:"url"`
+	Title  string   `json:"title,omitempty" yaml:"title,omitempty"`
+	Images []string `json:"images,omitempty" yaml:"images,omitempty"`
+	// Archive is the archived copy of the page, relative to the note
+	Archive string `json:"archive,omitempty" yaml:"archive,omitempty"`
+	// Screenshot is the image of the page in the note folder's ._images_
+	Screenshot string `json:"screenshot,omitempty" yaml:"screenshot,omitempty"`
+}
+
+// clipMeta is the front matter of a clipped note
+type clipMeta struct {
+	Title     string `yaml:"title,omitempty"`
+	Author    string `yaml:"author,omitempty"`
+	Site      string `yaml:"site,omitempty"`
+	Source    string `yaml:"source"`
+	Published string `yaml:"published,omitempty"`
+	Clipped   string `yaml:"clipped"`
+	Language  string `yaml:"language,omitempty"`
+	Image     string `yaml:"image,omitempty"`
+	Excerpt   string `yaml:"excerpt,omitempty"`
+}
+
 var clipCmd = &cobra.Command{
 	Use:   "clip [note] [url]",
 	Short: "Clip a webpage to a note",
-	Long: `Clip a webpage to a note. If ANTHROPIC_API_KEY is set in config, 
-it will download and summarize the content. Otherwise, it will just save the URL.
+	Long: `Clip a webpage to a note. The page's main content is extracted and saved
+according to --mode:
+
+  full     the article as markdown, keeping headings, lists, links and code
+  summary  a summary written by Claude, which needs ANTHROPIC_API_KEY
+  both     the summary followed by the full article
+
+The default is summary when ANTHROPIC_API_KEY is set in config and full
+otherwise.
+
+When the note already exists, clip asks whether to append the page to it.
+--append adds it without asking, as a section under a "## <page title>"
+heading with its own Source line, so a note can collect several pages.
+--force replaces the note and --new fails instead.
 
-Example:
-  ned clip mynote https://example.com`,
-	Args: cobra.ExactArgs(2),
+With --from, every URL in a file (or on stdin with --from -) is clipped,
+one per line; blank lines and lines starting with # are skipped. Each page
+becomes a note named after its title in the folder given as argument, or
+with --combine a section of the one note given. Pages are downloaded by
+--jobs workers at once, and no host is asked more often than --rate allows,
+for pages as well as the images and archived resources they need. A page
+whose note already exists gets a numbered name unless --force or --append
+is given. The end of the run lists the pages that failed and which of
+them are worth retrying.
+
+ned remembers which note each page was clipped to, by its URL without
+fragments or tracking parameters, and after redirects and canonical links.
+Clipping a page again offers to open that note, refresh it with the current
+page, or clip the page anyway; --refresh and --again decide up front, --yes
+refreshes it, and batches skip pages clipped before unless --again is given. --list shows
+every clipped page.
+
+--selector picks the content with a CSS selector instead of readability.
+Sites can get their own selectors in clip-rules.toml in the config
+directory, a table per domain that also covers its subdomains:
+
+  ["docs.example.com"]
+  include = ["main .content"]      # the content, instead of readability
+  exclude = [".feedback", "aside"] # removed from the page first
+
+--screenshot saves a full-page screenshot into the note folder's ._images_
+directory and shows it at the top of the note; it needs Chrome or
+Chromium, and the page is clipped without one when neither is installed.
+--archive also saves a single-file HTML snapshot of the page, with its
+stylesheets and images inlined, in the note folder's ._archive_ directory,
+and links it from the note as the "archived copy".
+
+PDFs, plain text and markdown files are clipped too: the text of a PDF is
+extracted, text files become paragraphs, and markdown is kept as it is.
+Text in other encodings than UTF-8 is decoded by its declared charset, or
+a guess when it has none. Only webpages are archived.
+
+Examples:
+  ned clip mynote https://example.com
+  ned clip reading/article https://example.com/post --mode both
+  ned clip reading/article https://example.com/post --archive
+  ned clip papers/report https://example.com/files/report.pdf
+  ned clip docs/install https://docs.example.com/install --selector 'main .content'
+  ned clip --from links.txt reading
+  pbpaste | ned clip --from - --combine meetings/2026-10-18-links`,
+	Args: cobra.RangeArgs(0, 2),
 	RunE: runClip,
 }
 
 func init() {
+	clipCmd.Flags().BoolVarP(&clipForce, "force", "f", false, "Overwrite the note if it already exists")
+	clipCmd.Flags().BoolVarP(&clipAppend, "append", "a", false, "Append the clip to the note if it already exists")
+	clipCmd.Flags().StringVarP(&clipMode, "mode", "m", "", "What to save: full, summary or both (default summary with an API key, full without)")
+	clipCmd.Flags().BoolVarP(&clipImages, "images", "i", false, "Download the article's images into the note folder's ._images_ directory")
+	clipCmd.Flags().BoolVar(&clipArchive, "archive", false, "Save a single-file HTML snapshot of the page in the note folder's ._archive_ directory")
+	clipCmd.Flags().BoolVar(&clipScreenshot, "screenshot", false, "Save a screenshot of the whole page into the note folder's ._images_ directory and show it at the top of the note; needs Chrome")
+	clipCmd.Flags().BoolVarP(&clipNew, "new", "n", false, "Fail if the note already exists instead of asking")
+	clipCmd.Flags().StringVar(&clipFrom, "from", "", "Clip every URL listed in a file, one per line; - reads them from stdin")
+	clipCmd.Flags().BoolVar(&clipCombine, "combine", false, "With --from, clip all pages into one note instead of a note each")
+	clipCmd.Flags().IntVarP(&clipJobs, "jobs", "j", 4, "With --from, how many pages to download at once")
+	clipCmd.Flags().DurationVar(&clipRate, "rate", time.Second, "With --from, the least time between two requests to the same host")
+	clipCmd.Flags().BoolVarP(&clipList, "list", "l", false, "List the pages that have been clipped and their notes")
+	clipCmd.Flags().BoolVar(&clipRefresh, "refresh", false, "Update the note a page was clipped to before instead of asking")
+	clipCmd.Flags().BoolVar(&clipAgain, "again", false, "Clip a page even if it was clipped before")
+	clipCmd.Flags().StringVar(&clipFetcher, "fetcher", "", "How to download pages: auto, chrome or http (default auto)")
+	clipCmd.Flags().StringVar(&clipUserAgent, "user-agent", "", "User agent to send")
+	clipCmd.Flags().DurationVar(&clipTimeout, "timeout", 0, "Time limit for downloading a page (default 30s)")
+	clipCmd.Flags().StringVar(&clipProxy, "proxy", "", "Proxy URL to download pages through")
+	clipCmd.Flags().StringArrayVarP(&clipHeaders, "header", "H", nil, "Extra request header for the page's host as \"Name: value\"; may be repeated")
+	clipCmd.Flags().StringVar(&clipSelector, "selector", "", "CSS selector of the content to clip, instead of finding it with readability")
+	clipCmd.Flags().StringVar(&clipCookies, "cookies", "", "Cookies file in the Netscape cookies.txt format to send cookies from")
+	clipCmd.MarkFlagsMutuallyExclusive("force", "append", "new")
+	clipCmd.MarkFlagsMutuallyExclusive("refresh", "again")
 	rootCmd.AddCommand(clipCmd)
 }
 
 func runClip(cmd *cobra.Command, args []string) error {
+	if clipMode != "" && !slices.Contains(clipModes, clipMode) {
+		return newCmdError(codeUsage, "invalid mode %q, use one of %s", clipMode, strings.Join(clipModes, ", "))
+	}
+	if clipList {
+		if len(args) > 0 {
+			return newCmdError(codeUsage, "--list takes no arguments")
+		}
+		return runClipList()
+	}
+	if clipFrom != "" {
+		return runClipBatch(args)
+	}
+	if clipCombine {
+		return newCmdError(codeUsage, "--combine needs a list of URLs given with --from")
+	}
 	if len(args) != 2 {
-		return fmt.Errorf("accepts 2 arg(s), received %d", len(args))
+		return newCmdError(codeUsage, "accepts 2 arg(s), received %d", len(args))
 	}
 	noteName := args[0]
 	url := args[1]
@@ -48,52 +214,554 @@ func runClip(cmd *cobra.Command, args []string) error {
 
 	// Create full path for the note
 	notePath := filepath.Join(absNotesDir, noteName)
+	if _, err := checkInNotesDir(notePath); err != nil {
+		return err
+	}
 
-	// Check if the note's directory exists, create if not
-	noteDir := filepath.Dir(notePath)
-	if err := os.MkdirAll(noteDir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+	settings, err := clipSettings()
+	if err != nil {
+		return err
+	}
+
+	// A page clipped before may be opened or refreshed instead
+	index, err := loadClipIndex()
+	if err != nil {
+		return err
+	}
+	var refresh *clipEntry
+	known := func(urls ...string) (bool, error) {
+		if clipAgain || refresh != nil {
+			return false, nil
+		}
+		entry, ok := index.lookup(urls...)
+		if !ok {
+			return false, nil
+		}
+		again, done, err := knownClip(entry)
+		if again {
+			refresh = &entry
+			noteName = entry.Note + ".md"
+			notePath = filepath.Join(absNotesDir, noteName)
+		}
+		return done, err
+	}
+	if done, err := known(url); done || err != nil {
+		return err
+	}
+
+	// Settle what happens to an existing note before fetching anything
+	var exists, appendClip bool
+	if refresh != nil {
+		exists, appendClip = true, refresh.Section
+	} else if exists, appendClip, err = clipTarget(noteName, notePath); err != nil {
+		return err
 	}
 
+	// Download and clean the webpage content
+	article, err := cleanpage.Fetch(context.Background(), url, settings.fetch)
+	if err != nil {
+		return newCmdError(codeFetchFailed, "failed to download webpage: %w", err)
+	}
+
+	// Redirects and canonical links may lead to a page clipped before
+	if refresh == nil {
+		if done, err := known(article.URL, article.Canonical); done || err != nil {
+			return err
+		}
+		if refresh != nil {
+			exists, appendClip = true, refresh.Section
+		}
+	}
+
+	level := 1
+	if appendClip {
+		level = 2
+	}
+	body, err := clipBody(article, settings.mode, settings.apiKey, strings.TrimSuffix(filepath.Base(noteName), ".md"), level)
+	if err != nil {
+		return err
+	}
+
+	var screenshot string
+	if screenshotsWanted() {
+		png, err := takeScreenshot(context.Background(), clipSource(article, url), settings.fetch)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not take a screenshot of %s: %v\n", url, err)
+		} else if body, screenshot, err = addScreenshot(body, png, notePath, clipFileName(noteName, article.Title, appendClip, "-screenshot.png"), article.Title); err != nil {
+			return err
+		}
+	}
+
+	var archive string
+	if archiveWanted(article) {
+		snapshot, err := cleanpage.Archive(context.Background(), article, settings.fetch)
+		if err != nil {
+			return fmt.Errorf("failed to archive webpage: %w", err)
+		}
+		if archive, err = saveArchive(snapshot, notePath, clipFileName(noteName, article.Title, appendClip, ".html")); err != nil {
+			return err
+		}
+	}
+
+	content, images, err := clipSection(body, clipSource(article, url), archive, filepath.Dir(notePath), settings.fetch)
+	if err != nil {
+		return err
+	}
+	update := func(existing string) (string, error) {
+		frontMatter, err := clipFrontMatter(article, clipSource(article, url), time.Now())
+		return frontMatter + content, err
+	}
+	if refresh != nil && appendClip {
+		// The section is under the title the page had back then
+		heading := refresh.Title
+		if heading == "" {
+			heading = strings.TrimSuffix(filepath.Base(noteName), ".md")
+		}
+		update = func(existing string) (string, error) {
+			return replaceSection(existing, "## "+heading, content), nil
+		}
+	} else if appendClip {
+		update = appendClipUpdate(content)
+	}
+	if err := writeClip(notePath, noteName, exists, update); err != nil {
+		return err
+	}
+	if err := recordClip(newClipEntry(article, url, noteName, appendClip), url, article.URL, article.Canonical); err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printResult(clipResult{Note: strings.TrimSuffix(noteName, ".md"), Path: notePath, URL: url, Title: article.Title, Images: images.Saved, Archive: archive, Screenshot: screenshot})
+	}
+
+	if refresh != nil {
+		fmt.Printf("Refreshed note: %s\n", noteName)
+	} else if appendClip {
+		fmt.Printf("Appended to note: %s\n", noteName)
+	} else {
+		fmt.Printf("Created note: %s\n", noteName)
+	}
+	if clipImages {
+		fmt.Printf("Saved %d images to %s", len(images.Saved), filepath.Join(filepath.Dir(noteName), "._images_"))
+		if images.Skipped > 0 || images.Failed > 0 {
+			fmt.Printf(" (%d trackers skipped, %d not downloaded)", images.Skipped, images.Failed)
+		}
+		fmt.Println()
+	}
+	if screenshot != "" {
+		fmt.Printf("Saved a screenshot to %s\n", filepath.Join(filepath.Dir(noteName), "._images_", screenshot))
+	}
+	if archive != "" {
+		fmt.Printf("Archived the page to %s\n", filepath.Join(filepath.Dir(noteName), archive))
+	}
+	return nil
+}
+
+// clipTarget decides what happens to the note at notePath: whether it
+// exists and whether the clip is appended to it. An existing note is
+// replaced with --force, appended to with --append and refused with --new;
+// otherwise the user is asked whether to append.
+func clipTarget(noteName, notePath string) (exists, appendClip bool, err error) {
+	_, statErr := os.Stat(notePath)
+	exists = statErr == nil
+	if !exists || clipForce {
+		return exists, false, nil
+	}
+	if clipAppend {
+		return true, true, nil
+	}
+	if clipNew {
+		return true, false, newCmdError(codeAlreadyExists, "note already exists: %s", noteName)
+	}
+	ok, err := confirm(fmt.Sprintf("Note %s already exists. Append the page to it?", noteName))
+	if err != nil && !errors.Is(err, errNoInput) {
+		return true, false, err
+	}
+	if !ok {
+		return true, false, newCmdError(codeAlreadyExists, "note already exists: %s (use --force to overwrite it or --append to add to it)", noteName)
+	}
+	return true, true, nil
+}
+
+// clipConfig is how clip works, from config and flags
+type clipConfig struct {
+	mode   string
+	apiKey string
+	fetch  cleanpage.Options
+}
+
+// clipSettings returns the clip mode to use, the API key it needs and how
+// to download pages. Flags override the config keys.
+func clipSettings() (*clipConfig, error) {
 	// Load config to check for API key
 	config, err := loadConfig()
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return nil, newCmdError(codeConfig, "failed to load config: %w", err)
+	}
+
+	settings := &clipConfig{apiKey: config.Values["ANTHROPIC_API_KEY"], mode: clipMode}
+	if settings.mode == "" {
+		settings.mode = clipModeFull
+		if settings.apiKey != "" {
+			settings.mode = clipModeSummary
+		}
+	}
+	if settings.mode != clipModeFull && settings.apiKey == "" {
+		return nil, newCmdError(codeConfig, "--mode %s needs ANTHROPIC_API_KEY, set it with: ned config set ANTHROPIC_API_KEY <key>", settings.mode)
+	}
+
+	if settings.fetch, err = fetchOptions(config); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// fetchOptions returns how to download pages from the CLIP_* config keys
+// and the flags that override them. Headers from both are sent.
+func fetchOptions(config *Config) (cleanpage.Options, error) {
+	setting := func(flag, key string) string {
+		if flag != "" {
+			return flag
+		}
+		return config.Values[key]
+	}
+	opts := cleanpage.Options{
+		Strategy:  setting(clipFetcher, "CLIP_FETCHER"),
+		UserAgent: setting(clipUserAgent, "CLIP_USER_AGENT"),
+		Proxy:     setting(clipProxy, "CLIP_PROXY"),
+		Timeout:   clipTimeout,
+	}
+
+	if opts.Strategy != "" && !slices.Contains(cleanpage.Strategies, opts.Strategy) {
+		return opts, newCmdError(codeConfig, "invalid fetcher %q, use one of %s", opts.Strategy, strings.Join(cleanpage.Strategies, ", "))
+	}
+	if timeout := config.Values["CLIP_TIMEOUT"]; opts.Timeout == 0 && timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return opts, newCmdError(codeConfig, "invalid CLIP_TIMEOUT %q, use a duration such as 45s or 2m", timeout)
+		}
+		opts.Timeout = d
+	}
+	if opts.Proxy != "" {
+		if u, err := neturl.Parse(opts.Proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			return opts, newCmdError(codeConfig, "invalid proxy %q, use a URL such as http://proxy:8080", opts.Proxy)
+		}
+	}
+
+	headers := strings.Split(config.Values["CLIP_HEADERS"], "\n")
+	for _, header := range append(headers, clipHeaders...) {
+		if strings.TrimSpace(header) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return opts, newCmdError(codeConfig, "invalid header %q, use \"Name: value\"", header)
+		}
+		if opts.Header == nil {
+			opts.Header = http.Header{}
+		}
+		opts.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	// A cookies file in config is relative to ~/.config/ned, like
+	// VIEW_THEME_DIR
+	cookies := clipCookies
+	if cookies == "" && config.Values["CLIP_COOKIES"] != "" {
+		cookies = config.Values["CLIP_COOKIES"]
+		if !filepath.IsAbs(cookies) {
+			configPath, err := getConfigPath()
+			if err != nil {
+				return opts, err
+			}
+			cookies = filepath.Join(filepath.Dir(configPath), cookies)
+		}
+	}
+	if cookies != "" {
+		loaded, err := cleanpage.LoadCookies(cookies)
+		if err != nil {
+			return opts, newCmdError(codeConfig, "failed to load cookies: %w", err)
+		}
+		opts.Cookies = loaded
+	}
+
+	if clipSelector != "" {
+		if err := cleanpage.ValidateSelector(clipSelector); err != nil {
+			return opts, newCmdError(codeUsage, "%w", err)
+		}
+		opts.Selector = clipSelector
+	}
+	rules, err := loadClipRules()
+	if err != nil {
+		return opts, err
 	}
+	opts.Rules = rules
+	return opts, nil
+}
+
+// clipRulesName is the file in the config directory with the selectors
+// that pick the content of pages on particular sites
+const clipRulesName = "clip-rules.toml"
 
-	var content string
-	if apiKey, exists := config.Values["ANTHROPIC_API_KEY"]; exists && apiKey != "" {
-		// Download and clean the webpage content
-		pageContent, err := cleanpage.CrawlPage(url)
+// loadClipRules reads the site rules of clip. There are none without a
+// rules file.
+func loadClipRules() (cleanpage.Rules, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(filepath.Dir(configPath), clipRulesName)
+
+	rules := cleanpage.Rules{}
+	meta, err := toml.DecodeFile(path, &rules)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, newCmdError(codeConfig, "failed to read %s: %w", path, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return nil, newCmdError(codeConfig, "unknown setting %s in %s, rules have include and exclude", undecoded[0], path)
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, newCmdError(codeConfig, "%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// clipSource is where a page was found after following redirects
+func clipSource(article *cleanpage.Article, url string) string {
+	if article.URL != "" {
+		return article.URL
+	}
+	return url
+}
+
+// clipFileName is the name of a file saved with the clip of a page to
+// noteName, made of the note's name and suffix. Pages that are a section
+// of a note add their title, so the files of one note's pages do not
+// replace each other.
+func clipFileName(noteName, title string, section bool, suffix string) string {
+	name := noteSlug(strings.TrimSuffix(filepath.Base(noteName), ".md"))
+	if section {
+		if slug := noteSlug(title); slug != "" {
+			name += "-" + slug
+		}
+	}
+	if name == "" {
+		name = "page"
+	}
+	return name + suffix
+}
+
+// saveClipFile writes a file saved with a clip into dir under name, which
+// clipFileName chose. A file with that name and the same content is reused;
+// a different one is kept, and the new file gets its hash appended, as
+// images do. It returns the name the file was saved under.
+func saveClipFile(dir, name string, data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	ext := filepath.Ext(name)
+	hashed := strings.TrimSuffix(name, ext) + "-" + hex.EncodeToString(sum[:])[:8] + ext
+	for _, candidate := range []string{name, hashed} {
+		path := filepath.Join(dir, candidate)
+		err := writeFileAtomic(path, data, 0644, false)
+		if err == nil {
+			return candidate, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+		if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%s already exists", hashed)
+}
+
+// clipSection finishes the body of a clip for a note in noteDir: images are
+// downloaded as opts ask with --images, and the source is linked at the
+// end, followed by the archived copy of the page when there is one
+func clipSection(body, source, archive, noteDir string, opts cleanpage.Options) (string, clipImageStats, error) {
+	var images clipImageStats
+	if err := os.MkdirAll(noteDir, 0755); err != nil {
+		return "", images, fmt.Errorf("failed to create directory: %w", err)
+	}
+	if clipImages {
+		var err error
+		body, images, err = localizeImages(body, filepath.Join(noteDir, "._images_"), source, opts)
 		if err != nil {
-			return fmt.Errorf("failed to download webpage: %w", err)
+			return "", images, err
 		}
+	}
+	line := "\nSource: [" + source + "](" + source + ")"
+	if archive != "" {
+		line += " ([archived copy](" + archive + "))"
+	}
+	return body + line + "\n", images, nil
+}
+
+// writeClip writes a clip to a note under its lock. update returns the new
+// content of the note from its existing content, which is empty for a note
+// that does not exist.
+func writeClip(notePath, noteName string, exists bool, update func(existing string) (string, error)) error {
+	lock, err := lockNote(notePath, "clip")
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	var existing []byte
+	if exists {
+		if existing, err = os.ReadFile(notePath); err != nil {
+			return fmt.Errorf("failed to read note: %w", err)
+		}
+	}
+	content, err := update(string(existing))
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(notePath, []byte(content), filePerm(notePath, 0644), exists); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return newCmdError(codeAlreadyExists, "note already exists: %s", noteName)
+		}
+		return fmt.Errorf("failed to write note: %w", err)
+	}
+	return nil
+}
+
+// appendClipUpdate adds a clip to the end of a note
+func appendClipUpdate(content string) func(string) (string, error) {
+	return func(existing string) (string, error) {
+		updated, _ := insertText(existing, strings.Trim(content, "\n"), "")
+		return updated, nil
+	}
+}
+
+// replaceSection replaces the section of content below heading with text,
+// which starts with a heading of its own. Without that section, text is
+// appended.
+func replaceSection(content, heading, text string) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	start, level := findHeading(lines, heading)
+	if start < 0 {
+		updated, _ := insertText(content, strings.Trim(text, "\n"), "")
+		return updated
+	}
+	end := sectionEnd(lines, start, level)
+
+	updated := append(lines[:start:start], strings.Split(strings.Trim(text, "\n"), "\n")...)
+	if end < len(lines) {
+		updated = append(append(updated, ""), lines[end:]...)
+	}
+	return strings.Join(updated, "\n") + "\n"
+}
 
+// newClipEntry records a clip of article, requested as url, in the note
+func newClipEntry(article *cleanpage.Article, url, noteName string, section bool) clipEntry {
+	source := article.Canonical
+	if source == "" {
+		source = clipSource(article, url)
+	}
+	return clipEntry{
+		URL:     source,
+		Note:    strings.TrimSuffix(filepath.ToSlash(noteName), ".md"),
+		Title:   article.Title,
+		Section: section,
+		Clipped: time.Now(),
+	}
+}
+
+// clipBody turns an article into the body of a note: the article as
+// markdown under its title, an AI summary, or the summary followed by the
+// article. level is the heading level of the clip's title: 1 for a note of
+// its own, 2 for a section appended to a note, whose headings move down
+// one level so that they nest under it.
+func clipBody(page *cleanpage.Article, mode, apiKey, fallbackTitle string, level int) (string, error) {
+	title := page.Title
+	if title == "" {
+		title = fallbackTitle
+	}
+
+	var parts []string
+	if level > 1 {
+		parts = append(parts, strings.Repeat("#", level)+" "+title)
+	}
+	if mode != clipModeFull {
 		// Create AI note instance
 		ai, err := ainote.NewAINote("", apiKey)
 		if err != nil {
-			return fmt.Errorf("failed to create AI note: %w", err)
+			return "", fmt.Errorf("failed to create AI note: %w", err)
 		}
 
 		// Summarize the content
-		summary, err := ai.SummarizeArticle(pageContent)
+		summary, err := ai.SummarizeArticle(page.Text)
 		if err != nil {
-			return fmt.Errorf("failed to summarize article: %w", err)
+			return "", fmt.Errorf("failed to summarize article: %w", err)
 		}
-
-		content = summary
-	} else {
-		content = "# " + strings.TrimSuffix(noteName, ".md") + "\n"
+		parts = append(parts, shiftHeadings(strings.TrimSpace(summary), level-1))
 	}
 
-	// Append the URL at the end
-	content += "\n\nSource: [" + url + "](" + url + ")\n"
+	if mode != clipModeSummary {
+		article := strings.TrimSpace(page.Markdown)
+		if level > 1 {
+			// The section heading already names the article
+			if first, rest, _ := strings.Cut(article, "\n"); first == "# "+title {
+				article = strings.TrimSpace(rest)
+			}
+		}
+		if mode == clipModeBoth {
+			parts = append(parts, strings.Repeat("#", level+1)+" Full text")
+		} else if level == 1 && !strings.HasPrefix(article, "# ") {
+			parts = append(parts, "# "+title)
+		}
+		if article != "" {
+			parts = append(parts, shiftHeadings(article, level-1))
+		}
+	}
+	return strings.Join(parts, "\n\n") + "\n", nil
+}
 
-	// Write to file
-	if err := os.WriteFile(notePath, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write note: %w", err)
+// shiftHeadings moves the headings of markdown down by levels, down to
+// level 6. Lines in code fences are left alone.
+func shiftHeadings(markdown string, levels int) string {
+	if levels <= 0 {
+		return markdown
+	}
+	lines := strings.Split(markdown, "\n")
+	inFence := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || headingRe.FindStringSubmatch(line) == nil {
+			continue
+		}
+		level := len(line) - len(strings.TrimLeft(line, "#"))
+		lines[i] = strings.Repeat("#", min(level+levels, 6)) + line[level:]
 	}
+	return strings.Join(lines, "\n")
+}
 
-	fmt.Printf("Created note: %s\n", noteName)
-	return nil
+// clipFrontMatter returns the YAML front matter block that records where a
+// clip came from
+func clipFrontMatter(article *cleanpage.Article, source string, now time.Time) (string, error) {
+	meta := clipMeta{
+		Title:    article.Title,
+		Author:   article.Byline,
+		Site:     article.SiteName,
+		Source:   source,
+		Clipped:  now.Format("2006-01-02 15:04"),
+		Language: article.Language,
+		Image:    article.Image,
+		Excerpt:  article.Excerpt,
+	}
+	if article.Published != nil {
+		meta.Published = article.Published.Format("2006-01-02")
+	}
+	data, err := yaml.Marshal(meta)
+	if err != nil {
+		return "", fmt.Errorf("failed to write front matter: %w", err)
+	}
+	return "---\n" + string(data) + "---\n\n", nil
 }
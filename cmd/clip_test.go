@@ -7,8 +7,12 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"ned/cleanpage"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestClipCmd(t *testing.T) {
@@ -64,7 +68,8 @@ func TestClipCmd(t *testing.T) {
 			validate: func(t *testing.T, notePath string) {
 				content, err := os.ReadFile(notePath)
 				assert.NoError(t, err)
-				assert.Contains(t, string(content), "# test\n")
+				assert.Contains(t, string(content), "# Test Page\n")
+				assert.Contains(t, string(content), "Test content for clipping.")
 				assert.Contains(t, string(content), "Source: ["+ts.URL+"]("+ts.URL+")")
 			},
 		},
@@ -126,3 +131,340 @@ func TestClipCmd(t *testing.T) {
 		})
 	}
 }
+
+func TestClipExistingNote(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, nil)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(articleHTML))
+	}))
+	defer ts.Close()
+
+	notePath := filepath.Join(tmpDir, "reading.md")
+	original := "# Reading\n\nSome notes\n"
+	url := ts.URL
+	clipped := "# A Post\n\n" + articleMarkdown + "\nSource: [" + url + "](" + url + ")\n"
+	section := "## A Post\n\n" + strings.Replace(articleMarkdown, "## ", "### ", 1) + "\nSource: [" + url + "](" + url + ")\n"
+
+	tests := []struct {
+		name        string
+		force       bool
+		appendNote  bool
+		newNote     bool
+		answers     []bool
+		wantCode    string
+		wantContent string
+	}{
+		{name: "refuses without a terminal", wantCode: codeAlreadyExists, wantContent: original},
+		{name: "asks and appends", answers: []bool{true}, wantContent: original + "\n" + section},
+		{name: "asks and keeps the note", answers: []bool{false}, wantCode: codeAlreadyExists, wantContent: original},
+		{name: "--new refuses", newNote: true, answers: []bool{true}, wantCode: codeAlreadyExists, wantContent: original},
+		{name: "--force overwrites", force: true, wantContent: clipped},
+		{name: "--append adds a section", appendNote: true, wantContent: original + "\n" + section},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(notePath, []byte(original), 0644))
+			os.Remove(filepath.Join(tmpDir, clipIndexName))
+			clipForce, clipAppend, clipNew = tt.force, tt.appendNote, tt.newNote
+			defer func() { clipForce, clipAppend, clipNew = false, false, false }()
+			var p *scriptedPrompter
+			if tt.answers != nil {
+				p = setupTestPrompter(t, tt.answers...)
+			}
+
+			_, err := captureStdout(t, func() error { return runClip(clipCmd, []string{"reading", url}) })
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, errorCode(err))
+			} else {
+				require.NoError(t, err)
+			}
+			if p != nil && tt.newNote {
+				assert.Empty(t, p.questions, "--new does not ask")
+			}
+
+			content, err := os.ReadFile(notePath)
+			require.NoError(t, err)
+			meta, body := parseFrontMatter(content)
+			assert.Equal(t, tt.wantContent, strings.TrimPrefix(string(body), "\n"))
+			assert.Equal(t, tt.force, meta != nil, "only whole notes get front matter")
+		})
+	}
+}
+
+// articleHTML is a page with an article for readability to find, and
+// articleMarkdown the article converted to markdown
+const (
+	articleHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<title>A Post</title>
+<meta name="author" content="Ada Lovelace">
+<meta property="og:site_name" content="The Blog">
+<meta property="og:image" content="https://example.com/lead.png">
+<meta property="article:published_time" content="2026-10-01T09:00:00Z">
+</head>
+<body>
+<nav><a href="/">Home</a></nav>
+<article>
+<h2>Getting started</h2>
+<p>Install the tool with <code>go install</code> and read the <a href="https://example.com/docs">documentation</a> first.</p>
+<ul><li>Fast</li><li>Small</li></ul>
+<p>This paragraph has enough text in it for readability to consider the article worth extracting from the page.</p>
+</article>
+<footer>Copyright</footer>
+</body>
+</html>`
+	articleMarkdown = "## Getting started\n\n" +
+		"Install the tool with `go install` and read the [documentation](https://example.com/docs) first.\n\n" +
+		"- Fast\n- Small\n\n" +
+		"This paragraph has enough text in it for readability to consider the article worth extracting from the page.\n"
+)
+
+func TestClipModes(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/post", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(articleHTML))
+	})
+	mux.Handle("/old", http.RedirectHandler("/post", http.StatusMovedPermanently))
+	pictures := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(strings.Replace(articleHTML, "<ul>", `<p><img src="/media/chart.png" alt="Chart"></p><ul>`, 1)))
+	}
+	mux.HandleFunc("/pictures", pictures)
+	mux.HandleFunc("/gallery", pictures)
+	mux.HandleFunc("/media/chart.png", func(w http.ResponseWriter, r *http.Request) { w.Write(testPNG(t, 4, 3)) })
+	mux.HandleFunc("/files/notes.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=iso-8859-1")
+		w.Write([]byte("Caf\xe9 menu\n\nCoffee and cake.\n"))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	defer func() { clipMode = ""; clipImages = false; clipArchive = false }()
+
+	t.Run("full text without an API key", func(t *testing.T) {
+		clipMode = ""
+		_, err := captureStdout(t, func() error { return runClip(clipCmd, []string{"post", ts.URL + "/old"}) })
+		require.NoError(t, err)
+		content, err := os.ReadFile(filepath.Join(tmpDir, "post.md"))
+		require.NoError(t, err)
+
+		meta, body := parseFrontMatter(content)
+		source := ts.URL + "/post"
+		assert.Equal(t, "# A Post\n\n"+articleMarkdown+"\nSource: ["+source+"]("+source+")\n", strings.TrimPrefix(string(body), "\n"))
+		require.NotNil(t, meta)
+		assert.Equal(t, "A Post", meta["title"])
+		assert.Equal(t, "Ada Lovelace", meta["author"])
+		assert.Equal(t, "The Blog", meta["site"])
+		assert.Equal(t, source, meta["source"], "redirects are followed")
+		assert.Equal(t, "2026-10-01", meta["published"])
+		assert.Equal(t, "en", meta["language"])
+		assert.Equal(t, "https://example.com/lead.png", meta["image"])
+		assert.Contains(t, meta, "clipped")
+	})
+
+	t.Run("images", func(t *testing.T) {
+		clipMode = clipModeFull
+		clipImages = true
+		defer func() { clipImages = false }()
+		_, err := captureStdout(t, func() error { return runClip(clipCmd, []string{"folder/pictures", ts.URL + "/pictures"}) })
+		require.NoError(t, err)
+		content, err := os.ReadFile(filepath.Join(tmpDir, "folder", "pictures.md"))
+		require.NoError(t, err)
+
+		assert.Contains(t, string(content), "![Chart](chart.png)")
+		assert.FileExists(t, filepath.Join(tmpDir, "folder", "._images_", "chart.png"))
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		clipMode = clipModeFull
+		clipArchive = true
+		defer func() { clipArchive = false }()
+		out, err := captureStdout(t, func() error { return runClip(clipCmd, []string{"folder/Saved Page", ts.URL + "/gallery"}) })
+		require.NoError(t, err)
+		assert.Contains(t, out, "Archived the page to "+filepath.Join("folder", "._archive_", "saved-page.html"))
+
+		content, err := os.ReadFile(filepath.Join(tmpDir, "folder", "Saved Page.md"))
+		require.NoError(t, err)
+		source := ts.URL + "/gallery"
+		assert.Contains(t, string(content), "\nSource: ["+source+"]("+source+") ([archived copy](._archive_/saved-page.html))\n")
+
+		archived, err := os.ReadFile(filepath.Join(tmpDir, "folder", "._archive_", "saved-page.html"))
+		require.NoError(t, err)
+		assert.Contains(t, string(archived), `<img src="data:image/png;base64,`)
+		assert.Contains(t, string(archived), `<a href="`+ts.URL+`/">Home</a>`, "the whole page is archived")
+	})
+
+	t.Run("plain text is not archived", func(t *testing.T) {
+		clipMode = clipModeFull
+		clipArchive = true
+		defer func() { clipArchive = false }()
+		out, err := captureStdout(t, func() error { return runClip(clipCmd, []string{"menu", ts.URL + "/files/notes.txt"}) })
+		require.NoError(t, err)
+		assert.NotContains(t, out, "Archived the page")
+
+		content, err := os.ReadFile(filepath.Join(tmpDir, "menu.md"))
+		require.NoError(t, err)
+		meta, body := parseFrontMatter(content)
+		assert.Equal(t, "Café menu", meta["title"])
+		assert.Contains(t, string(body), "Café menu\n\nCoffee and cake.\n")
+		assert.NoDirExists(t, filepath.Join(tmpDir, "._archive_"))
+	})
+
+	t.Run("summary needs an API key", func(t *testing.T) {
+		for _, mode := range []string{clipModeSummary, clipModeBoth} {
+			clipMode = mode
+			err := runClip(clipCmd, []string{"summary", ts.URL + "/post"})
+			assert.Equal(t, codeConfig, errorCode(err))
+		}
+		assert.NoFileExists(t, filepath.Join(tmpDir, "summary.md"))
+	})
+
+	t.Run("unknown mode", func(t *testing.T) {
+		clipMode = "everything"
+		err := runClip(clipCmd, []string{"unknown", ts.URL + "/post"})
+		assert.Equal(t, codeUsage, errorCode(err))
+	})
+}
+
+func TestClipOutsideNotesDir(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, nil)
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(articleHTML))
+	}))
+	defer ts.Close()
+
+	for _, name := range []string{"../escaped", "a/../../escaped"} {
+		err := runClip(clipCmd, []string{name, ts.URL})
+		assert.Equal(t, codeInvalidPath, errorCode(err), name)
+	}
+	assert.Zero(t, requests, "the page must not be fetched")
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(tmpDir), "escaped.md"))
+}
+
+func TestShiftHeadings(t *testing.T) {
+	markdown := "# One\n\ntext\n\n```sh\n# comment\n```\n\n##### Five\n\n###### Six\n#hashtag"
+	assert.Equal(t, "## One\n\ntext\n\n```sh\n# comment\n```\n\n###### Five\n\n###### Six\n#hashtag", shiftHeadings(markdown, 1))
+	assert.Equal(t, markdown, shiftHeadings(markdown, 0))
+}
+
+func TestFetchOptions(t *testing.T) {
+	configDir := setupTestConfig(t, nil)
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "cookies.txt"), []byte("intranet.example.com\tFALSE\t/\tTRUE\t0\tsso\txyz\n"), 0600))
+	defer func() {
+		clipFetcher, clipUserAgent, clipTimeout, clipProxy, clipHeaders, clipCookies = "", "", 0, "", nil, ""
+	}()
+
+	config := &Config{Values: map[string]string{
+		"CLIP_FETCHER":    "http",
+		"CLIP_USER_AGENT": "ned/1.0",
+		"CLIP_TIMEOUT":    "45s",
+		"CLIP_PROXY":      "http://proxy.example.com:8080",
+		"CLIP_HEADERS":    "X-Team: notes\nAccept-Language: de",
+		"CLIP_COOKIES":    "cookies.txt",
+	}}
+	opts, err := fetchOptions(config)
+	require.NoError(t, err)
+	assert.Equal(t, cleanpage.StrategyHTTP, opts.Strategy)
+	assert.Equal(t, "ned/1.0", opts.UserAgent)
+	assert.Equal(t, 45*time.Second, opts.Timeout)
+	assert.Equal(t, "http://proxy.example.com:8080", opts.Proxy)
+	assert.Equal(t, http.Header{"X-Team": {"notes"}, "Accept-Language": {"de"}}, opts.Header)
+	require.Len(t, opts.Cookies, 1, "the cookies file is relative to the config directory")
+	assert.Equal(t, "sso", opts.Cookies[0].Name)
+
+	// Flags win, and add headers
+	clipFetcher, clipUserAgent, clipTimeout, clipHeaders = "chrome", "other/2.0", 5*time.Second, []string{"X-Extra: 1"}
+	opts, err = fetchOptions(config)
+	require.NoError(t, err)
+	assert.Equal(t, cleanpage.StrategyChrome, opts.Strategy)
+	assert.Equal(t, "other/2.0", opts.UserAgent)
+	assert.Equal(t, 5*time.Second, opts.Timeout)
+	assert.Equal(t, "1", opts.Header.Get("X-Extra"))
+	assert.Equal(t, "notes", opts.Header.Get("X-Team"))
+	clipFetcher, clipUserAgent, clipTimeout, clipHeaders = "", "", 0, nil
+
+	for key, value := range map[string]string{
+		"CLIP_FETCHER": "wget",
+		"CLIP_TIMEOUT": "soon",
+		"CLIP_PROXY":   "proxy:8080",
+		"CLIP_HEADERS": "no colon",
+		"CLIP_COOKIES": "missing.txt",
+	} {
+		_, err := fetchOptions(&Config{Values: map[string]string{key: value}})
+		assert.Equal(t, codeConfig, errorCode(err), key)
+	}
+}
+
+func TestClipRules(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	configDir := setupTestConfig(t, nil)
+	rulesPath := filepath.Join(configDir, clipRulesName)
+	defer func() { clipSelector, clipMode = "", "" }()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(articleHTML))
+	}))
+	defer ts.Close()
+
+	opts, err := fetchOptions(&Config{})
+	require.NoError(t, err)
+	assert.Nil(t, opts.Rules, "there are no rules without a rules file")
+
+	require.NoError(t, os.WriteFile(rulesPath, []byte(`# Where readability goes wrong
+["127.0.0.1"]
+include = ["article"]
+exclude = ["ul"]
+`), 0644))
+	opts, err = fetchOptions(&Config{})
+	require.NoError(t, err)
+	assert.Equal(t, cleanpage.Rules{"127.0.0.1": {Include: []string{"article"}, Exclude: []string{"ul"}}}, opts.Rules)
+
+	clipMode = clipModeFull
+	_, err = captureStdout(t, func() error { return runClip(clipCmd, []string{"ruled", ts.URL}) })
+	require.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(tmpDir, "ruled.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "## Getting started")
+	assert.NotContains(t, string(content), "- Fast", "excluded elements are left out")
+
+	clipSelector = "h2"
+	_, err = captureStdout(t, func() error { return runClip(clipCmd, []string{"selected", ts.URL + "/selected"}) })
+	require.NoError(t, err)
+	content, err = os.ReadFile(filepath.Join(tmpDir, "selected.md"))
+	require.NoError(t, err)
+	_, body := parseFrontMatter(content)
+	assert.Equal(t, "# A Post\n\n## Getting started\n\nSource: ["+ts.URL+"/selected]("+ts.URL+"/selected)\n", strings.TrimPrefix(string(body), "\n"))
+
+	clipSelector = "h2["
+	_, err = fetchOptions(&Config{})
+	assert.Equal(t, codeUsage, errorCode(err))
+	clipSelector = ""
+
+	for _, rules := range []string{
+		"[\"example.com\"]\ninclude = [\"main[\"]\n",
+		"[\"example.com\"]\nincludes = [\"main\"]\n",
+		"not toml",
+	} {
+		require.NoError(t, os.WriteFile(rulesPath, []byte(rules), 0644))
+		_, err := fetchOptions(&Config{})
+		assert.Equal(t, codeConfig, errorCode(err), rules)
+	}
+}
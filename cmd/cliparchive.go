@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"ned/cleanpage"
+)
+
+// archiveDirName is the directory next to a note where clip --archive
+// keeps single-file snapshots of the pages clipped to it
+const archiveDirName = "._archive_"
+
+// archiveWanted reports whether to archive the page of article. Documents
+// that are not webpages, like PDFs, are not archived, and the user is told.
+func archiveWanted(article *cleanpage.Article) bool {
+	if !clipArchive {
+		return false
+	}
+	if article.Page == "" {
+		fmt.Fprintf(os.Stderr, "%s is not a webpage, so it is not archived.\n", article.URL)
+		return false
+	}
+	return true
+}
+
+// saveArchive writes the snapshot of a page to the archive directory next
+// to the note at notePath, without replacing an archived copy already
+// there. It returns the path of the snapshot relative to the note, which is
+// how the note links to it.
+func saveArchive(snapshot, notePath, name string) (string, error) {
+	dir := filepath.Join(filepath.Dir(notePath), archiveDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	name, err := saveClipFile(dir, name, []byte(snapshot))
+	if err != nil {
+		return "", fmt.Errorf("failed to save archived copy: %w", err)
+	}
+	return archiveDirName + "/" + name, nil
+}
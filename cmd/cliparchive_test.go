@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveArchive(t *testing.T) {
+	dir := t.TempDir()
+	notePath := filepath.Join(dir, "note.md")
+
+	archive, err := saveArchive("<html>old</html>", notePath, "note.html")
+	require.NoError(t, err)
+	assert.Equal(t, "._archive_/note.html", archive)
+
+	// The same snapshot is reused, a different one gets another name
+	archive, err = saveArchive("<html>old</html>", notePath, "note.html")
+	require.NoError(t, err)
+	assert.Equal(t, "._archive_/note.html", archive)
+
+	archive, err = saveArchive("<html>new</html>", notePath, "note.html")
+	require.NoError(t, err)
+	assert.Regexp(t, `^\._archive_/note-[0-9a-f]{8}\.html$`, archive)
+	old, err := os.ReadFile(filepath.Join(dir, "._archive_", "note.html"))
+	require.NoError(t, err)
+	assert.Equal(t, "<html>old</html>", string(old))
+}
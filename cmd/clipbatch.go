@@ -0,0 +1,500 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"ned/cleanpage"
+)
+
+// clipBatchResult is the structured output of clip --from
+type clipBatchResult struct {
+	Clipped []clipResult  `json:"clipped" yaml:"clipped"`
+	Known   []clipKnown   `json:"known" yaml:"known"`
+	Failed  []clipFailure `json:"failed" yaml:"failed"`
+}
+
+// clipKnown is a URL that was skipped because it was clipped before
+type clipKnown struct {
+	URL  string `json:"url" yaml:"url"`
+	Note string `json:"note" yaml:"note"`
+}
+
+// clipFailure is a URL that could not be clipped. Retryable failures, like
+// timeouts and server errors, may succeed when tried again later.
+type clipFailure struct {
+	URL       string `json:"url" yaml:"url"`
+	Error     string `json:"error" yaml:"error"`
+	Retryable bool   `json:"retryable" yaml:"retryable"`
+}
+
+// batchPage is a page of a batch after it was downloaded
+type batchPage struct {
+	url     string
+	article *cleanpage.Article
+	body    string
+	// snapshot is the archived copy of the page with --archive
+	snapshot string
+	// screenshot is the image of the page with --screenshot
+	screenshot []byte
+	err        error
+}
+
+// fetchPage downloads a page of a batch. Tests replace it.
+var fetchPage = func(ctx context.Context, url string, opts cleanpage.Options) (*cleanpage.Article, error) {
+	return cleanpage.Fetch(ctx, url, opts)
+}
+
+func runClipBatch(args []string) error {
+	if clipCombine && len(args) != 1 {
+		return newCmdError(codeUsage, "--combine needs the note to clip into")
+	}
+	if len(args) > 1 {
+		return newCmdError(codeUsage, "--from accepts at most a folder, received %d args", len(args))
+	}
+	if clipJobs < 1 {
+		return newCmdError(codeUsage, "--jobs must be at least 1")
+	}
+	if clipRefresh {
+		return newCmdError(codeUsage, "--refresh updates one page at a time and cannot be used with --from")
+	}
+
+	urls, err := readClipURLs(clipFrom)
+	if err != nil {
+		return err
+	}
+	if len(urls) == 0 {
+		return newCmdError(codeUsage, "no URLs to clip in %s", clipFrom)
+	}
+
+	absNotesDir, err := filepath.Abs(notesDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve notes directory path: %w", err)
+	}
+	target := ""
+	if len(args) == 1 {
+		target = filepath.Clean(args[0])
+		if filepath.IsAbs(target) || target == ".." || strings.HasPrefix(target, ".."+string(filepath.Separator)) {
+			return newCmdError(codeInvalidPath, "path must be within notes directory")
+		}
+	}
+
+	// A combined note is settled before the downloads, like a single clip
+	var noteName, notePath string
+	var exists, appendClip bool
+	if clipCombine {
+		noteName = target
+		if !strings.HasSuffix(noteName, ".md") {
+			noteName += ".md"
+		}
+		notePath = filepath.Join(absNotesDir, noteName)
+		exists, appendClip, err = clipTarget(noteName, notePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	settings, err := clipSettings()
+	if err != nil {
+		return err
+	}
+
+	// Pages clipped before are skipped unless --again is given
+	index, err := loadClipIndex()
+	if err != nil {
+		return err
+	}
+	result := clipBatchResult{Clipped: []clipResult{}, Known: []clipKnown{}, Failed: []clipFailure{}}
+	isKnown := func(url string, urls ...string) bool {
+		if clipAgain {
+			return false
+		}
+		entry, ok := index.lookup(urls...)
+		if ok {
+			result.Known = append(result.Known, clipKnown{URL: url, Note: entry.Note})
+		}
+		return ok
+	}
+	total := len(urls)
+	urls = slices.DeleteFunc(urls, func(url string) bool { return isKnown(url, url) })
+
+	// Separate notes are full notes; the pages of a combined note are
+	// sections of it
+	level := 1
+	if clipCombine {
+		level = 2
+	}
+	screenshots := screenshotsWanted()
+	// --rate paces the images and archived resources of pages too
+	limiter := newHostLimiter(clipRate)
+	settings.fetch.Wait = limiter.wait
+	pages := fetchPages(urls, clipJobs, limiter, settings.fetch, func(page *batchPage) {
+		page.body, page.err = clipBody(page.article, settings.mode, settings.apiKey, page.url, level)
+		if page.err == nil && archiveWanted(page.article) {
+			page.snapshot, page.err = cleanpage.Archive(context.Background(), page.article, settings.fetch)
+		}
+		if page.err == nil && screenshots {
+			var err error
+			if page.screenshot, err = takeScreenshot(context.Background(), clipSource(page.article, page.url), settings.fetch); err != nil {
+				fmt.Fprintf(os.Stderr, "Could not take a screenshot of %s: %v\n", page.url, err)
+			}
+		}
+	})
+
+	fail := func(url string, err error) {
+		result.Failed = append(result.Failed, clipFailure{URL: url, Error: err.Error(), Retryable: retryable(err)})
+	}
+	if clipCombine {
+		var sections []string
+		var clipped []*batchPage
+		if !appendClip {
+			sections = append(sections, "# "+strings.TrimSuffix(filepath.Base(noteName), ".md")+"\n")
+		}
+		for _, page := range pages {
+			if page.err != nil {
+				fail(page.url, page.err)
+				continue
+			}
+			if isKnown(page.url, page.article.URL, page.article.Canonical) {
+				continue
+			}
+			body, screenshot, archive, err := saveBatchFiles(page, page.body, notePath, noteName, true)
+			if err != nil {
+				return err
+			}
+			content, images, err := clipSection(body, clipSource(page.article, page.url), archive, filepath.Dir(notePath), settings.fetch)
+			if err != nil {
+				return err
+			}
+			sections = append(sections, content)
+			clipped = append(clipped, page)
+			result.Clipped = append(result.Clipped, clipResult{Note: strings.TrimSuffix(noteName, ".md"), Path: notePath, URL: page.url, Title: page.article.Title, Images: images.Saved, Archive: archive, Screenshot: screenshot})
+		}
+		if len(clipped) > 0 {
+			content := strings.Join(sections, "\n")
+			update := func(string) (string, error) { return content, nil }
+			if appendClip {
+				update = appendClipUpdate(content)
+			}
+			if err := writeClip(notePath, noteName, exists, update); err != nil {
+				return err
+			}
+			for _, page := range clipped {
+				if err := recordClip(newClipEntry(page.article, page.url, noteName, true), page.url, page.article.URL, page.article.Canonical); err != nil {
+					return err
+				}
+			}
+		}
+	} else {
+		used := map[string]bool{}
+		for _, page := range pages {
+			if page.err != nil {
+				fail(page.url, page.err)
+				continue
+			}
+			if isKnown(page.url, page.article.URL, page.article.Canonical) {
+				continue
+			}
+			clipped, err := saveBatchNote(absNotesDir, target, page, used, settings.fetch)
+			if err != nil {
+				fail(page.url, err)
+				continue
+			}
+			result.Clipped = append(result.Clipped, clipped)
+		}
+	}
+
+	if structuredOutput() {
+		if err := printResult(result); err != nil {
+			return err
+		}
+	} else {
+		printBatchSummary(result, noteName)
+	}
+	if len(result.Failed) > 0 {
+		return newCmdError(codeFetchFailed, "%d of %d pages could not be clipped", len(result.Failed), total)
+	}
+	return nil
+}
+
+// readClipURLs reads the URLs of a batch from a file, or from stdin for
+// "-". Blank lines and # comments are skipped, and so are repeated URLs.
+func readClipURLs(from string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if from != "-" {
+		f, err := os.Open(from)
+		if os.IsNotExist(err) {
+			return nil, newCmdError(codeNotFound, "URL list not found: %s", from)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to open URL list: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var urls []string
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || seen[line] {
+			continue
+		}
+		if !isWebAddress(line) {
+			return nil, newCmdError(codeUsage, "not a web address: %s", line)
+		}
+		seen[line] = true
+		urls = append(urls, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read URL list: %w", err)
+	}
+	return urls, nil
+}
+
+// isWebAddress reports whether s is an absolute http or https URL
+func isWebAddress(s string) bool {
+	u, err := neturl.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// fetchPages downloads pages with a pool of jobs workers and prepares each
+// with prepare. Progress is reported as pages finish; the pages are
+// returned in the order of urls.
+func fetchPages(urls []string, jobs int, limiter *hostLimiter, opts cleanpage.Options, prepare func(*batchPage)) []*batchPage {
+	pages := make([]*batchPage, len(urls))
+	queue := make(chan int)
+	done := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(jobs, len(urls)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				page := &batchPage{url: urls[i]}
+				limiter.wait(urls[i])
+				page.article, page.err = fetchPage(context.Background(), urls[i], opts)
+				if page.err == nil {
+					prepare(page)
+				}
+				pages[i] = page
+				done <- i
+			}
+		}()
+	}
+	go func() {
+		for i := range urls {
+			queue <- i
+		}
+		close(queue)
+		wg.Wait()
+		close(done)
+	}()
+
+	finished := 0
+	for i := range done {
+		finished++
+		page := pages[i]
+		if page.err != nil {
+			fmt.Fprintf(promptOutput(), "[%d/%d] failed  %s: %v\n", finished, len(urls), page.url, page.err)
+		} else {
+			fmt.Fprintf(promptOutput(), "[%d/%d] fetched %s\n", finished, len(urls), page.url)
+		}
+	}
+	return pages
+}
+
+// saveBatchNote writes a page of a batch to a note of its own in folder,
+// named after its title. Names taken by existing notes or earlier pages get
+// a number, unless --force or --append allow reusing an existing note.
+// Images are downloaded as opts ask.
+func saveBatchNote(absNotesDir, folder string, page *batchPage, used map[string]bool, opts cleanpage.Options) (clipResult, error) {
+	base := noteSlug(page.article.Title)
+	if base == "" {
+		base = noteSlug(page.url)
+	}
+
+	var noteName, notePath string
+	var exists bool
+	for n := 1; ; n++ {
+		name := base
+		if n > 1 {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		noteName = filepath.Join(folder, name+".md")
+		notePath = filepath.Join(absNotesDir, noteName)
+		if used[noteName] {
+			continue
+		}
+		_, err := os.Stat(notePath)
+		exists = err == nil
+		if !exists || clipForce || clipAppend {
+			break
+		}
+	}
+	used[noteName] = true
+
+	body := page.body
+	appendClip := exists && clipAppend
+	if appendClip {
+		// The body was written for a note of its own
+		body = shiftHeadings(body, 1)
+	}
+
+	source := clipSource(page.article, page.url)
+	body, screenshot, archive, err := saveBatchFiles(page, body, notePath, noteName, appendClip)
+	if err != nil {
+		return clipResult{}, err
+	}
+	content, images, err := clipSection(body, source, archive, filepath.Dir(notePath), opts)
+	if err != nil {
+		return clipResult{}, err
+	}
+	update := func(string) (string, error) {
+		frontMatter, err := clipFrontMatter(page.article, source, time.Now())
+		return frontMatter + content, err
+	}
+	if appendClip {
+		update = appendClipUpdate(content)
+	}
+	if err := writeClip(notePath, noteName, exists, update); err != nil {
+		return clipResult{}, err
+	}
+	if err := recordClip(newClipEntry(page.article, page.url, noteName, appendClip), page.url, page.article.URL, page.article.Canonical); err != nil {
+		return clipResult{}, err
+	}
+	return clipResult{Note: strings.TrimSuffix(filepath.ToSlash(noteName), ".md"), Path: notePath, URL: page.url, Title: page.article.Title, Images: images.Saved, Archive: archive, Screenshot: screenshot}, nil
+}
+
+// saveBatchFiles saves the screenshot and the archived copy of a page of a
+// batch next to the note at notePath, as far as they were taken. The
+// screenshot is embedded in body.
+func saveBatchFiles(page *batchPage, body, notePath, noteName string, section bool) (string, string, string, error) {
+	var screenshot, archive string
+	var err error
+	if page.screenshot != nil {
+		name := clipFileName(noteName, page.article.Title, section, "-screenshot.png")
+		if body, screenshot, err = addScreenshot(body, page.screenshot, notePath, name, page.article.Title); err != nil {
+			return "", "", "", err
+		}
+	}
+	if page.snapshot != "" {
+		if archive, err = saveArchive(page.snapshot, notePath, clipFileName(noteName, page.article.Title, section, ".html")); err != nil {
+			return "", "", "", err
+		}
+	}
+	return body, screenshot, archive, nil
+}
+
+var slugRe = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// noteSlug turns a title or URL into a note name
+func noteSlug(s string) string {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "https://"), "http://")
+	slug := strings.Trim(slugRe.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if runes := []rune(slug); len(runes) > 60 {
+		slug = strings.TrimRight(string(runes[:60]), "-")
+	}
+	return slug
+}
+
+// printBatchSummary reports how a batch went, listing the failures and
+// which of them are worth retrying
+func printBatchSummary(result clipBatchResult, combined string) {
+	total := len(result.Clipped) + len(result.Known) + len(result.Failed)
+	if combined != "" && len(result.Clipped) > 0 {
+		fmt.Printf("Clipped %d of %d pages into %s\n", len(result.Clipped), total, combined)
+	} else {
+		fmt.Printf("Clipped %d of %d pages\n", len(result.Clipped), total)
+		for _, clipped := range result.Clipped {
+			fmt.Printf("  %s\n", clipped.Note+".md")
+		}
+	}
+	if len(result.Known) > 0 {
+		fmt.Printf("Clipped before (use --again to clip them anyway):\n")
+		for _, known := range result.Known {
+			fmt.Printf("  %s: %s\n", known.URL, known.Note+".md")
+		}
+	}
+	if len(result.Failed) == 0 {
+		return
+	}
+
+	var retry []string
+	fmt.Printf("Failed:\n")
+	for _, failure := range result.Failed {
+		fmt.Printf("  %s: %s\n", failure.URL, failure.Error)
+		if failure.Retryable {
+			retry = append(retry, failure.URL)
+		}
+	}
+	if len(retry) > 0 {
+		fmt.Printf("These may work when tried again:\n")
+		for _, url := range retry {
+			fmt.Printf("  %s\n", url)
+		}
+	}
+}
+
+// retryable reports whether a failed download may succeed later: timeouts,
+// refused or dropped connections, rate limits and server errors
+func retryable(err error) bool {
+	var statusErr *cleanpage.StatusError
+	if errors.As(err, &statusErr) {
+		code := statusErr.StatusCode
+		return code == 408 || code == 425 || code == 429 || code >= 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// hostLimiter spaces out requests to the same host
+type hostLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+func newHostLimiter(interval time.Duration) *hostLimiter {
+	return &hostLimiter{interval: interval, next: map[string]time.Time{}}
+}
+
+// wait blocks until a request to the host of url is allowed
+func (l *hostLimiter) wait(url string) {
+	host := url
+	if u, err := neturl.Parse(url); err == nil {
+		host = u.Host
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(at))
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"ned/cleanpage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupClipBatch sets the clip flags for a batch and resets them afterwards
+func setupClipBatch(t *testing.T, from string, combine bool) {
+	t.Helper()
+	clipFrom, clipCombine, clipJobs, clipRate = from, combine, 3, 0
+	t.Cleanup(func() {
+		clipFrom, clipCombine, clipJobs, clipRate = "", false, 4, time.Second
+		clipForce, clipAppend = false, false
+	})
+}
+
+func articleServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	page := func(title string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(strings.Replace(articleHTML, "<title>A Post</title>", "<title>"+title+"</title>", 1)))
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/one", page("First Post"))
+	mux.HandleFunc("/two", page("Second: the Post!"))
+	mux.HandleFunc("/again", page("First Post"))
+	mux.HandleFunc("/missing", http.NotFound)
+	mux.HandleFunc("/busy", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func writeURLList(t *testing.T, dir string, urls ...string) string {
+	t.Helper()
+	path := filepath.Join(dir, "urls.txt")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(urls, "\n")+"\n"), 0644))
+	return path
+}
+
+func TestClipBatch(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, nil)
+	ts := articleServer(t)
+
+	list := writeURLList(t, t.TempDir(),
+		"# links from the meeting",
+		ts.URL+"/one",
+		"",
+		ts.URL+"/two",
+		ts.URL+"/missing",
+		ts.URL+"/again",
+		ts.URL+"/one",
+		ts.URL+"/busy",
+	)
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "reading"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "reading", "first-post.md"), []byte("mine\n"), 0644))
+	setupClipBatch(t, list, false)
+
+	out, err := captureStdout(t, func() error { return runClip(clipCmd, []string{"reading"}) })
+	assert.Equal(t, codeFetchFailed, errorCode(err))
+	assert.Contains(t, err.Error(), "2 of 5 pages")
+
+	for name, title := range map[string]string{
+		"first-post-2.md":    "First Post",
+		"second-the-post.md": "Second: the Post!",
+		"first-post-3.md":    "First Post",
+	} {
+		content, err := os.ReadFile(filepath.Join(tmpDir, "reading", name))
+		require.NoError(t, err, name)
+		meta, body := parseFrontMatter(content)
+		require.NotNil(t, meta, name)
+		assert.Equal(t, title, meta["title"], name)
+		assert.True(t, strings.HasPrefix(strings.TrimPrefix(string(body), "\n"), "# "+title+"\n"), name)
+	}
+	existing, err := os.ReadFile(filepath.Join(tmpDir, "reading", "first-post.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "mine\n", string(existing), "existing notes are kept")
+
+	assert.Contains(t, out, "[5/5]")
+	assert.Contains(t, out, "Clipped 3 of 5 pages")
+	assert.Contains(t, out, ts.URL+"/missing: HTTP status 404")
+	retry := out[strings.Index(out, "These may work when tried again:"):]
+	assert.Contains(t, retry, ts.URL+"/busy")
+	assert.NotContains(t, retry, ts.URL+"/missing")
+
+	// Pages clipped before are skipped the next time
+	out, err = captureStdout(t, func() error { return runClip(clipCmd, []string{"reading"}) })
+	assert.Equal(t, codeFetchFailed, errorCode(err))
+	assert.Contains(t, out, "Clipped 0 of 5 pages")
+	assert.Contains(t, out, ts.URL+"/one: reading/first-post-2.md")
+	assert.NoFileExists(t, filepath.Join(tmpDir, "reading", "first-post-4.md"))
+}
+
+func TestClipBatchCombined(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, nil)
+	ts := articleServer(t)
+
+	// URLs come from stdin with --from -
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	fmt.Fprintf(w, "%s/two\n%s/one\n", ts.URL, ts.URL)
+	w.Close()
+	oldStdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = oldStdin }()
+	setupClipBatch(t, "-", true)
+
+	_, err = captureStdout(t, func() error { return runClip(clipCmd, []string{"meetings/links"}) })
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "meetings", "links.md"))
+	require.NoError(t, err)
+	section := func(title, path string) string {
+		return "## " + title + "\n\n" + strings.Replace(articleMarkdown, "## ", "### ", 1) + "\nSource: [" + ts.URL + path + "](" + ts.URL + path + ")\n"
+	}
+	assert.Equal(t, "# links\n\n"+section("Second: the Post!", "/two")+"\n"+section("First Post", "/one"), string(content), "sections keep the order of the list")
+}
+
+func TestClipBatchArgs(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, nil)
+	list := writeURLList(t, t.TempDir(), "https://example.com/a")
+
+	setupClipBatch(t, list, true)
+	assert.Equal(t, codeUsage, errorCode(runClip(clipCmd, nil)), "--combine needs a note")
+
+	clipCombine = false
+	assert.Equal(t, codeUsage, errorCode(runClip(clipCmd, []string{"a", "b"})))
+
+	clipFrom = writeURLList(t, t.TempDir(), "example.com/a")
+	assert.Equal(t, codeUsage, errorCode(runClip(clipCmd, nil)), "URLs need a scheme")
+
+	clipFrom = filepath.Join(t.TempDir(), "none.txt")
+	assert.Equal(t, codeNotFound, errorCode(runClip(clipCmd, nil)))
+}
+
+func TestFetchPagesConcurrency(t *testing.T) {
+	var running, most atomic.Int32
+	oldFetch := fetchPage
+	fetchPage = func(ctx context.Context, url string, opts cleanpage.Options) (*cleanpage.Article, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := most.Load()
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return &cleanpage.Article{Title: url}, nil
+	}
+	defer func() { fetchPage = oldFetch }()
+
+	var urls []string
+	for i := range 8 {
+		urls = append(urls, fmt.Sprintf("https://host%d.example.com/", i))
+	}
+	_, err := captureStdout(t, func() error {
+		pages := fetchPages(urls, 3, newHostLimiter(time.Second), cleanpage.Options{}, func(*batchPage) {})
+		for i, page := range pages {
+			assert.Equal(t, urls[i], page.article.Title)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), most.Load())
+}
+
+func TestClipBatchRateCoversImages(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, nil)
+
+	var mu sync.Mutex
+	var requests []time.Time
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, time.Now())
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/a.png":
+			w.Write(testPNG(t, 4, 3))
+			return
+		case "/b.png":
+			w.Write(testPNG(t, 5, 5))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(strings.Replace(articleHTML, "<ul>", `<p><img src="/a.png" alt="A"><img src="/b.png" alt="B"></p><ul>`, 1)))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	setupClipBatch(t, writeURLList(t, t.TempDir(), ts.URL+"/post"), false)
+	clipRate, clipMode, clipImages = 100*time.Millisecond, clipModeFull, true
+	defer func() { clipMode, clipImages = "", false }()
+
+	_, err := captureStdout(t, func() error { return runClip(clipCmd, []string{"reading"}) })
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(tmpDir, "reading", "._images_", "b.png"))
+
+	require.Len(t, requests, 3, "the page and its two images")
+	for i := 1; i < len(requests); i++ {
+		assert.GreaterOrEqual(t, requests[i].Sub(requests[i-1]), 90*time.Millisecond, "request %d", i)
+	}
+}
+
+func TestHostLimiter(t *testing.T) {
+	limiter := newHostLimiter(50 * time.Millisecond)
+	start := time.Now()
+	limiter.wait("https://example.com/a")
+	limiter.wait("https://other.example.com/a")
+	assert.Less(t, time.Since(start), 40*time.Millisecond, "hosts are limited separately")
+
+	limiter.wait("https://example.com/b")
+	limiter.wait("https://example.com/c")
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&cleanpage.StatusError{StatusCode: 503}, true},
+		{&cleanpage.StatusError{StatusCode: 429}, true},
+		{&cleanpage.StatusError{StatusCode: 404}, false},
+		{fmt.Errorf("dial: %w", syscall.ECONNREFUSED), true},
+		{context.DeadlineExceeded, true},
+		{errors.New("no article found"), false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, retryable(tt.err), tt.err.Error())
+	}
+}
+
+func TestNoteSlug(t *testing.T) {
+	assert.Equal(t, "second-the-post", noteSlug("Second: the Post!"))
+	assert.Equal(t, "über-größen", noteSlug("Über Größen"))
+	assert.Equal(t, "example-com-a-b", noteSlug("https://example.com/a/b"))
+}
@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"ned/cleanpage"
+)
+
+// Limits for images downloaded by clip --images
+const (
+	maxClipImageSize  = 10 << 20
+	maxClipImagesSize = 50 << 20
+)
+
+// trackerHosts serve tracking pixels and ads rather than article images.
+// Subdomains match too.
+var trackerHosts = []string{
+	"doubleclick.net", "google-analytics.com", "googletagmanager.com",
+	"googlesyndication.com", "facebook.com", "facebook.net",
+	"scorecardresearch.com", "quantserve.com", "pixel.wp.com",
+	"stats.wp.com", "analytics.twitter.com", "bat.bing.com",
+	"mc.yandex.ru", "hit.gemius.pl", "chartbeat.net", "parsely.com",
+}
+
+// trackerNames are path segments and file names used for tracking pixels
+var trackerNames = map[string]bool{
+	"pixel": true, "beacon": true, "tracking": true, "track": true,
+	"pixel.gif": true, "1x1.gif": true, "1x1.png": true, "spacer.gif": true,
+	"blank.gif": true, "clear.gif": true, "transparent.gif": true,
+}
+
+var imageExtensions = map[string]string{
+	"image/png":     ".png",
+	"image/jpeg":    ".jpg",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/svg+xml": ".svg",
+	"image/avif":    ".avif",
+	"image/bmp":     ".bmp",
+}
+
+var (
+	markdownImageRe = regexp.MustCompile(`!\[([^\]]*)\]\((<[^>]*>|[^)\s]+)\)`)
+	blankLinesRe    = regexp.MustCompile(`\n{3,}`)
+)
+
+// clipImageStats counts what happened to the images of a clip
+type clipImageStats struct {
+	Saved   []string
+	Skipped int
+	Failed  int
+}
+
+// localizeImages downloads the remote images referenced in markdown into
+// imagesDir and points the references at the local copies. Images already
+// in the folder with the same content are reused. Trackers and 1x1 pixels
+// are removed; images that fail to download or are too large keep their
+// remote URL. Images are downloaded as opts ask, like the page at pageURL.
+func localizeImages(markdown, imagesDir, pageURL string, opts cleanpage.Options) (string, clipImageStats, error) {
+	var stats clipImageStats
+	known, err := imageHashes(imagesDir)
+	if err != nil {
+		return "", stats, err
+	}
+
+	resources, err := cleanpage.NewResources(context.Background(), pageURL, opts)
+	if err != nil {
+		return "", stats, err
+	}
+	local := map[string]string{}
+	total := 0
+	var firstErr error
+
+	result := markdownImageRe.ReplaceAllStringFunc(markdown, func(match string) string {
+		m := markdownImageRe.FindStringSubmatch(match)
+		alt, src := m[1], strings.Trim(m[2], "<>")
+
+		if name, ok := local[src]; ok {
+			if name == "" {
+				return ""
+			}
+			return "![" + alt + "](" + name + ")"
+		}
+
+		u, err := url.Parse(src)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return match
+		}
+		if isTracker(u) {
+			local[src] = ""
+			stats.Skipped++
+			return ""
+		}
+
+		data, contentType, err := downloadImage(resources, src, maxClipImageSize)
+		if err != nil || total+len(data) > maxClipImagesSize {
+			stats.Failed++
+			return match
+		}
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil && cfg.Width <= 1 && cfg.Height <= 1 {
+			local[src] = ""
+			stats.Skipped++
+			return ""
+		}
+
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		name, ok := known[hash]
+		if !ok {
+			name, err = saveClipImage(imagesDir, u, contentType, hash, data)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return match
+			}
+			known[hash] = name
+			total += len(data)
+		}
+		local[src] = name
+		if !slices.Contains(stats.Saved, name) {
+			stats.Saved = append(stats.Saved, name)
+		}
+		return "![" + alt + "](" + name + ")"
+	})
+	if firstErr != nil {
+		return "", stats, firstErr
+	}
+
+	// Removed images may leave empty lines behind
+	return blankLinesRe.ReplaceAllString(result, "\n\n"), stats, nil
+}
+
+// isTracker reports whether an image URL looks like a tracking pixel
+func isTracker(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	for _, tracker := range trackerHosts {
+		if host == tracker || strings.HasSuffix(host, "."+tracker) {
+			return true
+		}
+	}
+	for _, segment := range strings.Split(strings.ToLower(u.Path), "/") {
+		if trackerNames[segment] {
+			return true
+		}
+	}
+	return false
+}
+
+// downloadImage fetches an image of at most limit bytes and returns it with
+// its content type
+func downloadImage(resources *cleanpage.Resources, src string, limit int64) ([]byte, string, error) {
+	data, contentType, err := resources.Get(src, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", fmt.Errorf("not an image: %s", contentType)
+	}
+	return data, contentType, nil
+}
+
+// saveClipImage writes an image into imagesDir under the name from its URL.
+// A different image with that name already there gets the hash appended.
+func saveClipImage(imagesDir string, u *url.URL, contentType, hash string, data []byte) (string, error) {
+	if err := os.MkdirAll(imagesDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create images directory: %w", err)
+	}
+
+	base := sanitizeImageName(path.Base(u.Path))
+	ext := path.Ext(base)
+	if isImageFile(base) {
+		base = strings.TrimSuffix(base, ext)
+	} else if ext = imageExtensions[contentType]; ext == "" {
+		ext = ".img"
+	}
+	if base == "" {
+		base = "image"
+	}
+
+	for _, name := range []string{base + ext, base + "-" + hash[:8] + ext} {
+		err := writeFileAtomic(filepath.Join(imagesDir, name), data, 0644, false)
+		if err == nil {
+			return name, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", fmt.Errorf("failed to save image: %w", err)
+		}
+	}
+	return "", fmt.Errorf("failed to save image: %s-%s%s already exists", base, hash[:8], ext)
+}
+
+var unsafeNameRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// sanitizeImageName keeps file names portable and free of separators
+func sanitizeImageName(name string) string {
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	name = strings.Trim(unsafeNameRe.ReplaceAllString(name, "-"), "-.")
+	if len(name) > 80 {
+		name = name[len(name)-80:]
+	}
+	return name
+}
+
+// imageHashes maps the SHA-256 of every file in imagesDir to its name
+func imageHashes(imagesDir string) (map[string]string, error) {
+	hashes := map[string]string{}
+	entries, err := os.ReadDir(imagesDir)
+	if os.IsNotExist(err) {
+		return hashes, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read images directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(imagesDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image: %w", err)
+		}
+		sum := sha256.Sum256(data)
+		hashes[hex.EncodeToString(sum[:])] = entry.Name()
+	}
+	return hashes, nil
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ned/cleanpage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPNG encodes a blank PNG of the given size
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func TestLocalizeImages(t *testing.T) {
+	chart := testPNG(t, 4, 3)
+	photo := testPNG(t, 5, 5)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/img/chart.png", func(w http.ResponseWriter, r *http.Request) { w.Write(chart) })
+	mux.HandleFunc("/copy/chart.png", func(w http.ResponseWriter, r *http.Request) { w.Write(chart) })
+	mux.HandleFunc("/img/photo", func(w http.ResponseWriter, r *http.Request) {
+		// Images are downloaded the way the page was
+		assert.Equal(t, "ned-test/1.0", r.Header.Get("User-Agent"))
+		assert.Equal(t, "notes", r.Header.Get("X-Team"))
+		cookie, err := r.Cookie("sso")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "xyz", cookie.Value)
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(photo)
+	})
+	mux.HandleFunc("/img/dot.png", func(w http.ResponseWriter, r *http.Request) { w.Write(testPNG(t, 1, 1)) })
+	mux.HandleFunc("/pixel/open.gif", func(w http.ResponseWriter, r *http.Request) { t.Error("tracker downloaded") })
+	mux.HandleFunc("/page.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html></html>"))
+	})
+	mux.HandleFunc("/big.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, maxClipImageSize+1))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	imagesDir := filepath.Join(t.TempDir(), "._images_")
+	markdown := strings.Join([]string{
+		"![Chart](" + ts.URL + "/img/chart.png)",
+		"![Again](" + ts.URL + "/copy/chart.png)",
+		"![Photo](" + ts.URL + "/img/photo)",
+		"![](" + ts.URL + "/img/dot.png)",
+		"![](" + ts.URL + "/pixel/open.gif)",
+		"![Page](" + ts.URL + "/page.html)",
+		"![Big](" + ts.URL + "/big.png)",
+		"![Local](local.png)",
+	}, "\n\n") + "\n"
+
+	opts := cleanpage.Options{
+		UserAgent: "ned-test/1.0",
+		Header:    http.Header{"X-Team": {"notes"}},
+		Cookies:   []*http.Cookie{{Name: "sso", Value: "xyz", Domain: "127.0.0.1", Path: "/"}},
+	}
+	got, stats, err := localizeImages(markdown, imagesDir, ts.URL+"/article", opts)
+	require.NoError(t, err)
+
+	want := strings.Join([]string{
+		"![Chart](chart.png)",
+		"![Again](chart.png)",
+		"![Photo](photo.png)",
+		"![Page](" + ts.URL + "/page.html)",
+		"![Big](" + ts.URL + "/big.png)",
+		"![Local](local.png)",
+	}, "\n\n") + "\n"
+	assert.Equal(t, want, got)
+	assert.Equal(t, []string{"chart.png", "photo.png"}, stats.Saved)
+	assert.Equal(t, 2, stats.Skipped)
+	assert.Equal(t, 2, stats.Failed)
+
+	entries, err := os.ReadDir(imagesDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	t.Run("existing images", func(t *testing.T) {
+		// The same content is reused, a different image with the same name
+		// is kept apart
+		require.NoError(t, os.Rename(filepath.Join(imagesDir, "chart.png"), filepath.Join(imagesDir, "saved.png")))
+		require.NoError(t, os.WriteFile(filepath.Join(imagesDir, "photo.png"), testPNG(t, 7, 7), 0644))
+
+		got, _, err := localizeImages("![A]("+ts.URL+"/img/chart.png) ![B]("+ts.URL+"/img/photo)", imagesDir, ts.URL+"/article", opts)
+		require.NoError(t, err)
+
+		assert.Regexp(t, `^!\[A\]\(saved\.png\) !\[B\]\(photo-[0-9a-f]{8}\.png\)$`, got)
+		data, err := os.ReadFile(filepath.Join(imagesDir, strings.TrimSuffix(strings.SplitN(got, "](", 3)[2], ")")))
+		require.NoError(t, err)
+		assert.Equal(t, photo, data)
+	})
+}
+
+func TestIsTracker(t *testing.T) {
+	for src, want := range map[string]bool{
+		"https://www.google-analytics.com/collect?v=1": true,
+		"https://stats.wp.com/g.gif":                   true,
+		"https://example.com/images/1x1.gif":           true,
+		"https://example.com/track/open":               true,
+		"https://example.com/images/chart.png":         false,
+		"https://notfacebook.com/logo.png":             false,
+	} {
+		u, err := url.Parse(src)
+		require.NoError(t, err)
+		assert.Equal(t, want, isTracker(u), src)
+	}
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Clipped pages are remembered in .clips.json at the root of the notes
+// directory, keyed by their normalized URL, so that clipping a page again
+// can point at the note it went to.
+const clipIndexName = ".clips.json"
+
+// clipEntry records where a clipped page went
+type clipEntry struct {
+	URL   string `json:"url" yaml:"url"`
+	Note  string `json:"note" yaml:"note"`
+	Title string `json:"title,omitempty" yaml:"title,omitempty"`
+	// Section is set when the page was added to a note as a section
+	// under its title rather than making up the whole note
+	Section bool      `json:"section,omitempty" yaml:"section,omitempty"`
+	Clipped time.Time `json:"clipped" yaml:"clipped"`
+}
+
+// clipIndex maps normalized URLs to the clips made of them. A page is
+// found under the URL it was clipped with as well as the URL redirects led
+// to and its canonical URL.
+type clipIndex map[string]clipEntry
+
+// trackingParams are query parameters that only track where a visitor came
+// from. Parameters starting with utm_ are dropped too.
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true,
+	"yclid": true, "mc_cid": true, "mc_eid": true, "igshid": true,
+	"_hsenc": true, "_hsmi": true, "mkt_tok": true, "ref_src": true,
+	"si": true, "spm": true, "_ga": true, "oly_enc_id": true, "oly_anon_id": true,
+}
+
+// normalizeURL reduces a URL to the form pages are remembered by: scheme
+// and host in lower case without default ports, no fragment, and the query
+// without tracking parameters, sorted
+func normalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(raw)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (port == "80" && u.Scheme == "http") || (port == "443" && u.Scheme == "https") {
+		u.Host = u.Hostname()
+	}
+	u.Fragment, u.RawFragment = "", ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	query := u.Query()
+	for key := range query {
+		if trackingParams[strings.ToLower(key)] || strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func clipIndexPath() string {
+	return filepath.Join(notesDir, clipIndexName)
+}
+
+// loadClipIndex reads the clip index; it is empty before the first clip
+func loadClipIndex() (clipIndex, error) {
+	index := clipIndex{}
+	data, err := os.ReadFile(clipIndexPath())
+	if os.IsNotExist(err) {
+		return index, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read clip index: %w", err)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to read clip index %s: %w", clipIndexPath(), err)
+	}
+	return index, nil
+}
+
+// lookup returns the last clip of any of urls whose note still exists
+func (idx clipIndex) lookup(urls ...string) (clipEntry, bool) {
+	for _, u := range urls {
+		if u == "" {
+			continue
+		}
+		entry, ok := idx[normalizeURL(u)]
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(notesDir, entry.Note+".md")); err == nil {
+			return entry, true
+		}
+	}
+	return clipEntry{}, false
+}
+
+// entries returns the clips in the index, most recent first
+func (idx clipIndex) entries() []clipEntry {
+	var entries []clipEntry
+	for _, entry := range idx {
+		if !slices.Contains(entries, entry) {
+			entries = append(entries, entry)
+		}
+	}
+	slices.SortFunc(entries, func(a, b clipEntry) int {
+		if c := b.Clipped.Compare(a.Clipped); c != 0 {
+			return c
+		}
+		return strings.Compare(a.URL, b.URL)
+	})
+	return entries
+}
+
+// recordClip remembers a clip under each of urls
+func recordClip(entry clipEntry, urls ...string) error {
+	path := clipIndexPath()
+	lock, err := lockNote(path, "clip")
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	// Read again under the lock, another clip may have added to it
+	index, err := loadClipIndex()
+	if err != nil {
+		return err
+	}
+	for _, u := range urls {
+		if u != "" {
+			index[normalizeURL(u)] = entry
+		}
+	}
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to write clip index: %w", err)
+	}
+	if err := writeFileAtomic(path, append(data, '\n'), filePerm(path, 0644), true); err != nil {
+		return fmt.Errorf("failed to write clip index: %w", err)
+	}
+	return nil
+}
+
+// knownClip asks what to do about a page that was clipped before: open the
+// note it went to, refresh that note, or clip the page anyway. It returns
+// whether to refresh the old clip, and done when nothing is left to do.
+// --yes refreshes without asking. Without a terminal it fails unless
+// --refresh or --again decided already.
+func knownClip(entry clipEntry) (refresh, done bool, err error) {
+	if clipRefresh {
+		return true, false, nil
+	}
+	// --yes answers "Refresh it?" without opening the note in an editor,
+	// which would wait for the user
+	if assumeYes {
+		return true, false, nil
+	}
+	clipped := fmt.Sprintf("%s was clipped to %s on %s", entry.URL, entry.Note, entry.Clipped.Local().Format("2006-01-02"))
+	if !interactive() {
+		return false, true, newCmdError(codeAlreadyExists, "%s (use --refresh to update it or --again to clip it anyway)", clipped)
+	}
+
+	if ok, err := confirm(clipped + ". Open that note?"); err != nil {
+		return false, true, err
+	} else if ok {
+		return false, true, editNote(filepath.Join(notesDir, entry.Note+".md"), 0)
+	}
+	if ok, err := confirm("Refresh it with the current page?"); err != nil {
+		return false, true, err
+	} else if ok {
+		return true, false, nil
+	}
+	ok, err := confirm("Clip it again anyway?")
+	return false, err != nil || !ok, err
+}
+
+// runClipList prints every page that has been clipped
+func runClipList() error {
+	index, err := loadClipIndex()
+	if err != nil {
+		return err
+	}
+	entries := index.entries()
+	if structuredOutput() {
+		if entries == nil {
+			entries = []clipEntry{}
+		}
+		return printResult(entries)
+	}
+	if len(entries) == 0 {
+		fmt.Println("Nothing has been clipped yet")
+		return nil
+	}
+	for _, entry := range entries {
+		fmt.Printf("%s  %s  %s\n", entry.Clipped.Local().Format("2006-01-02 15:04"), entry.Note, entry.URL)
+	}
+	return nil
+}
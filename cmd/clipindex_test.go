@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://Example.COM/post", "https://example.com/post"},
+		{"https://example.com:443/post#comments", "https://example.com/post"},
+		{"http://example.com:8080", "http://example.com:8080/"},
+		{"https://example.com/post?utm_source=feed&utm_medium=rss&id=3&fbclid=abc", "https://example.com/post?id=3"},
+		{"https://example.com/search?q=go&a=1", "https://example.com/search?a=1&q=go"},
+		{"https://example.com/post/", "https://example.com/post/"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, normalizeURL(tt.url), tt.url)
+	}
+}
+
+func TestReplaceSection(t *testing.T) {
+	content := "# Reading\n\n## Old Post\n\nold text\n\n### Part\n\nmore\n\nSource: [a](a)\n\n## Other\n\nkept\n"
+	assert.Equal(t, "# Reading\n\n## New Post\n\nnew text\n\n## Other\n\nkept\n",
+		replaceSection(content, "## Old Post", "## New Post\n\nnew text\n"))
+	assert.Equal(t, "# Reading\n\n## Old Post\n\nnew\n",
+		replaceSection("# Reading\n\n## Old Post\n\nold\n", "## Old Post", "## Old Post\n\nnew\n"), "last section")
+	assert.Equal(t, "# Reading\n\n## Gone\n\nnew\n",
+		replaceSection("# Reading\n", "## Gone", "## Gone\n\nnew\n"), "a missing section is appended")
+}
+
+func TestClipKnownURL(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, nil)
+	t.Setenv("VISUAL", "true")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/post", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(articleHTML))
+	})
+	mux.HandleFunc("/amp", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(strings.Replace(articleHTML, "</head>", `<link rel="canonical" href="/post"></head>`, 1)))
+	})
+	mux.HandleFunc("/news", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(strings.Replace(articleHTML, "<title>A Post</title>", "<title>News</title>", 1)))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	defer func() { clipAgain, clipRefresh = false, false }()
+
+	clip := func(note, url string) (string, error) {
+		return captureStdout(t, func() error { return runClip(clipCmd, []string{note, url}) })
+	}
+	notePath := func(note string) string { return filepath.Join(tmpDir, note+".md") }
+
+	_, err := clip("first", ts.URL+"/post?utm_source=newsletter#top")
+	require.NoError(t, err)
+
+	t.Run("fails without a terminal", func(t *testing.T) {
+		_, err := clip("second", ts.URL+"/post")
+		assert.Equal(t, codeAlreadyExists, errorCode(err))
+		assert.Contains(t, err.Error(), "clipped to first")
+		assert.NoFileExists(t, notePath("second"))
+	})
+
+	t.Run("canonical link", func(t *testing.T) {
+		_, err := clip("second", ts.URL+"/amp")
+		assert.Equal(t, codeAlreadyExists, errorCode(err))
+		assert.NoFileExists(t, notePath("second"))
+	})
+
+	t.Run("open", func(t *testing.T) {
+		p := setupTestPrompter(t, true)
+		_, err := clip("second", ts.URL+"/post")
+		require.NoError(t, err)
+		require.Len(t, p.questions, 1)
+		assert.Contains(t, p.questions[0], "Open that note?")
+		assert.NoFileExists(t, notePath("second"))
+	})
+
+	t.Run("declined", func(t *testing.T) {
+		p := setupTestPrompter(t, false, false, false)
+		_, err := clip("second", ts.URL+"/post")
+		require.NoError(t, err)
+		assert.Len(t, p.questions, 3)
+		assert.NoFileExists(t, notePath("second"))
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(notePath("first"), []byte("stale\n"), 0644))
+		setupTestPrompter(t, false, true)
+		out, err := clip("second", ts.URL+"/post")
+		require.NoError(t, err)
+		assert.Contains(t, out, "Refreshed note: first.md")
+		assert.NoFileExists(t, notePath("second"))
+
+		content, err := os.ReadFile(notePath("first"))
+		require.NoError(t, err)
+		assert.Contains(t, string(content), articleMarkdown)
+		assert.NotContains(t, string(content), "stale")
+	})
+
+	t.Run("yes refreshes without opening the note", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("editor stub is a shell script")
+		}
+		opened := filepath.Join(t.TempDir(), "opened")
+		editor := filepath.Join(t.TempDir(), "editor.sh")
+		require.NoError(t, os.WriteFile(editor, []byte("#!/bin/sh\ntouch "+opened+"\n"), 0755))
+		t.Setenv("VISUAL", editor)
+		require.NoError(t, os.WriteFile(notePath("first"), []byte("stale\n"), 0644))
+
+		for _, terminal := range []bool{false, true} {
+			if terminal {
+				setupTestPrompter(t)
+			}
+			assumeYes = true
+			out, err := clip("second", ts.URL+"/post")
+			assumeYes = false
+			require.NoError(t, err)
+			assert.Contains(t, out, "Refreshed note: first.md")
+		}
+		assert.NoFileExists(t, opened, "the editor is not started")
+		assert.NoFileExists(t, notePath("second"))
+		content, err := os.ReadFile(notePath("first"))
+		require.NoError(t, err)
+		assert.NotContains(t, string(content), "stale")
+	})
+
+	t.Run("again", func(t *testing.T) {
+		clipAgain = true
+		defer func() { clipAgain = false }()
+		_, err := clip("second", ts.URL+"/post")
+		require.NoError(t, err)
+		assert.FileExists(t, notePath("second"))
+	})
+
+	t.Run("refresh a section", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(notePath("reading"), []byte("# Reading\n"), 0644))
+		clipAgain, clipAppend = true, true
+		_, err := clip("reading", ts.URL+"/post")
+		clipAgain, clipAppend = false, false
+		require.NoError(t, err)
+
+		clipRefresh = true
+		defer func() { clipRefresh = false }()
+		_, err = clip("elsewhere", ts.URL+"/post")
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(notePath("reading"))
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(content), "## A Post"), "the section is replaced")
+	})
+
+	t.Run("list", func(t *testing.T) {
+		_, err := clip("news", ts.URL+"/news")
+		require.NoError(t, err)
+
+		clipList = true
+		defer func() { clipList = false }()
+		out, err := captureStdout(t, func() error { return runClip(clipCmd, nil) })
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		require.Len(t, lines, 2, out)
+		assert.Contains(t, lines[0], "news  "+ts.URL+"/news")
+		assert.Contains(t, lines[1], "reading  "+ts.URL+"/post", "a page is listed with its last clip")
+	})
+}
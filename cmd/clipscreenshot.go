@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ned/cleanpage"
+)
+
+// Chrome takes the screenshots of clip --screenshot. Tests replace these.
+var (
+	hasChrome      = cleanpage.HasChrome
+	takeScreenshot = cleanpage.Screenshot
+)
+
+// screenshotsWanted reports whether to take screenshots of clipped pages.
+// Without Chrome there are none, and the user is told why.
+func screenshotsWanted() bool {
+	if !clipScreenshot {
+		return false
+	}
+	if !hasChrome() {
+		fmt.Fprintln(os.Stderr, "Chrome is not installed, so no screenshot is taken. Install Chrome or Chromium to use --screenshot.")
+		return false
+	}
+	return true
+}
+
+// addScreenshot saves the screenshot of a page into the images directory
+// next to the note at notePath, without replacing an image already there,
+// and embeds it at the top of body, under its heading. It returns body and
+// the name of the image.
+func addScreenshot(body string, png []byte, notePath, name, title string) (string, string, error) {
+	imagesDir := filepath.Join(filepath.Dir(notePath), "._images_")
+	if err := os.MkdirAll(imagesDir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create images directory: %w", err)
+	}
+	name, err := saveClipFile(imagesDir, name, png)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to save screenshot: %w", err)
+	}
+
+	image := "![Screenshot of " + strings.NewReplacer("[", "", "]", "").Replace(title) + "](" + name + ")"
+	if first, rest, _ := strings.Cut(body, "\n"); headingRe.MatchString(first) {
+		return first + "\n\n" + image + "\n\n" + strings.TrimLeft(rest, "\n"), name, nil
+	}
+	return image + "\n\n" + body, name, nil
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ned/cleanpage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubChrome makes screenshots come from shoot, or Chrome missing for nil
+func stubChrome(t *testing.T, shoot func(url string) ([]byte, error)) {
+	t.Helper()
+	oldHas, oldTake := hasChrome, takeScreenshot
+	hasChrome = func() bool { return shoot != nil }
+	takeScreenshot = func(ctx context.Context, url string, opts cleanpage.Options) ([]byte, error) {
+		return shoot(url)
+	}
+	t.Cleanup(func() { hasChrome, takeScreenshot = oldHas, oldTake })
+}
+
+func TestClipScreenshot(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, nil)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(articleHTML))
+	}))
+	defer ts.Close()
+	clipMode, clipScreenshot, clipAgain = clipModeFull, true, true
+	defer func() { clipMode, clipScreenshot, clipAgain = "", false, false }()
+
+	clip := func(note string) string {
+		t.Helper()
+		_, err := captureStdout(t, func() error { return runClip(clipCmd, []string{note, ts.URL + "/post"}) })
+		require.NoError(t, err)
+		content, err := os.ReadFile(filepath.Join(tmpDir, note+".md"))
+		require.NoError(t, err)
+		_, body := parseFrontMatter(content)
+		return string(body)
+	}
+
+	t.Run("embedded at the top", func(t *testing.T) {
+		png := testPNG(t, 8, 20)
+		var shot string
+		stubChrome(t, func(url string) ([]byte, error) {
+			shot = url
+			return png, nil
+		})
+		body := clip("Shot")
+		assert.Equal(t, ts.URL+"/post", shot)
+		assert.Contains(t, body, "# A Post\n\n![Screenshot of A Post](shot-screenshot.png)\n\n## Getting started\n")
+
+		saved, err := os.ReadFile(filepath.Join(tmpDir, "._images_", "shot-screenshot.png"))
+		require.NoError(t, err)
+		assert.Equal(t, png, saved)
+	})
+
+	t.Run("without Chrome", func(t *testing.T) {
+		stubChrome(t, nil)
+		body := clip("plain")
+		assert.NotContains(t, body, "Screenshot")
+		assert.NoFileExists(t, filepath.Join(tmpDir, "._images_", "plain-screenshot.png"))
+	})
+
+	t.Run("failed screenshot", func(t *testing.T) {
+		stubChrome(t, func(string) ([]byte, error) { return nil, errors.New("page crashed") })
+		body := clip("crashed")
+		assert.NotContains(t, body, "Screenshot")
+		assert.Contains(t, body, "## Getting started")
+	})
+}
+
+func TestAddScreenshot(t *testing.T) {
+	dir := t.TempDir()
+	notePath := filepath.Join(dir, "note.md")
+
+	body, name, err := addScreenshot("## A [Post]\n\ntext\n", []byte("png"), notePath, "note-a-post-screenshot.png", "A [Post]")
+	require.NoError(t, err)
+	assert.Equal(t, "note-a-post-screenshot.png", name)
+	assert.Equal(t, "## A [Post]\n\n![Screenshot of A Post](note-a-post-screenshot.png)\n\ntext\n", body, "sections keep their heading first")
+	assert.FileExists(t, filepath.Join(dir, "._images_", name))
+
+	body, _, err = addScreenshot("A summary.\n", []byte("png"), notePath, "note-screenshot.png", "Post")
+	require.NoError(t, err)
+	assert.Equal(t, "![Screenshot of Post](note-screenshot.png)\n\nA summary.\n", body)
+
+	t.Run("existing files are kept", func(t *testing.T) {
+		// The same screenshot is reused, a different one gets another name
+		_, name, err := addScreenshot("text\n", []byte("png"), notePath, "note-screenshot.png", "Post")
+		require.NoError(t, err)
+		assert.Equal(t, "note-screenshot.png", name)
+
+		_, name, err = addScreenshot("text\n", []byte("new png"), notePath, "note-screenshot.png", "Post")
+		require.NoError(t, err)
+		assert.Regexp(t, `^note-screenshot-[0-9a-f]{8}\.png$`, name)
+		old, err := os.ReadFile(filepath.Join(dir, "._images_", "note-screenshot.png"))
+		require.NoError(t, err)
+		assert.Equal(t, "png", string(old))
+	})
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ned/cleanpage"
+
+	"github.com/spf13/cobra"
+)
+
+// Shell completion for note, folder, image and config key arguments. The
+// scripts printed by "ned completion bash|zsh|fish|powershell" call back
+// into these functions, so they must be quick and print nothing else.
+
+func init() {
+	for _, c := range []*cobra.Command{editCmd, viewCmd, deleteCmd, appendCmd} {
+		c.ValidArgsFunction = completeFirstArg(completeNotes)
+	}
+	for _, c := range []*cobra.Command{imageListCmd, listCmd} {
+		c.ValidArgsFunction = completeFirstArg(completeFolders)
+	}
+	imageShowCmd.ValidArgsFunction = completeFirstArg(completeImages)
+	clipCmd.ValidArgsFunction = completeFirstArg(completeNotes)
+	importCmd.ValidArgsFunction = completeImportArgs
+	addCmd.RegisterFlagCompletionFunc("to", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeNotes(toComplete)
+	})
+	clipCmd.RegisterFlagCompletionFunc("mode", cobra.FixedCompletions(clipModes, cobra.ShellCompDirectiveNoFileComp))
+	clipCmd.RegisterFlagCompletionFunc("fetcher", cobra.FixedCompletions(cleanpage.Strategies, cobra.ShellCompDirectiveNoFileComp))
+	configSetCmd.ValidArgsFunction = completeConfigArgs
+}
+
+type completeFunc func(toComplete string) ([]string, cobra.ShellCompDirective)
+
+// completeFirstArg completes the first argument with complete and nothing
+// after it
+func completeFirstArg(complete completeFunc) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return complete(toComplete)
+	}
+}
+
+// completeNotes completes note names without the .md extension
+func completeNotes(toComplete string) ([]string, cobra.ShellCompDirective) {
+	var names []string
+	walkCompletions(func(rel string, d fs.DirEntry) {
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".md") {
+			names = appendMatch(names, strings.TrimSuffix(rel, ".md"), toComplete)
+		}
+	})
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFolders completes folder names relative to the notes directory
+func completeFolders(toComplete string) ([]string, cobra.ShellCompDirective) {
+	var names []string
+	walkCompletions(func(rel string, d fs.DirEntry) {
+		if d.IsDir() {
+			names = appendMatch(names, rel, toComplete)
+		}
+	})
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeImages completes images as folder/filename
+func completeImages(toComplete string) ([]string, cobra.ShellCompDirective) {
+	images, err := scanImages()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var names []string
+	for _, image := range images {
+		names = appendMatch(names, image.Name, toComplete)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeImportArgs completes local files for the image source and
+// folders for the target
+func completeImportArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return nil, cobra.ShellCompDirectiveDefault
+	case 1:
+		return completeFolders(toComplete)
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeConfigArgs completes known and already set config keys, then the
+// allowed values of keys that have a fixed set
+func completeConfigArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		keys := make(map[string]string)
+		for key, desc := range configKeys {
+			keys[key] = desc
+		}
+		if config, err := loadConfig(); err == nil {
+			for key := range config.Values {
+				if _, ok := keys[key]; !ok {
+					keys[key] = ""
+				}
+			}
+		}
+
+		var completions []string
+		for key, desc := range keys {
+			if !strings.HasPrefix(key, toComplete) {
+				continue
+			}
+			if desc != "" {
+				key += "\t" + desc
+			}
+			completions = append(completions, key)
+		}
+		sort.Strings(completions)
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	case 1:
+		var values []string
+		for _, value := range configKeyValues[args[0]] {
+			values = appendMatch(values, value, toComplete)
+		}
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// walkCompletions calls fn for every visible file and folder under the notes
+// directory with its slash-separated relative path. Unreadable entries are
+// skipped since completion has no way to report errors.
+func walkCompletions(fn func(rel string, d fs.DirEntry)) {
+	filepath.WalkDir(notesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == notesDir {
+			return nil
+		}
+		if d.IsDir() && isHiddenDir(d.Name()) {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(notesDir, path)
+		if err != nil {
+			return nil
+		}
+		fn(filepath.ToSlash(rel), d)
+		return nil
+	})
+}
+
+func appendMatch(names []string, name, toComplete string) []string {
+	if strings.HasPrefix(name, toComplete) {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletion(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, map[string]string{"CUSTOM_KEY": "x"})
+
+	for _, file := range []string{
+		"todo.md",
+		"work/plan.md",
+		"work/meetings/weekly.md",
+		"work/._images_/chart.png",
+		"._images_/logo.jpg",
+		".git/HEAD",
+		"empty/.keep",
+	} {
+		path := filepath.Join(tmpDir, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, nil, 0644))
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"notes", []string{"edit", ""}, []string{"todo", "work/meetings/weekly", "work/plan"}},
+		{"notes by prefix", []string{"view", "work/m"}, []string{"work/meetings/weekly"}},
+		{"delete notes", []string{"delete", "t"}, []string{"todo"}},
+		{"clip note", []string{"clip", "w"}, []string{"work/meetings/weekly", "work/plan"}},
+		{"clip url", []string{"clip", "todo", ""}, nil},
+		{"only one note", []string{"edit", "todo", ""}, nil},
+		{"image folders", []string{"image", "list", ""}, []string{"empty", "work", "work/meetings"}},
+		{"import folder", []string{"import", "pic.png", "w"}, []string{"work", "work/meetings"}},
+		{"images", []string{"image", "show", ""}, []string{"logo.jpg", "work/chart.png"}},
+		{"config keys", []string{"config", "set", ""}, []string{"ADD_TIMESTAMP", "ANTHROPIC_API_KEY", "CLIP_COOKIES", "CLIP_FETCHER", "CLIP_HEADERS", "CLIP_PROXY", "CLIP_TIMEOUT", "CLIP_USER_AGENT", "CUSTOM_KEY", "EDITOR", "INBOX", "VIEW_THEME", "VIEW_THEME_DIR"}},
+		{"add target note", []string{"add", "idea", "--to", "wo"}, []string{"work/meetings/weekly", "work/plan"}},
+		{"config values", []string{"config", "set", "VIEW_THEME", "d"}, []string{"dark"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			rootCmd.SetOut(&out)
+			rootCmd.SetArgs(append([]string{cobra.ShellCompRequestCmd}, tt.args...))
+			defer rootCmd.SetOut(nil)
+			defer rootCmd.SetArgs(nil)
+
+			require.NoError(t, rootCmd.Execute())
+
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+				if line == "" || strings.HasPrefix(line, ":") {
+					continue
+				}
+				name, _, _ := strings.Cut(line, "\t")
+				got = append(got, name)
+			}
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+
+	t.Run("import source uses file completion", func(t *testing.T) {
+		_, directive := completeImportArgs(importCmd, nil, "")
+		assert.Equal(t, cobra.ShellCompDirectiveDefault, directive)
+	})
+}
@@ -1,10 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/BurntSushi/toml"
 	"github.com/spf13/cobra"
@@ -14,6 +15,29 @@ type Config struct {
 	Values map[string]string `toml:"values"`
 }
 
+// configKeys lists the settings ned reads, with a short description each
+var configKeys = map[string]string{
+	"ADD_TIMESTAMP":     "Prefix text added with add and append with a timestamp bullet",
+	"ANTHROPIC_API_KEY": "API key used to summarize clipped pages",
+	"CLIP_COOKIES":      "Cookies file in the Netscape cookies.txt format sent with clip downloads",
+	"CLIP_FETCHER":      "How clip downloads pages: auto, chrome or http",
+	"CLIP_HEADERS":      "Extra request headers for clip, one \"Name: value\" per line",
+	"CLIP_PROXY":        "Proxy URL for clip downloads",
+	"CLIP_TIMEOUT":      "Time limit for downloading a page, such as 45s",
+	"CLIP_USER_AGENT":   "User agent sent by clip",
+	"EDITOR":            "Editor command, overriding $VISUAL and $EDITOR",
+	"INBOX":             "Note that add and append write to by default",
+	"VIEW_THEME":        "Color scheme of viewed pages: auto, light or dark",
+	"VIEW_THEME_DIR":    "Directory with template and stylesheet overrides",
+}
+
+// configKeyValues lists the allowed values of settings with a fixed set
+var configKeyValues = map[string][]string{
+	"ADD_TIMESTAMP": {"true", "false"},
+	"CLIP_FETCHER":  {"auto", "chrome", "http"},
+	"VIEW_THEME":    {"auto", "light", "dark"},
+}
+
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Manage configuration",
@@ -42,10 +66,13 @@ var configSetCmd = &cobra.Command{
 		}
 
 		if existingValue, exists := config.Values[key]; exists {
-			fmt.Printf("Key '%s' already exists with value '%s'. Do you want to override it? (y/N): ", key, existingValue)
-			var response string
-			fmt.Scanln(&response)
-			if !strings.EqualFold(response, "y") {
+			ok, err := confirm(fmt.Sprintf("Key '%s' already exists with value '%s'. Do you want to override it?", key, existingValue))
+			if errors.Is(err, errNoInput) {
+				return newCmdError(codeAlreadyExists, "key '%s' already exists, use --yes to override it", key)
+			} else if err != nil {
+				return err
+			}
+			if !ok {
 				return nil
 			}
 		}
@@ -62,7 +89,15 @@ var configShowCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		config, err := loadConfig()
 		if err != nil {
-			return err
+			return newCmdError(codeConfig, "failed to load config: %w", err)
+		}
+
+		if structuredOutput() {
+			values := config.Values
+			if values == nil {
+				values = map[string]string{}
+			}
+			return printResult(values)
 		}
 
 		if len(config.Values) == 0 {
@@ -122,11 +157,7 @@ func saveConfig(config *Config) error {
 		return err
 	}
 
-	f, err := os.Create(configPath)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	return toml.NewEncoder(f).Encode(config)
+	return writeAtomic(configPath, filePerm(configPath, 0644), true, func(w io.Writer) error {
+		return toml.NewEncoder(w).Encode(config)
+	})
 }
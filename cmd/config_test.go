@@ -13,6 +13,8 @@ import (
 )
 
 func TestConfigSetCmd(t *testing.T) {
+	setupTestConfig(t, nil)
+
 	tests := []struct {
 		name     string
 		args     []string
@@ -25,6 +27,22 @@ func TestConfigSetCmd(t *testing.T) {
 			args:    []string{"test_key", "test_value"},
 			wantErr: false,
 		},
+		{
+			name: "override existing key when confirmed",
+			args: []string{"test_key", "new_value"},
+			simulate: func() {
+				setupTestPrompter(t, true)
+			},
+		},
+		{
+			name:    "existing key without input",
+			args:    []string{"test_key", "other_value"},
+			wantErr: true,
+			errMsg:  "use --yes to override",
+			simulate: func() {
+				noInput = true
+			},
+		},
 		{
 			name:    "missing value",
 			args:    []string{"test_key"},
@@ -46,6 +64,7 @@ func TestConfigSetCmd(t *testing.T) {
 			}
 
 			err := configSetCmd.RunE(configSetCmd, tt.args)
+			noInput = false
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// fileCTime returns the inode change time of a file
+func fileCTime(info fs.FileInfo) time.Time {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Ctimespec.Unix())
+	}
+	return info.ModTime()
+}
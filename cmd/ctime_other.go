@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !windows
+
+package cmd
+
+import (
+	"io/fs"
+	"time"
+)
+
+// fileCTime falls back to the modification time on platforms without a
+// portable change time
+func fileCTime(info fs.FileInfo) time.Time {
+	return info.ModTime()
+}
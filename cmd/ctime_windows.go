@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// fileCTime returns the creation time of a file
+func fileCTime(info fs.FileInfo) time.Time {
+	if data, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, data.CreationTime.Nanoseconds())
+	}
+	return info.ModTime()
+}
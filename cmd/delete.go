@@ -1,7 +1,7 @@
 package cmd
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -16,14 +16,23 @@ var (
 	silent bool
 )
 
+// deleteResult is the structured output of the delete command
+type deleteResult struct {
+	Path    string `json:"path" yaml:"path"`
+	Deleted bool   `json:"deleted" yaml:"deleted"`
+}
+
 var deleteCmd = &cobra.Command{
 	Use:   "delete [filename]",
 	Short: "Delete a note or empty directory",
 	Long: `Delete a note or directory. The .md extension is optional for note files.
 Empty directories can be deleted normally. Use --force to delete non-empty directories.
-Use --silent to skip confirmation prompt.`,
+Use --silent or the global --yes to skip confirmation prompt.
+
+Without a filename, a fuzzy finder lists all notes; select several with Tab
+to delete them together.`,
 	Aliases: []string{"d"},
-	Args:    cobra.ExactArgs(1),
+	Args:    cobra.MaximumNArgs(1),
 	RunE:    runDelete,
 }
 
@@ -33,15 +42,82 @@ func init() {
 	rootCmd.AddCommand(deleteCmd)
 }
 
+// deleteTarget is a note or directory resolved for deletion
+type deleteTarget struct {
+	arg      string
+	fullPath string
+	show     string
+	info     os.FileInfo
+}
+
 func runDelete(cmd *cobra.Command, args []string) error {
-	path := args[0]
+	if len(args) == 0 {
+		names, err := pickNotes("delete", true)
+		if err != nil || names == nil {
+			return err
+		}
+		args = names
+	}
+
+	targets := make([]deleteTarget, 0, len(args))
+	for _, path := range args {
+		target, err := resolveDeleteTarget(path)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, target)
+	}
+
+	// Confirm deletion unless silent flag is set
+	if !silent {
+		question := fmt.Sprintf("Are you sure you want to delete '%s'?", targets[0].arg)
+		if len(targets) > 1 {
+			question = fmt.Sprintf("Are you sure you want to delete %d notes (%s)?", len(targets), strings.Join(args, ", "))
+		}
+		ok, confirmErr := confirm(question)
+		if errors.Is(confirmErr, errNoInput) {
+			return newCmdError(codeUsage, "refusing to delete '%s' without confirmation, use --yes or --silent", strings.Join(args, "', '"))
+		} else if confirmErr != nil {
+			return confirmErr
+		}
+		if !ok {
+			if structuredOutput() {
+				return printDeleteResults(targets, false)
+			}
+			fmt.Println("Deletion cancelled")
+			return nil
+		}
+	}
+
+	for _, target := range targets {
+		// Perform deletion
+		var err error
+		if target.info.IsDir() && force {
+			err = os.RemoveAll(target.fullPath)
+		} else {
+			err = removeNote(target.fullPath)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to delete: %w", err)
+		}
+		if !structuredOutput() {
+			fmt.Printf("Deleted: %s\n", target.show)
+		}
+	}
+
+	if structuredOutput() {
+		return printDeleteResults(targets, true)
+	}
+	return nil
+}
 
+// resolveDeleteTarget finds the note or directory named by path and checks
+// that it may be deleted
+func resolveDeleteTarget(path string) (deleteTarget, error) {
 	// Create full path
 	fullPath := filepath.Join(notesDir, path)
 
-	var info os.FileInfo
-	var err error
-
 	// Check if path exists
 	checkInfo, err := os.Stat(fullPath)
 	if err == nil && !checkInfo.IsDir() && !strings.HasSuffix(path, ".md") {
@@ -51,7 +127,7 @@ func runDelete(cmd *cobra.Command, args []string) error {
 
 	// Try both with and without .md extension for files
 	pathToShow := path
-	info, err = os.Stat(fullPath)
+	info, err := os.Stat(fullPath)
 	if os.IsNotExist(err) && !strings.HasSuffix(fullPath, ".md") {
 		// Try with .md extension
 		info, err = os.Stat(fullPath + ".md")
@@ -62,52 +138,34 @@ func runDelete(cmd *cobra.Command, args []string) error {
 	}
 
 	if os.IsNotExist(err) {
-		return fmt.Errorf("note or directory not found: %s", path)
+		return deleteTarget{}, newCmdError(codeNotFound, "note or directory not found: %s", path)
 	} else if err != nil {
-		return fmt.Errorf("error accessing path: %w", err)
+		return deleteTarget{}, fmt.Errorf("error accessing path: %w", err)
 	}
 
 	// Handle directory deletion
 	if info.IsDir() {
 		isEmpty, dirErr := isDirEmpty(fullPath)
 		if dirErr != nil {
-			return fmt.Errorf("error checking directory: %w", dirErr)
+			return deleteTarget{}, fmt.Errorf("error checking directory: %w", dirErr)
 		}
 
 		if !isEmpty && !force {
-			return fmt.Errorf("directory not empty, use --force to delete recursively")
-		}
-	}
-
-	// Confirm deletion unless silent flag is set
-	if !silent {
-		fmt.Printf("Are you sure you want to delete '%s'? [y/N]: ", path)
-		reader := bufio.NewReader(os.Stdin)
-		response, confirmErr := reader.ReadString('\n')
-		if confirmErr != nil {
-			return fmt.Errorf("error reading response: %w", confirmErr)
-		}
-
-		response = strings.ToLower(strings.TrimSpace(response))
-		if response != "y" && response != "yes" {
-			fmt.Println("Deletion cancelled")
-			return nil
+			return deleteTarget{}, newCmdError(codeUsage, "directory not empty, use --force to delete recursively")
 		}
 	}
 
-	// Perform deletion
-	if info.IsDir() && force {
-		err = os.RemoveAll(fullPath)
-	} else {
-		err = os.Remove(fullPath)
-	}
+	return deleteTarget{arg: path, fullPath: fullPath, show: pathToShow, info: info}, nil
+}
 
-	if err != nil {
-		return fmt.Errorf("failed to delete: %w", err)
+// printDeleteResults prints the results of the targets as a list, even for
+// a single note, so that scripts read one shape
+func printDeleteResults(targets []deleteTarget, deleted bool) error {
+	results := make([]deleteResult, len(targets))
+	for i, target := range targets {
+		results[i] = deleteResult{Path: target.show, Deleted: deleted}
 	}
-
-	fmt.Printf("Deleted: %s\n", pathToShow)
-	return nil
+	return printResult(results)
 }
 
 // isDirEmpty returns true if the directory is empty
@@ -124,3 +182,14 @@ func isDirEmpty(dir string) (bool, error) {
 	}
 	return false, err
 }
+
+// removeNote deletes a note, or an empty directory, once no other ned
+// command is writing it
+func removeNote(path string) error {
+	lock, err := lockNote(path, "delete")
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+	return os.Remove(path)
+}
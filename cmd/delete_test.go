@@ -1,8 +1,7 @@
 package cmd
 
 import (
-	"bytes"
-	"io"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -13,13 +12,15 @@ func TestDeleteCmd(t *testing.T) {
 	defer cleanup()
 
 	tests := []struct {
-		name       string
-		setup      func(t *testing.T) string
-		withForce  bool
-		withSilent bool
-		input      string
-		wantErr    bool
-		verify     func(t *testing.T, path string)
+		name        string
+		setup       func(t *testing.T) string
+		withForce   bool
+		withSilent  bool
+		answers     []bool
+		withYes     bool
+		withNoInput bool
+		wantErr     bool
+		verify      func(t *testing.T, path string)
 	}{
 		{
 			name: "delete file",
@@ -116,7 +117,7 @@ func TestDeleteCmd(t *testing.T) {
 				}
 				return "confirm"
 			},
-			input:   "y\n",
+			answers: []bool{true},
 			wantErr: false,
 			verify: func(t *testing.T, path string) {
 				fullPath := filepath.Join(tmpDir, path+".md")
@@ -135,7 +136,7 @@ func TestDeleteCmd(t *testing.T) {
 				}
 				return "keep"
 			},
-			input:   "n\n",
+			answers: []bool{false},
 			wantErr: false,
 			verify: func(t *testing.T, path string) {
 				fullPath := filepath.Join(tmpDir, path+".md")
@@ -144,6 +145,35 @@ func TestDeleteCmd(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "delete with --yes",
+			setup: func(t *testing.T) string {
+				path := filepath.Join(tmpDir, "assumed.md")
+				if err := os.WriteFile(path, []byte("test content"), 0644); err != nil {
+					t.Fatalf("failed to create test file: %v", err)
+				}
+				return "assumed"
+			},
+			withYes: true,
+			verify: func(t *testing.T, path string) {
+				fullPath := filepath.Join(tmpDir, path+".md")
+				if _, err := os.Stat(fullPath); !os.IsNotExist(err) {
+					t.Errorf("file still exists: %s", path)
+				}
+			},
+		},
+		{
+			name: "delete with --no-input refuses",
+			setup: func(t *testing.T) string {
+				path := filepath.Join(tmpDir, "unasked.md")
+				if err := os.WriteFile(path, []byte("test content"), 0644); err != nil {
+					t.Fatalf("failed to create test file: %v", err)
+				}
+				return "unasked"
+			},
+			withNoInput: true,
+			wantErr:     true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -151,31 +181,15 @@ func TestDeleteCmd(t *testing.T) {
 			// Reset flags
 			force = tt.withForce
 			silent = tt.withSilent
+			assumeYes = tt.withYes
+			noInput = tt.withNoInput
+			defer func() { assumeYes, noInput = false, false }()
 
 			filename := tt.setup(t)
 
-			// Mock stdin if input provided
-			var stdin bytes.Buffer
-			if tt.input != "" {
-				stdin.WriteString(tt.input)
-				oldStdin := os.Stdin
-				r, w, err := os.Pipe()
-				if err != nil {
-					t.Fatalf("failed to create pipe: %v", err)
-				}
-				os.Stdin = r
-
-				errCh := make(chan error, 1)
-				go func() {
-					_, err := io.WriteString(w, tt.input)
-					errCh <- err
-					w.Close()
-				}()
-
-				if err := <-errCh; err != nil {
-					t.Fatalf("failed to write to pipe: %v", err)
-				}
-				defer func() { os.Stdin = oldStdin }()
+			// Answer the confirmation prompt
+			if tt.answers != nil {
+				setupTestPrompter(t, tt.answers...)
 			}
 
 			err := runDelete(deleteCmd, []string{filename})
@@ -196,3 +210,27 @@ func TestDeleteCmd(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteOutput(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setOutputFormat(t, "json")
+	assumeYes = true
+	defer func() { assumeYes = false }()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "old.md"), []byte("# Old\n"), 0644); err != nil {
+		t.Fatalf("failed to write note: %v", err)
+	}
+
+	out, err := captureStdout(t, func() error { return runDelete(deleteCmd, []string{"old"}) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var results []deleteResult
+	if err := json.Unmarshal([]byte(out), &results); err != nil {
+		t.Fatalf("a single note must still be a list: %v\n%s", err, out)
+	}
+	if len(results) != 1 || results[0].Path != "old.md" || !results[0].Deleted {
+		t.Errorf("results = %+v", results)
+	}
+}
@@ -1,10 +1,11 @@
 package cmd
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
@@ -14,21 +15,64 @@ import (
 var editCmd = &cobra.Command{
 	Use:   "edit [filename]",
 	Short: "Edit a note",
-	Long: `Edit a note using the editor specified in EDITOR environment variable.
+	Long: `Edit a note using the editor set by the EDITOR config key, or the VISUAL or
+EDITOR environment variable. The editor may include arguments, e.g.
+"code --wait". If no editor is specified, it defaults to 'vim'.
 The .md extension is optional and will be added automatically if not provided.
-If no editor is specified, it defaults to 'vim'. You can also pipe in content
-to replace the entire note.`,
+You can also pipe in content to replace the entire note. Replacing a note
+that already exists needs --force, or --yes.
+
+Append :N to the note name or use --line to open the note at a line, or
+--search to open it at the first line containing some text. The jump uses
+the syntax of vim, nvim, emacs, code, helix and similar editors.
+
+Without a filename, a fuzzy finder lists all notes to pick from.
+
+The editor works on a copy of the note that is saved back when it exits.
+If the note changed in the meantime, ned offers to merge both changes or
+saves your version as a conflict copy next to the note. While a note is
+being edited, other ned commands ask before writing to it.
+
+Examples:
+  ned edit todo:42
+  ned edit projects/plan --search "## Risks"
+  ned edit journal/2026-10-18 --create
+  pbpaste | ned edit todo --force`,
 	Aliases: []string{"e"},
-	Args:    cobra.ExactArgs(1),
+	Args:    cobra.MaximumNArgs(1),
 	RunE:    runEdit,
 }
 
+var (
+	editLine   int
+	editSearch string
+	editCreate bool
+	editForce  bool
+)
+
 func init() {
+	editCmd.Flags().IntVarP(&editLine, "line", "l", 0, "Open the note at this line")
+	editCmd.Flags().StringVarP(&editSearch, "search", "s", "", "Open the note at the first line containing this text")
+	editCmd.Flags().BoolVarP(&editCreate, "create", "c", false, "Create the note if it does not exist")
+	editCmd.Flags().BoolVarP(&editForce, "force", "f", false, "Replace an existing note with the piped content")
+	editCmd.MarkFlagsMutuallyExclusive("line", "search")
 	rootCmd.AddCommand(editCmd)
 }
 
 func runEdit(cmd *cobra.Command, args []string) error {
-	filename := args[0]
+	if len(args) == 0 {
+		names, err := pickNotes("edit", false)
+		if err != nil || names == nil {
+			return err
+		}
+		args = names
+	}
+	filename, line := args[0], editLine
+	if _, err := os.Stat(noteArgPath(filename)); os.IsNotExist(err) {
+		if name, l := splitNoteLine(filename); l > 0 {
+			filename, line = name, l
+		}
+	}
 
 	// Ensure filename has .md extension
 	if !strings.HasSuffix(filename, ".md") {
@@ -40,43 +84,108 @@ func runEdit(cmd *cobra.Command, args []string) error {
 	}
 
 	// Check if file exists
+	created := false
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		return fmt.Errorf("note not found: %s", filename)
+		if !editCreate {
+			return newCmdError(codeNotFound, "note not found: %s (use --create to create it)", filename)
+		}
+		if err := createNote(filename); err != nil {
+			return err
+		}
+		created = true
 	}
 
 	// Check if we have content from stdin
 	stat, _ := os.Stdin.Stat()
 	if (stat.Mode() & os.ModeCharDevice) == 0 {
-		// Read content from stdin
-		scanner := bufio.NewScanner(os.Stdin)
-		var content string
-		for scanner.Scan() {
-			content += scanner.Text() + "\n"
+		// A note that already exists is only replaced when asked to
+		if !created && !editForce {
+			ok, err := confirm(fmt.Sprintf("Replace %s with the piped content?", filename))
+			if err != nil && !errors.Is(err, errNoInput) {
+				return err
+			}
+			if !ok {
+				return newCmdError(codeAlreadyExists, "note already exists: %s (use --force to replace it)", filename)
+			}
+		}
+
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+
+		lock, err := lockNote(filename, "edit")
+		if err != nil {
+			return err
 		}
+		defer lock.unlock()
 
 		// Write content to file
-		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		if err := writeFileAtomic(filename, content, filePerm(filename, 0644), true); err != nil {
 			return fmt.Errorf("failed to write content: %w", err)
 		}
 		return nil
 	}
 
-	// Get editor from environment variable
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		editor = "vim" // Default to vim if no editor is specified
+	if editSearch != "" {
+		found, err := searchLine(filename, editSearch)
+		if err != nil {
+			return fmt.Errorf("failed to search note: %w", err)
+		}
+		if found == 0 {
+			return newCmdError(codeNotFound, "%q not found in %s", editSearch, filename)
+		}
+		line = found
 	}
 
-	// Create command to open editor
-	cmd2 := exec.Command(editor, filename)
-	cmd2.Stdin = os.Stdin
-	cmd2.Stdout = os.Stdout
-	cmd2.Stderr = os.Stderr
+	return editNote(filename, line)
+}
 
-	// Run editor
-	if err := cmd2.Run(); err != nil {
-		return fmt.Errorf("failed to run editor: %w", err)
+// noteArgPath returns the file a note argument refers to
+func noteArgPath(name string) string {
+	if !strings.HasSuffix(name, ".md") {
+		name += ".md"
+	}
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(notesDir, name)
 	}
+	return name
+}
 
+// checkInNotesDir returns the absolute path of filename, or an error if it
+// is not inside the notes directory
+func checkInNotesDir(filename string) (string, error) {
+	absNotesDir, err := filepath.Abs(notesDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve notes directory path: %w", err)
+	}
+	absPath, err := filepath.Abs(filename)
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %w", err)
+	}
+	if !strings.HasPrefix(absPath, absNotesDir+string(filepath.Separator)) {
+		return "", newCmdError(codeInvalidPath, "path must be within notes directory")
+	}
+	return absPath, nil
+}
+
+// createNote creates an empty note titled after its file name. The note
+// must be inside the notes directory.
+func createNote(filename string) error {
+	absPath, err := checkInNotesDir(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
+	}
+	title := strings.TrimSuffix(filepath.Base(absPath), ".md")
+	if err := writeFileAtomic(absPath, []byte(fmt.Sprintf("# %s\n\n", title)), 0644, false); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return newCmdError(codeAlreadyExists, "note already exists: %s", filename)
+		}
+		return fmt.Errorf("failed to create note file: %w", err)
+	}
 	return nil
 }
@@ -16,6 +16,8 @@ func TestEditCmd(t *testing.T) {
 
 	tmpDir, cleanup := setupTestEnv(t)
 	defer cleanup()
+	editForce = true
+	defer func() { editForce = false }()
 
 	tests := []struct {
 		name    string
@@ -136,3 +138,81 @@ func TestEditCmd(t *testing.T) {
 		})
 	}
 }
+
+// pipeStdin replaces stdin with a pipe that yields content
+func pipeStdin(t *testing.T, content string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	oldStdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = oldStdin
+		r.Close()
+	})
+	go func() {
+		io.WriteString(w, content)
+		w.Close()
+	}()
+}
+
+func TestEditPipeExistingNote(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	path := filepath.Join(tmpDir, "todo.md")
+	long := strings.Repeat("x", 100000) + "\n"
+
+	tests := []struct {
+		name        string
+		force       bool
+		yes         bool
+		wantCode    string
+		wantContent string
+	}{
+		{name: "refused without force", wantCode: codeAlreadyExists, wantContent: "original\n"},
+		{name: "force replaces", force: true, wantContent: long},
+		{name: "yes replaces", yes: true, wantContent: long},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte("original\n"), 0644); err != nil {
+				t.Fatalf("failed to write note: %v", err)
+			}
+			editForce, assumeYes = tt.force, tt.yes
+			defer func() { editForce, assumeYes = false, false }()
+			pipeStdin(t, long)
+
+			err := runEdit(editCmd, []string{"todo"})
+			if tt.wantCode == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantCode != "" && errorCode(err) != tt.wantCode {
+				t.Fatalf("error = %v, want code %q", err, tt.wantCode)
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read note: %v", err)
+			}
+			if string(content) != tt.wantContent {
+				t.Errorf("note has %d bytes, want %d", len(content), len(tt.wantContent))
+			}
+		})
+	}
+
+	t.Run("new note needs no force", func(t *testing.T) {
+		editCreate = true
+		defer func() { editCreate = false }()
+		pipeStdin(t, "fresh\n")
+
+		if err := runEdit(editCmd, []string{"fresh"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		content, _ := os.ReadFile(filepath.Join(tmpDir, "fresh.md"))
+		if string(content) != "fresh\n" {
+			t.Errorf("content = %q", content)
+		}
+	})
+}
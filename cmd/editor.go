@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// editorCommand returns the user's editor split into the program and its
+// arguments. The EDITOR config key wins over $VISUAL and $EDITOR, and vim
+// is used when none is set.
+func editorCommand() ([]string, error) {
+	editor := ""
+	if config, err := loadConfig(); err == nil {
+		editor = config.Values["EDITOR"]
+	}
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor == "" {
+			editor = os.Getenv(env)
+		}
+	}
+	if strings.TrimSpace(editor) == "" {
+		return []string{"vim"}, nil
+	}
+
+	words, err := splitShellWords(editor)
+	if err != nil {
+		return nil, newCmdError(codeConfig, "invalid editor %q: %w", editor, err)
+	}
+	return words, nil
+}
+
+// splitShellWords splits s into words the way a POSIX shell would, so
+// EDITOR="code --wait" or EDITOR="'/opt/My Editor/bin/edit' -n" work.
+// Quotes and backslashes are honoured; variables and globs are not expanded.
+func splitShellWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case r == '\\':
+			inWord = true
+			if i+1 < len(runes) {
+				i++
+				word.WriteRune(runes[i])
+			}
+		case r == '\'':
+			inWord = true
+			closed := false
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\'' {
+					closed = true
+					break
+				}
+				word.WriteRune(runes[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+		case r == '"':
+			inWord = true
+			closed := false
+			for i++; i < len(runes); i++ {
+				if runes[i] == '"' {
+					closed = true
+					break
+				}
+				// Inside double quotes a backslash only escapes these
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`", runes[i+1]) {
+					i++
+				}
+				word.WriteRune(runes[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+		default:
+			inWord = true
+			word.WriteRune(r)
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// editorArgs returns the command that opens file in editor, at line when
+// it is positive, using the editor's own syntax for the jump
+func editorArgs(editor []string, file string, line int) []string {
+	args := append([]string{}, editor...)
+	// Editor paths may use either separator, whatever the platform ned runs on
+	program := editor[0][strings.LastIndexAny(editor[0], `/\`)+1:]
+	name := strings.TrimSuffix(strings.ToLower(program), ".exe")
+
+	switch name {
+	case "code", "code-insiders", "codium", "cursor":
+		// VS Code returns at once unless told to wait for the file to close
+		if !containsArg(args, "--wait", "-w") {
+			args = append(args, "--wait")
+		}
+		if line > 0 {
+			return append(args, "--goto", fmt.Sprintf("%s:%d", file, line))
+		}
+	case "hx", "helix", "subl", "sublime_text":
+		if line > 0 {
+			return append(args, fmt.Sprintf("%s:%d", file, line))
+		}
+	default:
+		// vi, vim, nvim, emacs, emacsclient, nano, micro and kak all take +N
+		if line > 0 {
+			args = append(args, fmt.Sprintf("+%d", line))
+		}
+	}
+	return append(args, file)
+}
+
+func containsArg(args []string, names ...string) bool {
+	for _, arg := range args[1:] {
+		for _, name := range names {
+			if arg == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitNoteLine splits "note:42" into the note and line. Arguments without
+// a line suffix are returned unchanged with line 0.
+func splitNoteLine(arg string) (string, int) {
+	i := strings.LastIndex(arg, ":")
+	if i <= 0 || i == len(arg)-1 {
+		return arg, 0
+	}
+	line := 0
+	for _, r := range arg[i+1:] {
+		if r < '0' || r > '9' {
+			return arg, 0
+		}
+		line = line*10 + int(r-'0')
+	}
+	return arg[:i], line
+}
+
+// searchLine returns the first line of file that contains text, ignoring
+// case, or 0 when there is none
+func searchLine(file, text string) (int, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	text = strings.ToLower(text)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.Contains(strings.ToLower(scanner.Text()), text) {
+			return line, nil
+		}
+	}
+	return 0, scanner.Err()
+}
+
+// openInEditor opens a file in the user's editor, at line when it is
+// positive, and waits for the editor to exit
+func openInEditor(filename string, line int) error {
+	editor, err := editorCommand()
+	if err != nil {
+		return err
+	}
+	args := editorArgs(editor, filename, line)
+
+	// Create command to open editor
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// Run editor
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run editor: %w", err)
+	}
+
+	return nil
+}
+
+// editNote opens a note in the editor while holding its lock. The editor
+// works on a private copy that is saved back when it exits, so changes made
+// to the note in the meantime are noticed: they are merged with the user's
+// edit, or the edit is saved as a conflict copy next to the note.
+func editNote(notePath string, line int) error {
+	lock, err := lockNote(notePath, "edit")
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	base, err := os.ReadFile(notePath)
+	if err != nil {
+		return fmt.Errorf("failed to read note: %w", err)
+	}
+
+	// The copy keeps the note's file name so the editor shows it and picks
+	// its markdown mode
+	dir, err := os.MkdirTemp("", "ned-edit-*")
+	if err != nil {
+		return fmt.Errorf("failed to create working copy: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	workPath := filepath.Join(dir, filepath.Base(notePath))
+	if err := os.WriteFile(workPath, base, 0600); err != nil {
+		return fmt.Errorf("failed to create working copy: %w", err)
+	}
+
+	if err := openInEditor(workPath, line); err != nil {
+		return err
+	}
+
+	edited, err := os.ReadFile(workPath)
+	if err != nil {
+		return fmt.Errorf("failed to read edited note: %w", err)
+	}
+	if bytes.Equal(edited, base) {
+		return nil
+	}
+
+	current, err := os.ReadFile(notePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read note: %w", err)
+	}
+	if err == nil && !bytes.Equal(current, base) {
+		return resolveEditConflict(notePath, string(base), string(current), string(edited))
+	}
+
+	if err := writeFileAtomic(notePath, edited, filePerm(notePath, 0644), true); err != nil {
+		return fmt.Errorf("failed to save note: %w", err)
+	}
+	return nil
+}
+
+// resolveEditConflict handles an edit of a note that changed on disk while
+// the editor was open. The user may merge both changes into the note;
+// otherwise, or when nobody can be asked, the note is left alone and the
+// edit is saved as a conflict copy.
+func resolveEditConflict(notePath, base, current, edited string) error {
+	merged, conflicts := merge3(base, edited, current)
+
+	question := fmt.Sprintf("%s changed while you were editing it. Merge your changes into it?", notePath)
+	if conflicts > 0 {
+		question = fmt.Sprintf("%s changed while you were editing it and %d of your changes conflict. Merge with conflict markers?", notePath, conflicts)
+	}
+	ok, err := confirm(question)
+	if err != nil && !errors.Is(err, errNoInput) {
+		return err
+	}
+
+	out := promptOutput()
+	if ok {
+		if err := writeFileAtomic(notePath, []byte(merged), filePerm(notePath, 0644), true); err != nil {
+			return fmt.Errorf("failed to save note: %w", err)
+		}
+		if conflicts > 0 {
+			fmt.Fprintf(out, "Merged with %d conflicts marked with %q in %s\n", conflicts, conflictOurs, notePath)
+		} else {
+			fmt.Fprintf(out, "Merged your changes into %s\n", notePath)
+		}
+		return nil
+	}
+
+	copyPath := conflictCopyPath(notePath, time.Now())
+	if err := writeFileAtomic(copyPath, []byte(edited), filePerm(notePath, 0644), false); err != nil {
+		return fmt.Errorf("failed to save conflict copy: %w", err)
+	}
+	fmt.Fprintf(out, "%s changed while you were editing it; your version was saved to %s\n", notePath, copyPath)
+	return nil
+}
+
+// conflictCopyPath returns where an edit that could not be saved to a note
+// is kept: next to the note, named after it and the time
+func conflictCopyPath(notePath string, now time.Time) string {
+	return strings.TrimSuffix(notePath, ".md") + ".conflict-" + now.Format("2006-01-02-150405") + ".md"
+}
@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{in: "vim", want: []string{"vim"}},
+		{in: "  code   --wait ", want: []string{"code", "--wait"}},
+		{in: `'/opt/My Editor/edit' -n`, want: []string{"/opt/My Editor/edit", "-n"}},
+		{in: `"C:\\Program Files\\ed.exe" --new-window`, want: []string{`C:\Program Files\ed.exe`, "--new-window"}},
+		{in: `emacsclient -a "" -t`, want: []string{"emacsclient", "-a", "", "-t"}},
+		{in: `my\ editor`, want: []string{"my editor"}},
+		{in: `vim -c 'set tw=72'`, want: []string{"vim", "-c", "set tw=72"}},
+		{in: `vim 'oops`, wantErr: true},
+		{in: `vim "oops`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := splitShellWords(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEditorArgs(t *testing.T) {
+	tests := []struct {
+		editor []string
+		line   int
+		want   []string
+	}{
+		{[]string{"vim"}, 0, []string{"vim", "n.md"}},
+		{[]string{"vim"}, 42, []string{"vim", "+42", "n.md"}},
+		{[]string{"/usr/bin/nvim"}, 3, []string{"/usr/bin/nvim", "+3", "n.md"}},
+		{[]string{"emacsclient", "-t"}, 7, []string{"emacsclient", "-t", "+7", "n.md"}},
+		{[]string{"code"}, 0, []string{"code", "--wait", "n.md"}},
+		{[]string{"code", "-w"}, 9, []string{"code", "-w", "--goto", "n.md:9"}},
+		{[]string{"hx"}, 5, []string{"hx", "n.md:5"}},
+		{[]string{"helix"}, 0, []string{"helix", "n.md"}},
+		{[]string{`C:\bin\Code.exe`}, 2, []string{`C:\bin\Code.exe`, "--wait", "--goto", "n.md:2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.want, " "), func(t *testing.T) {
+			assert.Equal(t, tt.want, editorArgs(tt.editor, "n.md", tt.line))
+		})
+	}
+}
+
+func TestSplitNoteLine(t *testing.T) {
+	tests := []struct {
+		arg      string
+		wantNote string
+		wantLine int
+	}{
+		{"todo:42", "todo", 42},
+		{"work/plan.md:3", "work/plan.md", 3},
+		{"todo", "todo", 0},
+		{"todo:", "todo:", 0},
+		{"meeting:notes", "meeting:notes", 0},
+		{":12", ":12", 0},
+	}
+
+	for _, tt := range tests {
+		note, line := splitNoteLine(tt.arg)
+		assert.Equal(t, tt.wantNote, note, tt.arg)
+		assert.Equal(t, tt.wantLine, line, tt.arg)
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	setupTestConfig(t, nil)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+
+	editor, err := editorCommand()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vim"}, editor)
+
+	t.Setenv("EDITOR", "nano -w")
+	editor, _ = editorCommand()
+	assert.Equal(t, []string{"nano", "-w"}, editor)
+
+	t.Setenv("VISUAL", "code --wait")
+	editor, _ = editorCommand()
+	assert.Equal(t, []string{"code", "--wait"}, editor, "VISUAL wins over EDITOR")
+
+	setupTestConfig(t, map[string]string{"EDITOR": "hx"})
+	editor, _ = editorCommand()
+	assert.Equal(t, []string{"hx"}, editor, "config wins over the environment")
+}
+
+func TestEditJumps(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("editor stub is a shell script")
+	}
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, nil)
+
+	// The stub is named vim so ned passes it vim's +N syntax; it records
+	// its arguments
+	binDir := t.TempDir()
+	argsFile := filepath.Join(binDir, "args")
+	stub := "#!/bin/sh\necho \"$@\" > " + argsFile + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "vim"), []byte(stub), 0755))
+	t.Setenv("VISUAL", filepath.Join(binDir, "vim"))
+
+	notePath := filepath.Join(tmpDir, "todo.md")
+	require.NoError(t, os.WriteFile(notePath, []byte("# Todo\n\n## Ideas\n- one\n"), 0644))
+
+	// Keep stdin from looking like piped content
+	devNull, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	defer devNull.Close()
+	oldStdin := os.Stdin
+	os.Stdin = devNull
+	defer func() { os.Stdin = oldStdin }()
+
+	reset := func() { editLine, editSearch, editCreate = 0, "", false }
+	defer reset()
+
+	tests := []struct {
+		name     string
+		arg      string
+		setFlags func()
+		want     string
+		wantErr  bool
+	}{
+		{name: "plain", arg: "todo", want: "todo.md"},
+		{name: "line suffix", arg: "todo:3", want: "+3 todo.md"},
+		{name: "line flag", arg: "todo", setFlags: func() { editLine = 2 }, want: "+2 todo.md"},
+		{name: "search", arg: "todo", setFlags: func() { editSearch = "## ideas" }, want: "+3 todo.md"},
+		{name: "search without match", arg: "todo", setFlags: func() { editSearch = "nothing" }, wantErr: true},
+		{name: "missing note", arg: "journal/today", wantErr: true},
+		{name: "create", arg: "journal/today", setFlags: func() { editCreate = true }, want: "today.md"},
+		{name: "create outside notes", arg: "../outside", setFlags: func() { editCreate = true }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset()
+			os.Remove(argsFile)
+			if tt.setFlags != nil {
+				tt.setFlags()
+			}
+
+			err := runEdit(editCmd, []string{tt.arg})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			// The editor gets a working copy named like the note
+			got, err := os.ReadFile(argsFile)
+			require.NoError(t, err)
+			args := strings.Fields(string(got))
+			args[len(args)-1] = filepath.Base(args[len(args)-1])
+			assert.Equal(t, tt.want, strings.Join(args, " "))
+		})
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "journal", "today.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# today\n\n", string(content))
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(tmpDir), "outside.md"))
+}
+
+func TestEditNoteConcurrentChanges(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("editor stub is a shell script")
+	}
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, nil)
+
+	// The stub writes the user's edit to the file it is given and, to
+	// simulate another program, a new version to the note itself
+	stub := filepath.Join(t.TempDir(), "editor.sh")
+	script := "#!/bin/sh\n" +
+		"[ -n \"$NED_TEST_EDIT\" ] && printf '%s' \"$NED_TEST_EDIT\" > \"$1\"\n" +
+		"[ -n \"$NED_TEST_THEIRS\" ] && printf '%s' \"$NED_TEST_THEIRS\" > \"$NED_TEST_NOTE\"\n" +
+		"exit 0\n"
+	require.NoError(t, os.WriteFile(stub, []byte(script), 0755))
+	t.Setenv("VISUAL", stub)
+
+	notePath := filepath.Join(tmpDir, "plan.md")
+	t.Setenv("NED_TEST_NOTE", notePath)
+	base := "# Plan\n\nintro\n\n- one\n"
+
+	tests := []struct {
+		name          string
+		edit, theirs  string
+		answers       []bool
+		noInput       bool
+		want          string
+		wantQuestions int
+		wantCopy      bool
+	}{
+		{
+			name: "edit is saved",
+			edit: "# Plan\n\nbetter intro\n\n- one\n",
+			want: "# Plan\n\nbetter intro\n\n- one\n",
+		},
+		{
+			name:   "untouched edit keeps other changes",
+			theirs: base + "- two\n",
+			want:   base + "- two\n",
+		},
+		{
+			name:          "changes are merged",
+			edit:          "# Plan\n\nbetter intro\n\n- one\n",
+			theirs:        base + "- two\n",
+			answers:       []bool{true},
+			want:          "# Plan\n\nbetter intro\n\n- one\n- two\n",
+			wantQuestions: 1,
+		},
+		{
+			name:          "conflicts are marked",
+			edit:          "# Plan\n\nmine\n\n- one\n",
+			theirs:        "# Plan\n\ntheirs\n\n- one\n",
+			answers:       []bool{true},
+			want:          "# Plan\n\n<<<<<<< your edit\nmine\n=======\ntheirs\n>>>>>>> changed meanwhile\n\n- one\n",
+			wantQuestions: 1,
+		},
+		{
+			name:          "declined merge saves a conflict copy",
+			edit:          "# Plan\n\nbetter intro\n\n- one\n",
+			theirs:        base + "- two\n",
+			answers:       []bool{false},
+			want:          base + "- two\n",
+			wantQuestions: 1,
+			wantCopy:      true,
+		},
+		{
+			name:     "without input a conflict copy is saved",
+			edit:     "# Plan\n\nbetter intro\n\n- one\n",
+			theirs:   base + "- two\n",
+			noInput:  true,
+			want:     base + "- two\n",
+			wantCopy: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(notePath, []byte(base), 0644))
+			copies, _ := filepath.Glob(filepath.Join(tmpDir, "plan.conflict-*.md"))
+			for _, c := range copies {
+				os.Remove(c)
+			}
+			t.Setenv("NED_TEST_EDIT", tt.edit)
+			t.Setenv("NED_TEST_THEIRS", tt.theirs)
+			p := setupTestPrompter(t, tt.answers...)
+			noInput = tt.noInput
+			defer func() { noInput = false }()
+
+			_, err := captureStdout(t, func() error { return editNote(notePath, 0) })
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(notePath)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(content))
+			assert.Len(t, p.questions, tt.wantQuestions)
+			assert.NoFileExists(t, lockPath(notePath))
+
+			copies, _ = filepath.Glob(filepath.Join(tmpDir, "plan.conflict-*.md"))
+			if !tt.wantCopy {
+				assert.Empty(t, copies)
+				return
+			}
+			require.Len(t, copies, 1)
+			saved, err := os.ReadFile(copies[0])
+			require.NoError(t, err)
+			assert.Equal(t, tt.edit, string(saved))
+		})
+	}
+}
@@ -0,0 +1,456 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"ned/cleanpage"
+	"ned/feed"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	feedFolder       string
+	feedMode         string
+	feedSkipExisting bool
+)
+
+// Feeds followed with "ned feed add" are kept in .feeds.json at the root
+// of the notes directory, next to the clip index
+const feedsName = ".feeds.json"
+
+// feedEntry is a feed that is followed and what has been seen of it
+type feedEntry struct {
+	URL    string `json:"url" yaml:"url"`
+	Title  string `json:"title" yaml:"title"`
+	Folder string `json:"folder" yaml:"folder"`
+	// Mode is the clip mode for the feed's items; empty means the mode
+	// clip uses by default
+	Mode    string     `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Added   time.Time  `json:"added" yaml:"added"`
+	Fetched *time.Time `json:"fetched,omitempty" yaml:"fetched,omitempty"`
+	// Seen holds the IDs of the items in the feed the last time it was
+	// fetched that are clipped or were skipped
+	Seen []string `json:"seen,omitempty" yaml:"seen,omitempty"`
+}
+
+// feedFetchResult is the structured output of feed fetch for a feed
+type feedFetchResult struct {
+	URL     string        `json:"url" yaml:"url"`
+	Title   string        `json:"title" yaml:"title"`
+	Folder  string        `json:"folder" yaml:"folder"`
+	Clipped []clipResult  `json:"clipped" yaml:"clipped"`
+	Known   []clipKnown   `json:"known" yaml:"known"`
+	Failed  []clipFailure `json:"failed" yaml:"failed"`
+	// Error is why the feed itself could not be fetched
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+var feedCmd = &cobra.Command{
+	Use:   "feed",
+	Short: "Follow RSS and Atom feeds and clip their new entries",
+	Long: `Follow RSS and Atom feeds and clip their new entries into notes.
+
+"ned feed add" subscribes to a feed, "ned feed fetch" clips every entry
+that is new since the last fetch into the feed's folder, a note per entry
+named after its title, and "ned feed list" shows the feeds followed.
+Entries are clipped like "ned clip --from" does, in full or summarized by
+--mode, and pages clipped before are skipped.`,
+}
+
+var feedAddCmd = &cobra.Command{
+	Use:   "add [url]",
+	Short: "Subscribe to a feed",
+	Long: `Subscribe to an RSS or Atom feed. Its entries are clipped into --folder,
+which defaults to a folder named after the feed.
+
+--mode sets how the feed's entries are clipped: full, summary or both. It
+defaults to the mode clip uses, summary with an ANTHROPIC_API_KEY and full
+without. --skip-existing marks the entries in the feed now as seen, so that
+only later ones are clipped.
+
+Examples:
+  ned feed add https://go.dev/blog/feed.atom --folder reading/golang
+  ned feed add https://example.com/rss.xml --mode full --skip-existing`,
+	Args: cobra.ExactArgs(1),
+	RunE: runFeedAdd,
+}
+
+var feedListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the feeds followed",
+	Args:  cobra.NoArgs,
+	RunE:  runFeedList,
+}
+
+var feedFetchCmd = &cobra.Command{
+	Use:   "fetch [url...]",
+	Short: "Clip the new entries of feeds",
+	Long: `Download the feeds followed, or the ones given, and clip their new entries
+into the feeds' folders. Entries that fail are tried again on the next
+fetch.
+
+Examples:
+  ned feed fetch
+  ned feed fetch https://go.dev/blog/feed.atom`,
+	RunE: runFeedFetch,
+}
+
+func init() {
+	feedAddCmd.Flags().StringVar(&feedFolder, "folder", "", "Folder to clip the feed's entries into (default a folder named after the feed)")
+	feedAddCmd.Flags().StringVarP(&feedMode, "mode", "m", "", "What to save of each entry: full, summary or both (default summary with an API key, full without)")
+	feedAddCmd.Flags().BoolVar(&feedSkipExisting, "skip-existing", false, "Only clip entries added to the feed from now on")
+	feedCmd.AddCommand(feedAddCmd)
+	feedCmd.AddCommand(feedListCmd)
+	feedCmd.AddCommand(feedFetchCmd)
+	rootCmd.AddCommand(feedCmd)
+}
+
+func feedsPath() string {
+	return filepath.Join(notesDir, feedsName)
+}
+
+// loadFeeds reads the feeds followed; there are none before the first add
+func loadFeeds() ([]feedEntry, error) {
+	var feeds []feedEntry
+	data, err := os.ReadFile(feedsPath())
+	if os.IsNotExist(err) {
+		return feeds, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read feeds: %w", err)
+	}
+	if err := json.Unmarshal(data, &feeds); err != nil {
+		return nil, fmt.Errorf("failed to read feeds %s: %w", feedsPath(), err)
+	}
+	return feeds, nil
+}
+
+// updateFeeds changes the feeds followed with update, under a lock
+func updateFeeds(update func([]feedEntry) ([]feedEntry, error)) error {
+	path := feedsPath()
+	lock, err := lockNote(path, "feed")
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	// Read again under the lock, another fetch may have changed them
+	feeds, err := loadFeeds()
+	if err != nil {
+		return err
+	}
+	if feeds, err = update(feeds); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(feeds, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to write feeds: %w", err)
+	}
+	if err := writeFileAtomic(path, append(data, '\n'), filePerm(path, 0644), true); err != nil {
+		return fmt.Errorf("failed to write feeds: %w", err)
+	}
+	return nil
+}
+
+// findFeed returns the index of the feed with url in feeds, or -1
+func findFeed(feeds []feedEntry, url string) int {
+	return slices.IndexFunc(feeds, func(f feedEntry) bool { return normalizeURL(f.URL) == normalizeURL(url) })
+}
+
+// downloadFeed downloads and parses a feed
+func downloadFeed(ctx context.Context, url string, opts cleanpage.Options) (*feed.Feed, error) {
+	data, finalURL, err := cleanpage.Download(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+	return feed.Parse(data, finalURL)
+}
+
+func runFeedAdd(cmd *cobra.Command, args []string) error {
+	url := args[0]
+	if !isWebAddress(url) {
+		return newCmdError(codeUsage, "not a web address: %s", url)
+	}
+	if feedMode != "" && !slices.Contains(clipModes, feedMode) {
+		return newCmdError(codeUsage, "invalid mode %q, use one of %s", feedMode, strings.Join(clipModes, ", "))
+	}
+	folder := filepath.Clean(feedFolder)
+	if filepath.IsAbs(folder) || folder == ".." || strings.HasPrefix(folder, ".."+string(filepath.Separator)) {
+		return newCmdError(codeInvalidPath, "path must be within notes directory")
+	}
+
+	feeds, err := loadFeeds()
+	if err != nil {
+		return err
+	}
+	if i := findFeed(feeds, url); i >= 0 {
+		return newCmdError(codeAlreadyExists, "already subscribed to %s, clipped into %s", url, feeds[i].Folder)
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return newCmdError(codeConfig, "failed to load config: %w", err)
+	}
+	opts, err := fetchOptions(config)
+	if err != nil {
+		return err
+	}
+	parsed, err := downloadFeed(context.Background(), url, opts)
+	if err != nil {
+		return newCmdError(codeFetchFailed, "failed to read feed: %w", err)
+	}
+
+	entry := feedEntry{URL: url, Title: parsed.Title, Folder: filepath.ToSlash(folder), Mode: feedMode, Added: time.Now()}
+	if entry.Title == "" {
+		entry.Title = url
+	}
+	if feedFolder == "" {
+		if entry.Folder = noteSlug(parsed.Title); entry.Folder == "" {
+			entry.Folder = noteSlug(url)
+		}
+	}
+	if feedSkipExisting {
+		for _, item := range parsed.Items {
+			entry.Seen = append(entry.Seen, item.ID)
+		}
+	}
+
+	err = updateFeeds(func(feeds []feedEntry) ([]feedEntry, error) {
+		if i := findFeed(feeds, url); i >= 0 {
+			return nil, newCmdError(codeAlreadyExists, "already subscribed to %s, clipped into %s", url, feeds[i].Folder)
+		}
+		return append(feeds, entry), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return printResult(entry)
+	}
+	fmt.Printf("Subscribed to %s, clipping into %s\n", entry.Title, entry.Folder)
+	if feedSkipExisting {
+		fmt.Printf("Skipping its %d current entries\n", len(entry.Seen))
+	} else {
+		fmt.Printf("Its %d current entries are clipped on the next \"ned feed fetch\"\n", len(parsed.Items))
+	}
+	return nil
+}
+
+func runFeedList(cmd *cobra.Command, args []string) error {
+	feeds, err := loadFeeds()
+	if err != nil {
+		return err
+	}
+	if structuredOutput() {
+		if feeds == nil {
+			feeds = []feedEntry{}
+		}
+		return printResult(feeds)
+	}
+	if len(feeds) == 0 {
+		fmt.Println("No feeds yet, add one with: ned feed add <url>")
+		return nil
+	}
+	for _, f := range feeds {
+		mode := f.Mode
+		if mode == "" {
+			mode = "default"
+		}
+		fetched := "never fetched"
+		if f.Fetched != nil {
+			fetched = "fetched " + f.Fetched.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%s\n  %s\n  into %s, %s mode, %s\n", f.Title, f.URL, f.Folder, mode, fetched)
+	}
+	return nil
+}
+
+func runFeedFetch(cmd *cobra.Command, args []string) error {
+	feeds, err := loadFeeds()
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		var picked []feedEntry
+		for _, url := range args {
+			i := findFeed(feeds, url)
+			if i < 0 {
+				return newCmdError(codeNotFound, "not subscribed to %s, add it with: ned feed add %s", url, url)
+			}
+			picked = append(picked, feeds[i])
+		}
+		feeds = picked
+	}
+	if len(feeds) == 0 {
+		return newCmdError(codeNotFound, "no feeds to fetch, add one with: ned feed add <url>")
+	}
+
+	absNotesDir, err := filepath.Abs(notesDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve notes directory path: %w", err)
+	}
+	settings, err := clipSettings()
+	if err != nil {
+		return err
+	}
+
+	limiter := newHostLimiter(clipRate)
+	settings.fetch.Wait = limiter.wait
+	results := []feedFetchResult{}
+	failed := 0
+	for _, f := range feeds {
+		result, err := fetchFeed(f, absNotesDir, settings, limiter)
+		if err != nil {
+			return err
+		}
+		if result.Error != "" || len(result.Failed) > 0 {
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	if structuredOutput() {
+		if err := printResult(results); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range results {
+			printFeedSummary(result)
+		}
+	}
+	if failed > 0 {
+		return newCmdError(codeFetchFailed, "%d of %d feeds could not be fetched in full", failed, len(feeds))
+	}
+	return nil
+}
+
+// fetchFeed clips the new items of a feed into its folder and remembers
+// them as seen. Items that fail are not, so they are tried again on the
+// next fetch. Errors with the feed itself are reported in the result.
+func fetchFeed(f feedEntry, absNotesDir string, settings *clipConfig, limiter *hostLimiter) (feedFetchResult, error) {
+	result := feedFetchResult{URL: f.URL, Title: f.Title, Folder: f.Folder, Clipped: []clipResult{}, Known: []clipKnown{}, Failed: []clipFailure{}}
+	mode := f.Mode
+	if mode == "" {
+		mode = settings.mode
+	}
+	if mode != clipModeFull && settings.apiKey == "" {
+		result.Error = fmt.Sprintf("--mode %s needs ANTHROPIC_API_KEY, set it with: ned config set ANTHROPIC_API_KEY <key>", mode)
+		return result, nil
+	}
+
+	limiter.wait(f.URL)
+	parsed, err := downloadFeed(context.Background(), f.URL, settings.fetch)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	if parsed.Title != "" {
+		result.Title = parsed.Title
+	}
+
+	index, err := loadClipIndex()
+	if err != nil {
+		return result, err
+	}
+	seen := map[string]bool{}
+	for _, id := range f.Seen {
+		seen[id] = true
+	}
+	// Only the items still in the feed are remembered; ones that come back
+	// are found in the clip index
+	var stillSeen []string
+	titles, ids := map[string]string{}, map[string]string{}
+	var urls []string
+	for _, item := range parsed.Items {
+		switch {
+		case seen[item.ID]:
+			stillSeen = append(stillSeen, item.ID)
+		case !isWebAddress(item.Link) || slices.Contains(urls, item.Link):
+			// Nothing to clip
+			stillSeen = append(stillSeen, item.ID)
+		default:
+			if entry, ok := index.lookup(item.Link); ok {
+				result.Known = append(result.Known, clipKnown{URL: item.Link, Note: entry.Note})
+				stillSeen = append(stillSeen, item.ID)
+				continue
+			}
+			urls = append(urls, item.Link)
+			titles[item.Link], ids[item.Link] = item.Title, item.ID
+		}
+	}
+
+	pages := fetchPages(urls, clipJobs, limiter, settings.fetch, func(page *batchPage) {
+		// Documents without a title of their own go by the feed's
+		if page.article.Title == "" {
+			page.article.Title = titles[page.url]
+		}
+		page.body, page.err = clipBody(page.article, mode, settings.apiKey, page.url, 1)
+	})
+	used := map[string]bool{}
+	for _, page := range pages {
+		if page.err != nil {
+			result.Failed = append(result.Failed, clipFailure{URL: page.url, Error: page.err.Error(), Retryable: retryable(page.err)})
+			continue
+		}
+		if entry, ok := index.lookup(page.article.URL, page.article.Canonical); ok {
+			result.Known = append(result.Known, clipKnown{URL: page.url, Note: entry.Note})
+			stillSeen = append(stillSeen, ids[page.url])
+			continue
+		}
+		clipped, err := saveBatchNote(absNotesDir, f.Folder, page, used, settings.fetch)
+		if err != nil {
+			result.Failed = append(result.Failed, clipFailure{URL: page.url, Error: err.Error()})
+			continue
+		}
+		result.Clipped = append(result.Clipped, clipped)
+		stillSeen = append(stillSeen, ids[page.url])
+	}
+
+	err = updateFeeds(func(feeds []feedEntry) ([]feedEntry, error) {
+		// The feed may have been removed by hand in the meantime
+		if i := findFeed(feeds, f.URL); i >= 0 {
+			now := time.Now()
+			feeds[i].Title = result.Title
+			feeds[i].Fetched = &now
+			feeds[i].Seen = stillSeen
+		}
+		return feeds, nil
+	})
+	return result, err
+}
+
+// printFeedSummary reports what fetching a feed clipped and what failed
+func printFeedSummary(result feedFetchResult) {
+	if result.Error != "" {
+		fmt.Printf("%s: failed: %s\n", result.Title, result.Error)
+		return
+	}
+	switch len(result.Clipped) {
+	case 0:
+		fmt.Printf("%s: no new entries\n", result.Title)
+	case 1:
+		fmt.Printf("%s: clipped 1 new entry into %s\n", result.Title, result.Folder)
+	default:
+		fmt.Printf("%s: clipped %d new entries into %s\n", result.Title, len(result.Clipped), result.Folder)
+	}
+	for _, clipped := range result.Clipped {
+		fmt.Printf("  %s\n", clipped.Note+".md")
+	}
+	for _, known := range result.Known {
+		fmt.Printf("  clipped before: %s: %s\n", known.URL, known.Note+".md")
+	}
+	if len(result.Failed) > 0 {
+		fmt.Printf("  Failed, tried again on the next fetch:\n")
+		for _, failure := range result.Failed {
+			fmt.Printf("    %s: %s\n", failure.URL, failure.Error)
+		}
+	}
+}
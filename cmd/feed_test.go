@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// feedServer serves an RSS feed of items, which tests may change between
+// fetches, an Atom feed and a page that is not a feed
+func feedServer(t *testing.T, items *[]string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel><title>Test Blog</title><link>https://blog.example.com/</link>`)
+		for _, link := range *items {
+			fmt.Fprintf(w, "<item><title>Item %s</title><link>%s</link><guid>id:%s</guid></item>\n", link, link, link)
+		}
+		fmt.Fprint(w, "</channel></rss>")
+	})
+	mux.HandleFunc("/feed.atom", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+<title>Example Engineering</title>
+<entry><title>One</title><link href="/one"/><id>urn:one</id></entry>
+<entry><title>Two</title><link href="/two"/><id>urn:two</id></entry>
+</feed>`)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(articleHTML))
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// setupFeedFlags resets the feed add flags after a test
+func setupFeedFlags(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() { feedFolder, feedMode, feedSkipExisting = "", "", false })
+}
+
+func readFeeds(t *testing.T) []feedEntry {
+	t.Helper()
+	feeds, err := loadFeeds()
+	require.NoError(t, err)
+	return feeds
+}
+
+func TestFeedAdd(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, nil)
+	setupFeedFlags(t)
+	items := []string{"https://blog.example.com/a", "https://blog.example.com/b"}
+	ts := feedServer(t, &items)
+
+	feedFolder, feedMode = "reading/golang", clipModeFull
+	out, err := captureStdout(t, func() error { return runFeedAdd(feedAddCmd, []string{ts.URL + "/feed.xml"}) })
+	require.NoError(t, err)
+	assert.Contains(t, out, "Subscribed to Test Blog, clipping into reading/golang")
+
+	feeds := readFeeds(t)
+	require.Len(t, feeds, 1)
+	assert.Equal(t, ts.URL+"/feed.xml", feeds[0].URL)
+	assert.Equal(t, "Test Blog", feeds[0].Title)
+	assert.Equal(t, "reading/golang", feeds[0].Folder)
+	assert.Equal(t, clipModeFull, feeds[0].Mode)
+	assert.Empty(t, feeds[0].Seen, "current entries are clipped on the first fetch")
+
+	t.Run("already subscribed", func(t *testing.T) {
+		err := runFeedAdd(feedAddCmd, []string{ts.URL + "/feed.xml#latest"})
+		assert.Equal(t, codeAlreadyExists, errorCode(err))
+	})
+
+	t.Run("skip existing with the default folder", func(t *testing.T) {
+		feedFolder, feedMode, feedSkipExisting = "", "", true
+		_, err := captureStdout(t, func() error { return runFeedAdd(feedAddCmd, []string{ts.URL + "/feed.atom"}) })
+		require.NoError(t, err)
+		feeds := readFeeds(t)
+		require.Len(t, feeds, 2)
+		assert.Equal(t, "example-engineering", feeds[1].Folder)
+		assert.Empty(t, feeds[1].Mode)
+		assert.Equal(t, []string{"urn:one", "urn:two"}, feeds[1].Seen)
+	})
+
+	t.Run("not a feed", func(t *testing.T) {
+		feedFolder, feedMode, feedSkipExisting = "", "", false
+		err := runFeedAdd(feedAddCmd, []string{ts.URL + "/page"})
+		assert.Equal(t, codeFetchFailed, errorCode(err))
+		assert.Contains(t, err.Error(), "not an RSS or Atom feed")
+	})
+
+	t.Run("bad arguments", func(t *testing.T) {
+		feedMode = "everything"
+		assert.Equal(t, codeUsage, errorCode(runFeedAdd(feedAddCmd, []string{ts.URL + "/other.xml"})))
+		feedMode, feedFolder = "", "../outside"
+		assert.Equal(t, codeInvalidPath, errorCode(runFeedAdd(feedAddCmd, []string{ts.URL + "/other.xml"})))
+		feedFolder = ""
+		assert.Equal(t, codeUsage, errorCode(runFeedAdd(feedAddCmd, []string{"feed.xml"})))
+		assert.Len(t, readFeeds(t), 2)
+	})
+}
+
+func TestFeedFetch(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, nil)
+	setupFeedFlags(t)
+	setupClipBatch(t, "", false)
+	articles := articleServer(t)
+	items := []string{articles.URL + "/one", articles.URL + "/two", articles.URL + "/busy"}
+	ts := feedServer(t, &items)
+
+	feedFolder = "reading/blog"
+	_, err := captureStdout(t, func() error { return runFeedAdd(feedAddCmd, []string{ts.URL + "/feed.xml"}) })
+	require.NoError(t, err)
+
+	out, err := captureStdout(t, func() error { return runFeedFetch(feedFetchCmd, nil) })
+	assert.Equal(t, codeFetchFailed, errorCode(err), "the busy page fails")
+	assert.Contains(t, out, "Test Blog: clipped 2 new entries into reading/blog")
+	assert.Contains(t, out, articles.URL+"/busy: HTTP status 503")
+	for name, title := range map[string]string{"first-post.md": "First Post", "second-the-post.md": "Second: the Post!"} {
+		content, err := os.ReadFile(filepath.Join(tmpDir, "reading", "blog", name))
+		require.NoError(t, err, name)
+		meta, body := parseFrontMatter(content)
+		require.NotNil(t, meta, name)
+		assert.Equal(t, title, meta["title"], name)
+		assert.Contains(t, string(body), "Install the tool", "feeds default to the full text without an API key")
+	}
+
+	feeds := readFeeds(t)
+	require.Len(t, feeds, 1)
+	assert.NotNil(t, feeds[0].Fetched)
+	assert.Equal(t, []string{"id:" + articles.URL + "/one", "id:" + articles.URL + "/two"}, feeds[0].Seen, "failed entries are not seen")
+
+	// Seen entries are not clipped again, new ones and failed ones are
+	// tried; entries that left the feed are forgotten
+	items = []string{articles.URL + "/again", articles.URL + "/two", articles.URL + "/busy"}
+	out, err = captureStdout(t, func() error { return runFeedFetch(feedFetchCmd, []string{ts.URL + "/feed.xml"}) })
+	assert.Equal(t, codeFetchFailed, errorCode(err))
+	assert.Contains(t, out, "Test Blog: clipped 1 new entry into reading/blog")
+	assert.FileExists(t, filepath.Join(tmpDir, "reading", "blog", "first-post-2.md"))
+	assert.NoFileExists(t, filepath.Join(tmpDir, "reading", "blog", "second-the-post-2.md"))
+	assert.ElementsMatch(t, []string{"id:" + articles.URL + "/again", "id:" + articles.URL + "/two"}, readFeeds(t)[0].Seen)
+
+	// An entry that comes back is found among the clipped pages
+	items = []string{articles.URL + "/one"}
+	out, err = captureStdout(t, func() error { return runFeedFetch(feedFetchCmd, nil) })
+	require.NoError(t, err)
+	assert.Contains(t, out, "Test Blog: no new entries")
+	assert.Contains(t, out, "clipped before: "+articles.URL+"/one: reading/blog/first-post.md")
+
+	t.Run("list", func(t *testing.T) {
+		out, err := captureStdout(t, func() error { return runFeedList(feedListCmd, nil) })
+		require.NoError(t, err)
+		assert.Contains(t, out, "Test Blog\n  "+ts.URL+"/feed.xml\n  into reading/blog, default mode, fetched ")
+
+		setOutputFormat(t, "json")
+		out, err = captureStdout(t, func() error { return runFeedList(feedListCmd, nil) })
+		require.NoError(t, err)
+		var listed []feedEntry
+		require.NoError(t, json.Unmarshal([]byte(out), &listed))
+		require.Len(t, listed, 1)
+		assert.Equal(t, "reading/blog", listed[0].Folder)
+	})
+
+	t.Run("summary needs an API key", func(t *testing.T) {
+		feedFolder, feedMode = "summaries", clipModeSummary
+		_, err := captureStdout(t, func() error { return runFeedAdd(feedAddCmd, []string{ts.URL + "/feed.atom"}) })
+		require.NoError(t, err)
+		items = nil
+		out, err := captureStdout(t, func() error { return runFeedFetch(feedFetchCmd, nil) })
+		assert.Equal(t, codeFetchFailed, errorCode(err))
+		assert.Contains(t, err.Error(), "1 of 2 feeds")
+		assert.Contains(t, out, "Test Blog: no new entries")
+		assert.Contains(t, out, "Example Engineering: failed: --mode summary needs ANTHROPIC_API_KEY")
+		assert.NoDirExists(t, filepath.Join(tmpDir, "summaries"))
+	})
+
+	t.Run("unknown feed", func(t *testing.T) {
+		err := runFeedFetch(feedFetchCmd, []string{ts.URL + "/other.xml"})
+		assert.Equal(t, codeNotFound, errorCode(err))
+	})
+}
+
+func TestFeedFetchNothingFollowed(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setupTestConfig(t, nil)
+
+	err := runFeedFetch(feedFetchCmd, nil)
+	assert.Equal(t, codeNotFound, errorCode(err))
+	out, err := captureStdout(t, func() error { return runFeedList(feedListCmd, nil) })
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out, "No feeds yet"))
+}
@@ -1,7 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -46,6 +52,12 @@ func ConvertImagePath(path string) string {
 	return newPath
 }
 
+// imageListResult is the structured output of the image list command
+type imageListResult struct {
+	Folder string   `json:"folder" yaml:"folder"`
+	Images []string `json:"images" yaml:"images"`
+}
+
 type EmptyError struct {
 	Message string
 }
@@ -75,10 +87,11 @@ var imageShowCmd = &cobra.Command{
 	Short: "Show an image",
 	Long: `Show an image using the system's default image viewer.
 The image path can be just the filename for root images, or folder/filename for images in subfolders.
+Without an image, a fuzzy finder lists the images of all folders to pick from.
 Examples:
   ned image show image1.jpg           # Shows ._images_/image1.jpg
   ned image show folder1/image2.png   # Shows folder1/._images_/image2.png`,
-	Args: cobra.ExactArgs(1),
+	Args: cobra.MaximumNArgs(1),
 	RunE: runImageShow,
 }
 
@@ -106,7 +119,7 @@ func runImageList(cmd *cobra.Command, args []string) error {
 		// Clean and validate the folder path
 		cleanPath = filepath.Clean(folder)
 		if filepath.IsAbs(cleanPath) {
-			return fmt.Errorf("absolute paths are not allowed")
+			return newCmdError(codeInvalidPath, "absolute paths are not allowed")
 		}
 
 		// Verify the folder path is within notes directory
@@ -116,33 +129,21 @@ func runImageList(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("invalid path: %w", err)
 		}
 		if !strings.HasPrefix(absPath, absNotesDir) {
-			return fmt.Errorf("path must be within notes directory")
+			return newCmdError(codeInvalidPath, "path must be within notes directory")
 		}
 	}
 	// Check for invalid folder names
 	if folder == "." || folder == ".." {
-		return fmt.Errorf("invalid folder name: %s", folder)
+		return newCmdError(codeInvalidPath, "invalid folder name: %s", folder)
 	}
-	// Create full path for the images directory
-	imagesDir := filepath.Join(absNotesDir, cleanPath, "._images_")
-	// Check if images directory exists
-	if _, err := os.Stat(imagesDir); os.IsNotExist(err) {
-		return EmptyError{fmt.Sprintf("No images found in %s", filepath.Join(cleanPath, "._images_"))}
+	// A folder without images is listed as empty, like list does; a
+	// folder that does not exist is not found
+	if info, err := os.Stat(filepath.Join(absNotesDir, cleanPath)); os.IsNotExist(err) || (err == nil && !info.IsDir()) {
+		return newCmdError(codeNotFound, "folder not found: %s", folder)
 	}
-
-	// Check if images directory exists
-	if _, err := os.Stat(imagesDir); os.IsNotExist(err) {
-		if folder == "" {
-			fmt.Println("No images found in root ._images_ directory")
-		} else {
-			fmt.Printf("No images found in %s\n", filepath.Join(cleanPath, "._images_"))
-		}
-		return nil
-	}
-
-	// Read directory contents
+	imagesDir := filepath.Join(absNotesDir, cleanPath, "._images_")
 	entries, err := os.ReadDir(imagesDir)
-	if err != nil {
+	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to read images directory: %w", err)
 	}
 
@@ -150,15 +151,19 @@ func runImageList(cmd *cobra.Command, args []string) error {
 	var images []string
 	for _, entry := range entries {
 		if !entry.IsDir() {
-			name := entry.Name()
-			// Basic image file extension check
-			ext := strings.ToLower(filepath.Ext(name))
-			if ext == ".jpg" || ext == ".jpeg" || ext == ".png" || ext == ".gif" || ext == ".bmp" || ext == ".webp" {
+			if name := entry.Name(); isImageFile(name) {
 				images = append(images, name)
 			}
 		}
 	}
 
+	if structuredOutput() {
+		if images == nil {
+			images = []string{}
+		}
+		return printResult(imageListResult{Folder: filepath.ToSlash(cleanPath), Images: images})
+	}
+
 	if len(images) == 0 {
 		if folder == "" {
 			fmt.Println("No images found in root ._images_ directory")
@@ -182,12 +187,19 @@ func runImageList(cmd *cobra.Command, args []string) error {
 }
 
 func runImageShow(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		images, err := pickImage()
+		if err != nil || images == nil {
+			return err
+		}
+		args = images
+	}
 	imagePath := args[0]
 
 	// Clean and validate the path
 	cleanPath := filepath.Clean(imagePath)
 	if filepath.IsAbs(cleanPath) {
-		return fmt.Errorf("absolute paths are not allowed")
+		return newCmdError(codeInvalidPath, "absolute paths are not allowed")
 	}
 
 	// Get absolute path of notes directory
@@ -216,15 +228,20 @@ func runImageShow(cmd *cobra.Command, args []string) error {
 
 	// Ensure the path is within the notes directory
 	if !strings.HasPrefix(absPath, absNotesDir) {
-		return fmt.Errorf("path must be within notes directory")
+		return newCmdError(codeInvalidPath, "path must be within notes directory")
 	}
 
 	// Check if file exists
 	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-		return fmt.Errorf("image not found: %s", imagePath)
+		return newCmdError(codeNotFound, "image not found: %s", imagePath)
 	}
 
-	// Open the image with the system's default viewer
+	return openImage(fullPath)
+}
+
+// openImage opens an image with the system's default viewer. Tests replace
+// it to keep the viewer closed.
+var openImage = func(fullPath string) error {
 	var cmd2 *exec.Cmd
 	switch runtime.GOOS {
 	case "windows":
@@ -241,3 +258,103 @@ func runImageShow(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// isImageFile does a basic image file extension check
+func isImageFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".bmp", ".webp", ".svg", ".avif":
+		return true
+	}
+	return false
+}
+
+// scanImages finds the images in the ._images_ folders of all note folders.
+// Their names are folder/filename, or just the filename for root images.
+func scanImages() ([]pickerItem, error) {
+	var items []pickerItem
+	err := filepath.WalkDir(notesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != notesDir && isHiddenDir(d.Name()) && d.Name() != "._images_" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Base(filepath.Dir(path)) != "._images_" || !isImageFile(d.Name()) {
+			return nil
+		}
+
+		folder, err := filepath.Rel(notesDir, filepath.Dir(filepath.Dir(path)))
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		items = append(items, pickerItem{
+			Name:    filepath.ToSlash(filepath.Join(folder, d.Name())),
+			ModTime: info.ModTime(),
+			Path:    path,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan images: %w", err)
+	}
+	return items, nil
+}
+
+// pickImage offers the images of all folders in the picker and returns the
+// chosen one as folder/filename. A cancelled picker returns nothing.
+func pickImage() ([]string, error) {
+	if !interactive() {
+		return nil, newCmdError(codeUsage, "an image is required when not running in a terminal")
+	}
+
+	items, err := scanImages()
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, EmptyError{"No images found"}
+	}
+
+	picked, err := pickItems(items, pickerOptions{Prompt: "image", Preview: previewImage})
+	if errors.Is(err, errPickerCancelled) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return []string{picked[0].Name}, nil
+}
+
+// previewImage describes an image file: its size, modification time and,
+// for formats the standard library decodes, its dimensions
+func previewImage(item pickerItem) []string {
+	f, err := os.Open(item.Path)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return []string{err.Error()}
+	}
+	lines := []string{
+		item.Name,
+		"",
+		fmt.Sprintf("Size:       %d bytes", info.Size()),
+		fmt.Sprintf("Modified:   %s", info.ModTime().Format("2006-01-02 15:04")),
+	}
+	if config, format, err := image.DecodeConfig(f); err == nil {
+		lines = append(lines,
+			fmt.Sprintf("Format:     %s", format),
+			fmt.Sprintf("Dimensions: %d×%d", config.Width, config.Height),
+		)
+	}
+	return lines
+}
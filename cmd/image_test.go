@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -53,7 +54,7 @@ func TestConvertImagePath(t *testing.T) {
 }
 
 func TestImageCommands(t *testing.T) {
-	testMode = true
+	stubOpeners(t)
 	tmpDir, cleanup := setupTestEnv(t)
 	defer cleanup()
 
@@ -248,9 +249,6 @@ func TestImageCommands(t *testing.T) {
 
 	for _, tt := range showTests {
 		t.Run(tt.name, func(t *testing.T) {
-			if !testMode {
-				t.Skip("Skipping image show test in non-test mode")
-			}
 			err := runImageShow(imageShowCmd, tt.args)
 			if tt.wantErr {
 				if err == nil {
@@ -264,3 +262,49 @@ func TestImageCommands(t *testing.T) {
 		})
 	}
 }
+
+func TestImageListEmpty(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	for _, dir := range []string{"notes-only", filepath.Join("cleared", "._images_")} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, dir), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+	}
+
+	// A folder without an images directory is as empty as one with an
+	// empty images directory
+	for _, args := range [][]string{nil, {"notes-only"}, {"cleared"}} {
+		out, err := captureStdout(t, func() error { return runImageList(imageListCmd, args) })
+		if err != nil {
+			t.Errorf("image list %v: %v", args, err)
+		}
+		if !strings.HasPrefix(out, "No images found in ") {
+			t.Errorf("image list %v = %q", args, out)
+		}
+	}
+
+	setOutputFormat(t, "json")
+	for _, args := range [][]string{nil, {"notes-only"}, {"cleared"}} {
+		out, err := captureStdout(t, func() error { return runImageList(imageListCmd, args) })
+		if err != nil {
+			t.Errorf("image list %v: %v", args, err)
+		}
+		var got map[string]any
+		if err := json.Unmarshal([]byte(out), &got); err != nil {
+			t.Fatalf("image list %v: invalid JSON %q: %v", args, out, err)
+		}
+		folder := ""
+		if len(args) > 0 {
+			folder = args[0]
+		}
+		if images, ok := got["images"].([]any); got["folder"] != folder || !ok || len(images) != 0 {
+			t.Errorf("image list %v = %s, want folder %q and no images", args, out, folder)
+		}
+	}
+
+	err := runImageList(imageListCmd, []string{"missing"})
+	if code := errorCode(err); code != codeNotFound {
+		t.Errorf("image list missing: code = %s, want %s", code, codeNotFound)
+	}
+}
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -11,12 +13,21 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var importForce bool
+
+// importResult is the structured output of the import command
+type importResult struct {
+	Image string `json:"image" yaml:"image"`
+	Path  string `json:"path" yaml:"path"`
+}
+
 var importCmd = &cobra.Command{
 	Use:   "import [image_source] [folder]",
 	Short: "Import an image from file or URL",
 	Long: `Import an image from a local file or URL into the notes system.
 The image will be stored in the ._images_ subdirectory under the specified folder
-or root folder if no folder is specified.
+or root folder if no folder is specified. An image with the same name is only
+replaced with --force.
 
 Examples:
   ned import image.jpg            # Import local image to root ._images_ folder
@@ -27,6 +38,7 @@ Examples:
 }
 
 func init() {
+	importCmd.Flags().BoolVarP(&importForce, "force", "f", false, "Overwrite the image if it already exists")
 	rootCmd.AddCommand(importCmd)
 }
 
@@ -42,7 +54,7 @@ func runImport(cmd *cobra.Command, args []string) error {
 	// Clean and validate the folder path
 	cleanPath := filepath.Clean(targetFolder)
 	if filepath.IsAbs(cleanPath) {
-		return fmt.Errorf("absolute paths are not allowed")
+		return newCmdError(codeInvalidPath, "absolute paths are not allowed")
 	}
 
 	// Convert to absolute paths for consistent handling
@@ -68,46 +80,41 @@ func runImport(cmd *cobra.Command, args []string) error {
 	// Full path for the target image
 	targetPath := filepath.Join(imagesDir, filename)
 
+	if _, err := os.Stat(targetPath); err == nil && !importForce {
+		return newCmdError(codeAlreadyExists, "image already exists: %s (use --force to overwrite it)", filepath.Join(targetFolder, "._images_", filename))
+	}
+
 	// Check if it's a URL or local file
+	var src io.Reader
 	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
 		// Download from URL
 		resp, err := http.Get(source)
 		if err != nil {
-			return fmt.Errorf("failed to download image: %w", err)
+			return newCmdError(codeFetchFailed, "failed to download image: %w", err)
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("failed to download image: HTTP status %d", resp.StatusCode)
-		}
-
-		// Create the target file
-		out, err := os.Create(targetPath)
-		if err != nil {
-			return fmt.Errorf("failed to create image file: %w", err)
+			return newCmdError(codeFetchFailed, "failed to download image: HTTP status %d", resp.StatusCode)
 		}
-		defer out.Close()
 
-		// Copy the content
-		if _, err := io.Copy(out, resp.Body); err != nil {
-			return fmt.Errorf("failed to save image: %w", err)
-		}
+		src = resp.Body
 	} else {
 		// Handle local file
 		// Clean and validate the source path
 		cleanSource := filepath.Clean(source)
 		if filepath.IsAbs(cleanSource) {
-			return fmt.Errorf("absolute paths are not allowed for source file")
+			return newCmdError(codeInvalidPath, "absolute paths are not allowed for source file")
 		}
 
 		// Check for . and .. in source path components
 		sourceParts := strings.Split(filepath.Dir(cleanSource), string(filepath.Separator))
 		for _, part := range sourceParts {
 			if part == "." || part == ".." {
-				return fmt.Errorf("source path cannot contain '.' or '..'")
+				return newCmdError(codeInvalidPath, "source path cannot contain '.' or '..'")
 			}
 			if part == "._images_" {
-				return fmt.Errorf("cannot import from ._images_ directory")
+				return newCmdError(codeInvalidPath, "cannot import from ._images_ directory")
 			}
 		}
 
@@ -120,34 +127,39 @@ func runImport(cmd *cobra.Command, args []string) error {
 
 		// Ensure the source path is within the notes directory and not in any ._images_ directory
 		if !strings.HasPrefix(absSourcePath, absNotesDir) {
-			return fmt.Errorf("source path must be within notes directory")
+			return newCmdError(codeInvalidPath, "source path must be within notes directory")
 		}
 		if strings.Contains(absSourcePath, string(filepath.Separator)+"._images_"+string(filepath.Separator)) {
-			return fmt.Errorf("cannot import from ._images_ directory")
+			return newCmdError(codeInvalidPath, "cannot import from ._images_ directory")
 		}
 
 		// Read the source file
 		in, err := os.Open(fullSourcePath)
-		if err != nil {
+		if os.IsNotExist(err) {
+			return newCmdError(codeNotFound, "source image not found: %s", source)
+		} else if err != nil {
 			return fmt.Errorf("failed to open source image: %w", err)
 		}
 		defer in.Close()
+		src = in
+	}
 
-		// Create the target file
-		out, err := os.Create(targetPath)
-		if err != nil {
-			return fmt.Errorf("failed to create image file: %w", err)
-		}
-		defer out.Close()
-
-		// Copy the content
-		if _, err := io.Copy(out, in); err != nil {
-			return fmt.Errorf("failed to copy image: %w", err)
-		}
+	// Copy the content into place
+	err = writeAtomic(targetPath, filePerm(targetPath, 0644), importForce, func(w io.Writer) error {
+		_, err := io.Copy(w, src)
+		return err
+	})
+	if errors.Is(err, fs.ErrExist) {
+		return newCmdError(codeAlreadyExists, "image already exists: %s (use --force to overwrite it)", filepath.Join(targetFolder, "._images_", filename))
+	} else if err != nil {
+		return fmt.Errorf("failed to save image: %w", err)
 	}
 
 	// Generate relative path for output
 	relPath := filepath.Join(targetFolder, "._images_", filename)
+	if structuredOutput() {
+		return printResult(importResult{Image: filepath.ToSlash(relPath), Path: targetPath})
+	}
 	fmt.Printf("Imported image to: %s\n", relPath)
 	return nil
 }
@@ -11,7 +11,6 @@ import (
 
 func TestImportCmd(t *testing.T) {
 	// Initialize test environment
-	testMode = true
 	tmpDir, cleanup := setupTestEnv(t)
 	defer cleanup()
 
@@ -149,3 +148,39 @@ func TestImportCmd(t *testing.T) {
 		})
 	}
 }
+
+func TestImportExistingImage(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	source := filepath.Join(tmpDir, "source", "new.png")
+	target := filepath.Join(tmpDir, "._images_", "new.png")
+	for _, dir := range []string{filepath.Dir(source), filepath.Dir(target)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(source, []byte("new"), 0644); err != nil {
+		t.Fatalf("failed to write source: %v", err)
+	}
+	if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+		t.Fatalf("failed to write target: %v", err)
+	}
+
+	err := runImport(importCmd, []string{"source/new.png"})
+	if got := errorCode(err); got != codeAlreadyExists {
+		t.Errorf("error code = %q, want %q (%v)", got, codeAlreadyExists, err)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "old" {
+		t.Errorf("image was replaced without --force: %q", content)
+	}
+
+	importForce = true
+	defer func() { importForce = false }()
+	if err := runImport(importCmd, []string{"source/new.png"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "new" {
+		t.Errorf("image was not replaced with --force: %q", content)
+	}
+}
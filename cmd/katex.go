@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// katexVersion is the KaTeX release the viewer uses. Keep it in step with
+// the go:generate line below.
+const katexVersion = "0.16.11"
+
+//go:generate sh -c "curl -sSfL https://registry.npmjs.org/katex/-/katex-0.16.11.tgz | tar -xzf - -C assets/katex --strip-components=2 package/dist/katex.min.css package/dist/katex.min.js package/dist/fonts"
+
+// katexFS holds KaTeX's stylesheet, script and fonts once they are
+// vendored into assets/katex, so that math renders without a network
+// connection
+//
+//go:embed all:assets/katex
+var katexFS embed.FS
+
+// katexAssets is where the viewer finds KaTeX's files. Tests replace it.
+var katexAssets fs.FS = func() fs.FS {
+	files, _ := fs.Sub(katexFS, "assets/katex")
+	return files
+}()
+
+// katexFiles returns the vendored KaTeX files, or nil when they are missing
+func katexFiles() fs.FS {
+	if _, err := fs.Stat(katexAssets, "katex.min.js"); err != nil {
+		return nil
+	}
+	return katexAssets
+}
+
+// katexBase returns the URL pages load KaTeX from: the viewer's /katex
+// route when the files are vendored, the CDN otherwise
+func katexBase() string {
+	if katexFiles() != nil {
+		return "/katex"
+	}
+	return "https://cdn.jsdelivr.net/npm/katex@" + katexVersion + "/dist"
+}
@@ -4,100 +4,383 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	listSort  string
+	listLong  bool
+	listFlat  bool
+	listDepth int
+	listGlobs []string
+)
+
 var listCmd = &cobra.Command{
-	Use:     "list",
-	Short:   "List all notes in tree structure",
-	Long:    `List all notes in a tree structure showing directories and files`,
+	Use:   "list [folder]",
+	Short: "List all notes in tree structure",
+	Long: `List notes in a tree structure showing directories and files.
+If a folder is given, only notes under that folder are listed.
+
+Sort orders:
+  name   alphabetical (default)
+  mtime  most recently modified first
+  ctime  most recently changed (created on Windows) first
+  size   largest first
+
+Examples:
+  ned list --long
+  ned list projects --flat --sort mtime
+  ned list --glob 'meeting-*' --glob '*/2026-*'`,
 	Aliases: []string{"l"},
+	Args:    cobra.MaximumNArgs(1),
 	RunE:    runList,
 }
 
 func init() {
+	listCmd.Flags().StringVarP(&listSort, "sort", "s", "name", "Sort by name, mtime, ctime or size")
+	listCmd.Flags().BoolVarP(&listLong, "long", "L", false, "Show title, modification time, word count and tags")
+	listCmd.Flags().BoolVarP(&listFlat, "flat", "f", false, "List note paths one per line instead of a tree")
+	listCmd.Flags().IntVarP(&listDepth, "depth", "d", 0, "Maximum folder depth to descend into (0 for no limit)")
+	listCmd.Flags().StringArrayVarP(&listGlobs, "glob", "g", nil, "Only list notes whose path or name matches the pattern (repeatable)")
 	rootCmd.AddCommand(listCmd)
 }
 
+// listResult is the structured output for a note in the listing. Title,
+// words and tags are only filled with --long.
+type listResult struct {
+	Note     string    `json:"note" yaml:"note"`
+	Modified time.Time `json:"modified" yaml:"modified"`
+	Size     int64     `json:"size" yaml:"size"`
+	Title    string    `json:"title,omitempty" yaml:"title,omitempty"`
+	Words    int       `json:"words,omitempty" yaml:"words,omitempty"`
+	Tags     []string  `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// listEntry is a note or folder in the listing
+type listEntry struct {
+	name     string
+	path     string
+	isDir    bool
+	info     fs.FileInfo
+	children []*listEntry
+	// collapsed is set on folders at the depth limit with notes below it
+	collapsed bool
+
+	// Details read for --long
+	title string
+	words int
+	tags  []string
+}
+
 func runList(cmd *cobra.Command, args []string) error {
-	// Check if directory has any .md files
-	entries, err := os.ReadDir(notesDir)
+	switch listSort {
+	case "name", "mtime", "ctime", "size":
+	default:
+		return newCmdError(codeUsage, "invalid sort order %q: must be name, mtime, ctime or size", listSort)
+	}
+	if listDepth < 0 {
+		return newCmdError(codeUsage, "depth must not be negative")
+	}
+	for _, pattern := range listGlobs {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return newCmdError(codeUsage, "invalid glob pattern %q: %w", pattern, err)
+		}
+	}
+
+	folder := ""
+	if len(args) > 0 {
+		folder = args[0]
+	}
+	root, err := listRoot(folder)
+	if err != nil {
+		return err
+	}
+
+	tree, notes, err := walkNotes(root)
 	if err != nil {
 		return err
 	}
 
-	hasMdFiles := false
-	for _, entry := range entries {
-		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".md" {
-			hasMdFiles = true
-			break
+	if structuredOutput() {
+		sortEntries(notes, true)
+		results := make([]listResult, 0, len(notes))
+		for _, note := range notes {
+			results = append(results, listResult{
+				Note:     note.path,
+				Modified: note.info.ModTime(),
+				Size:     note.info.Size(),
+				Title:    note.title,
+				Words:    note.words,
+				Tags:     note.tags,
+			})
 		}
+		return printResult(results)
 	}
 
-	if !hasMdFiles {
+	// Notes below --depth only show up as their folders
+	if len(tree.children) == 0 {
 		fmt.Println("Empty")
 		return nil
 	}
 
-	fmt.Println("Notes structure:")
-	return filepath.Walk(notesDir, func(path string, info fs.FileInfo, err error) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if listFlat {
+		entries := append(collapsedFolders(tree), notes...)
+		sortEntries(entries, true)
+		for _, entry := range entries {
+			label := entry.path
+			if entry.isDir {
+				label += "/"
+			}
+			printListEntry(w, label, entry)
+		}
+		return w.Flush()
+	}
+
+	if folder == "" {
+		fmt.Println("Notes structure:")
+	} else {
+		fmt.Printf("Notes structure of %s:\n", filepath.ToSlash(filepath.Clean(folder)))
+	}
+	printTree(w, tree, 0)
+	return w.Flush()
+}
+
+// listRoot validates the folder argument and returns its directory
+func listRoot(folder string) (string, error) {
+	if folder == "" {
+		return notesDir, nil
+	}
+
+	cleanPath := filepath.Clean(folder)
+	if filepath.IsAbs(cleanPath) {
+		return "", newCmdError(codeInvalidPath, "absolute paths are not allowed")
+	}
+
+	absNotesDir, err := filepath.Abs(notesDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve notes directory path: %w", err)
+	}
+	absPath, err := filepath.Abs(filepath.Join(notesDir, cleanPath))
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %w", err)
+	}
+	if absPath != absNotesDir && !strings.HasPrefix(absPath, absNotesDir+string(filepath.Separator)) {
+		return "", newCmdError(codeInvalidPath, "path must be within notes directory")
+	}
+
+	info, err := os.Stat(absPath)
+	if os.IsNotExist(err) {
+		return "", newCmdError(codeNotFound, "folder not found: %s", folder)
+	} else if err != nil {
+		return "", fmt.Errorf("error accessing folder: %w", err)
+	}
+	if !info.IsDir() {
+		return "", newCmdError(codeNotFound, "not a folder: %s", folder)
+	}
+	return absPath, nil
+}
+
+// walkNotes walks root once, building a tree of its folders and listed
+// notes. Hidden folders such as ._images_ are skipped, and with --glob only
+// the folders of matching notes are kept. It also returns the listed notes
+// in walk order.
+func walkNotes(root string) (*listEntry, []*listEntry, error) {
+	tree := &listEntry{isDir: true}
+	folders := map[string]*listEntry{".": tree}
+	var notes []*listEntry
+
+	// folderFor returns the entry of a folder, adding it and its parents to
+	// the tree the first time it or a note beneath it is found
+	var folderFor func(rel string) *listEntry
+	folderFor = func(rel string) *listEntry {
+		if folder, ok := folders[rel]; ok {
+			return folder
+		}
+		parent := folderFor(path.Dir(rel))
+		folder := &listEntry{name: path.Base(rel), path: rel, isDir: true}
+		folders[rel] = folder
+		parent.children = append(parent.children, folder)
+		return folder
+	}
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-
-		// Skip the root directory itself and non-.md files
-		if path == notesDir {
+		if p == root {
 			return nil
 		}
 
-		// Only process directories and .md files
-		if !info.IsDir() && filepath.Ext(path) != ".md" {
+		relPath, err := filepath.Rel(root, p)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+		rel := filepath.ToSlash(relPath)
+		depth := strings.Count(rel, "/") + 1
+
+		if d.IsDir() {
+			if isHiddenDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			// Folders are shown even without notes, unless --glob picks
+			// the notes to list
+			if len(listGlobs) == 0 && (listDepth == 0 || depth <= listDepth) {
+				folderFor(rel)
+			}
+			return nil
+		}
+		if filepath.Ext(d.Name()) != ".md" {
 			return nil
 		}
 
-		// Get relative path from notes directory
-		relPath, err := filepath.Rel(notesDir, path)
+		notePath, err := noteNameFromPath(p)
 		if err != nil {
-			return fmt.Errorf("failed to get relative path: %w", err)
+			return err
+		}
+		if !matchesGlobs(notePath) {
+			return nil
+		}
+
+		// Notes below the depth limit only make their folders visible,
+		// collapsed
+		dir := path.Dir(rel)
+		if listDepth > 0 && depth > listDepth {
+			folderFor(strings.Join(strings.Split(dir, "/")[:listDepth], "/")).collapsed = true
+			return nil
 		}
 
-		// Calculate depth for indentation
-		depth := len(strings.Split(relPath, string(os.PathSeparator))) - 1
-		indent := strings.Repeat("  ", depth)
-
-		// Determine if the current entry is the last visible one in its directory
-		isLast := false
-		parentDir := filepath.Dir(path)
-		entries, err := os.ReadDir(parentDir)
-		if err == nil {
-			// Find the last visible entry (directory or .md file)
-			var lastVisibleEntry string
-			for i := len(entries) - 1; i >= 0; i-- {
-				entry := entries[i]
-				if entry.IsDir() || filepath.Ext(entry.Name()) == ".md" {
-					lastVisibleEntry = entry.Name()
-					break
-				}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		note := &listEntry{
+			name: strings.TrimSuffix(d.Name(), ".md"),
+			path: notePath,
+			info: info,
+		}
+		if listLong {
+			content, err := os.ReadFile(p)
+			if err != nil {
+				return err
 			}
-			if lastVisibleEntry == filepath.Base(path) {
-				isLast = true
+			meta, body := parseFrontMatter(content)
+			note.title = noteTitle(content, note.name)
+			note.words = len(strings.Fields(string(body)))
+			note.tags = noteTags(meta)
+		}
+
+		parent := folderFor(dir)
+		parent.children = append(parent.children, note)
+		notes = append(notes, note)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return tree, notes, nil
+}
+
+// collapsedFolders returns the folders below folder that hide notes
+// deeper than --depth
+func collapsedFolders(folder *listEntry) []*listEntry {
+	var folders []*listEntry
+	for _, entry := range folder.children {
+		if entry.collapsed {
+			folders = append(folders, entry)
+		}
+		if entry.isDir {
+			folders = append(folders, collapsedFolders(entry)...)
+		}
+	}
+	return folders
+}
+
+// matchesGlobs reports whether a note name matches any --glob pattern. A
+// pattern matches either the full note path or the note's base name.
+func matchesGlobs(name string) bool {
+	if len(listGlobs) == 0 {
+		return true
+	}
+	for _, pattern := range listGlobs {
+		pattern = strings.TrimSuffix(pattern, ".md")
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sortEntries orders entries by --sort. With name, folders and notes are
+// mixed alphabetically, by full path when byPath is set; otherwise folders
+// come first by name and notes follow, newest or largest first.
+func sortEntries(entries []*listEntry, byPath bool) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if listSort == "name" || (a.isDir && b.isDir) {
+			if byPath {
+				return a.path < b.path
 			}
+			return a.name < b.name
+		}
+		if a.isDir != b.isDir {
+			return a.isDir
 		}
+		switch listSort {
+		case "mtime":
+			return a.info.ModTime().After(b.info.ModTime())
+		case "ctime":
+			return fileCTime(a.info).After(fileCTime(b.info))
+		default:
+			return a.info.Size() > b.info.Size()
+		}
+	})
+}
 
-		// Add different prefix for files and directories
+func printTree(w *tabwriter.Writer, folder *listEntry, depth int) {
+	sortEntries(folder.children, false)
+	indent := strings.Repeat("  ", depth)
+	for i, entry := range folder.children {
 		prefix := "├──"
-		if isLast {
+		if i == len(folder.children)-1 {
 			prefix = "└──"
 		}
-		name := filepath.Base(path)
-		if filepath.Ext(name) == ".md" {
-			name = strings.TrimSuffix(name, ".md")
+		printListEntry(w, fmt.Sprintf("%s%s %s", indent, prefix, entry.name), entry)
+		if entry.isDir {
+			printTree(w, entry, depth+1)
 		}
+	}
+}
 
-		fmt.Printf("%s%s %s\n", indent, prefix, name)
-		return nil
-	})
+func printListEntry(w *tabwriter.Writer, label string, entry *listEntry) {
+	if !listLong {
+		fmt.Fprintln(w, label)
+		return
+	}
+	if entry.isDir {
+		fmt.Fprintf(w, "%s\t\t\t\t\n", label)
+		return
+	}
+
+	tags := make([]string, len(entry.tags))
+	for i, tag := range entry.tags {
+		tags[i] = "#" + tag
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%d words\t%s\n",
+		label,
+		entry.title,
+		entry.info.ModTime().Format("2006-01-02 15:04"),
+		entry.words,
+		strings.Join(tags, " "),
+	)
 }
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestEmptyListCmd(t *testing.T) {
@@ -135,3 +136,210 @@ func TestListCmd(t *testing.T) {
 		}
 	}
 }
+
+// captureListOutput runs the list command with the given flags and returns
+// its output lines
+func captureListOutput(t *testing.T, args []string, setFlags func()) []string {
+	t.Helper()
+
+	listSort, listLong, listFlat, listDepth, listGlobs = "name", false, false, 0, nil
+	if setFlags != nil {
+		setFlags()
+	}
+	defer func() {
+		listSort, listLong, listFlat, listDepth, listGlobs = "name", false, false, 0, nil
+	}()
+
+	oldStdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	os.Stdout = w
+
+	runErr := runList(listCmd, args)
+	w.Close()
+	os.Stdout = oldStdout
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		t.Fatalf("failed to read captured output: %v", err)
+	}
+	if runErr != nil {
+		t.Fatalf("unexpected error: %v", runErr)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+	return lines
+}
+
+func TestListOptions(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	now := time.Now()
+	files := []struct {
+		path    string
+		content string
+		age     time.Duration
+	}{
+		{"old.md", "---\ntitle: Old note\ntags: [archive, misc]\n---\none two three\n", 3 * time.Hour},
+		{"new.md", "# New note\n\nsome longer content here\n", time.Hour},
+		{"projects/plan.md", "# Plan\n", 2 * time.Hour},
+		{"projects/deep/meeting-1.md", "# Meeting\n", 0},
+		{"projects/._images_/pic.md", "hidden", 0},
+		{"empty/readme.txt", "not a note", 0},
+	}
+	for _, f := range files {
+		path := filepath.Join(tmpDir, f.path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(f.content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if err := os.Chtimes(path, now.Add(-f.age), now.Add(-f.age)); err != nil {
+			t.Fatalf("failed to set times: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		setFlags func()
+		want     []string
+	}{
+		{
+			name: "tree hides image folders and shows folders without notes",
+			want: []string{
+				"Notes structure:",
+				"├── empty",
+				"├── new",
+				"├── old",
+				"└── projects",
+				"  ├── deep",
+				"    └── meeting-1",
+				"  └── plan",
+			},
+		},
+		{
+			name:     "flat sorted by mtime",
+			setFlags: func() { listFlat = true; listSort = "mtime" },
+			want:     []string{"projects/deep/meeting-1", "new", "projects/plan", "old"},
+		},
+		{
+			name:     "depth limit",
+			setFlags: func() { listDepth = 1 },
+			want: []string{
+				"Notes structure:",
+				"├── empty",
+				"├── new",
+				"├── old",
+				"└── projects",
+			},
+		},
+		{
+			name:     "glob filter",
+			setFlags: func() { listFlat = true; listGlobs = []string{"meeting-*", "n*"} },
+			want:     []string{"new", "projects/deep/meeting-1"},
+		},
+		{
+			name: "folder argument",
+			args: []string{"projects"},
+			want: []string{
+				"Notes structure of projects:",
+				"├── deep",
+				"  └── meeting-1",
+				"└── plan",
+			},
+		},
+		{
+			name:     "size sort puts folders first",
+			setFlags: func() { listSort = "size"; listDepth = 1 },
+			want: []string{
+				"Notes structure:",
+				"├── empty",
+				"├── projects",
+				"├── old",
+				"└── new",
+			},
+		},
+		{
+			name:     "long format",
+			setFlags: func() { listFlat = true; listLong = true; listGlobs = []string{"old"} },
+			want: []string{
+				"old  Old note  " + now.Add(-3*time.Hour).Format("2006-01-02 15:04") + "  3 words  #archive #misc",
+			},
+		},
+		{
+			name:     "no match is empty",
+			setFlags: func() { listGlobs = []string{"nothing*"} },
+			want:     []string{"Empty"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := captureListOutput(t, tt.args, tt.setFlags)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("output mismatch\nwant:\n%s\ngot:\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestListEmptyRootWithSubfolders(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	path := filepath.Join(tmpDir, "folder", "note.md")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	got := captureListOutput(t, nil, nil)
+	want := []string{"Notes structure:", "└── folder", "  └── note"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("output mismatch\nwant:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestListDepthOnlyDeeperNotes(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	for _, name := range []string{"a/x.md", "a/b/n.md"} {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		setFlags func()
+		want     []string
+	}{
+		{"tree", func() { listDepth = 1 }, []string{"Notes structure:", "└── a"}},
+		{"flat", func() { listDepth = 1; listFlat = true }, []string{"a/"}},
+		{"tree at depth 2", func() { listDepth = 2 }, []string{"Notes structure:", "└── a", "  ├── b", "  └── x"}},
+		{"flat at depth 2", func() { listDepth = 2; listFlat = true }, []string{"a/b/", "a/x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := captureListOutput(t, nil, tt.setFlags)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("output mismatch\nwant:\n%s\ngot:\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Notes are guarded by advisory lock files so that two ned processes do not
+// write the same note at once. A lock is a hidden file next to the note,
+// .name.md.lock, holding the owner's details. Other programs ignore it; it
+// only coordinates ned with itself.
+
+var (
+	// lockWait is how long a writer waits for another writer's short-lived
+	// lock before giving up. Edit sessions are not waited for.
+	lockWait = 2 * time.Second
+
+	// staleLockAge is the age after which a lock taken on another host,
+	// whose owner ned cannot check, is ignored
+	staleLockAge = 24 * time.Hour
+)
+
+// lockInfo is the content of a lock file
+type lockInfo struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Command string    `json:"command"`
+	Since   time.Time `json:"since"`
+}
+
+// noteLock is a lock held on a note. A nil lock is valid and unlocks
+// nothing; it is returned for notes in folders that do not exist yet and
+// when the user chose to write a note despite another edit session.
+type noteLock struct {
+	path string
+}
+
+// lockPath returns the lock file of a note
+func lockPath(notePath string) string {
+	dir, base := filepath.Split(notePath)
+	return filepath.Join(dir, "."+base+".lock")
+}
+
+// lockNote takes the lock on a note for command. Locks of crashed processes
+// are taken over. A short-lived lock of another writer is waited for; when
+// the note is open in another ned edit session, the user may write anyway
+// because that session merges changes when its editor closes. Otherwise it
+// fails with a locked error.
+func lockNote(notePath, command string) (*noteLock, error) {
+	path := lockPath(notePath)
+	if _, err := os.Stat(filepath.Dir(path)); os.IsNotExist(err) {
+		// A note in a folder that does not exist yet has no writers, and
+		// creating notes never replaces one
+		return nil, nil
+	}
+	host, _ := os.Hostname()
+	data, err := json.Marshal(lockInfo{PID: os.Getpid(), Host: host, Command: command, Since: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(lockWait)
+	for {
+		err := writeFileAtomic(path, data, 0644, false)
+		if err == nil {
+			return &noteLock{path: path}, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to lock note: %w", err)
+		}
+
+		info, err := readLock(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lock: %w", err)
+		}
+		if info == nil {
+			// Released between our attempt and the read
+			continue
+		}
+		if info.stale(host) {
+			os.Remove(path)
+			continue
+		}
+		if info.Command != "edit" && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+			continue
+		}
+		return nil, lockHeld(notePath, path, info)
+	}
+}
+
+// lockHeld lets the user decide whether to go ahead while another ned edit
+// session holds the lock, and fails otherwise
+func lockHeld(notePath, path string, info *lockInfo) error {
+	held := fmt.Sprintf("%s is locked by ned %s (pid %d on %s) since %s", notePath, info.Command, info.PID, info.Host, info.Since.Format("2006-01-02 15:04"))
+	if info.Command == "edit" {
+		ok, err := confirm(held + ". Continue anyway? The editor session merges the changes when it ends")
+		if err == nil && ok {
+			return nil
+		} else if err != nil && !errors.Is(err, errNoInput) {
+			return err
+		}
+	}
+	return newCmdError(codeLocked, "%s; remove %s if that process is gone", held, path)
+}
+
+// readLock returns the owner of a lock, or nil when the lock is gone
+func readLock(path string) (*lockInfo, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var info lockInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		// Not written by ned, or damaged: treat it as an old lock of
+		// an unknown owner
+		if stat, statErr := os.Stat(path); statErr == nil {
+			info.Since = stat.ModTime()
+		}
+	}
+	return &info, nil
+}
+
+// stale reports whether the lock's owner is gone. Owners on other hosts
+// cannot be checked, so their locks only expire with age.
+func (l *lockInfo) stale(host string) bool {
+	if l.Host == host && l.PID > 0 {
+		return !processAlive(l.PID)
+	}
+	return time.Since(l.Since) > staleLockAge
+}
+
+// unlock releases the lock
+func (l *noteLock) unlock() {
+	if l != nil {
+		os.Remove(l.path)
+	}
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeLock fakes a lock held by another process
+func writeLock(t *testing.T, notePath string, info lockInfo) {
+	t.Helper()
+	data, err := json.Marshal(info)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(lockPath(notePath), data, 0644))
+}
+
+func TestLockNote(t *testing.T) {
+	dir := t.TempDir()
+	notePath := filepath.Join(dir, "todo.md")
+	host, _ := os.Hostname()
+
+	oldWait := lockWait
+	lockWait = 100 * time.Millisecond
+	defer func() { lockWait = oldWait }()
+
+	t.Run("lock and unlock", func(t *testing.T) {
+		lock, err := lockNote(notePath, "add")
+		require.NoError(t, err)
+		assert.FileExists(t, filepath.Join(dir, ".todo.md.lock"))
+		lock.unlock()
+		assert.NoFileExists(t, filepath.Join(dir, ".todo.md.lock"))
+	})
+
+	t.Run("locks of exited processes are taken over", func(t *testing.T) {
+		// No process has a pid this large
+		writeLock(t, notePath, lockInfo{PID: 1 << 30, Host: host, Command: "edit", Since: time.Now()})
+		lock, err := lockNote(notePath, "add")
+		require.NoError(t, err)
+		lock.unlock()
+	})
+
+	t.Run("old locks of other hosts are taken over", func(t *testing.T) {
+		writeLock(t, notePath, lockInfo{PID: 1, Host: "elsewhere", Command: "edit", Since: time.Now().Add(-48 * time.Hour)})
+		lock, err := lockNote(notePath, "add")
+		require.NoError(t, err)
+		lock.unlock()
+	})
+
+	t.Run("a busy writer is waited for", func(t *testing.T) {
+		writeLock(t, notePath, lockInfo{PID: os.Getpid(), Host: host, Command: "clip", Since: time.Now()})
+		_, err := lockNote(notePath, "add")
+		assert.Equal(t, codeLocked, errorCode(err))
+
+		writeLock(t, notePath, lockInfo{PID: os.Getpid(), Host: host, Command: "clip", Since: time.Now()})
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			os.Remove(lockPath(notePath))
+		}()
+		lock, err := lockNote(notePath, "add")
+		require.NoError(t, err)
+		lock.unlock()
+	})
+
+	t.Run("an edit session asks", func(t *testing.T) {
+		defer os.Remove(lockPath(notePath))
+		writeLock(t, notePath, lockInfo{PID: os.Getpid(), Host: host, Command: "edit", Since: time.Now()})
+
+		p := setupTestPrompter(t, true)
+		lock, err := lockNote(notePath, "add")
+		require.NoError(t, err)
+		assert.Nil(t, lock, "the edit session keeps its lock")
+		assert.Len(t, p.questions, 1)
+		assert.FileExists(t, lockPath(notePath))
+
+		setupTestPrompter(t, false)
+		_, err = lockNote(notePath, "add")
+		assert.Equal(t, codeLocked, errorCode(err))
+
+		noInput = true
+		defer func() { noInput = false }()
+		_, err = lockNote(notePath, "add")
+		assert.Equal(t, codeLocked, errorCode(err))
+	})
+}
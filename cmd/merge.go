@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+)
+
+// Conflict markers written by merge3, in the style of git
+const (
+	conflictOurs   = "<<<<<<< your edit"
+	conflictSep    = "======="
+	conflictTheirs = ">>>>>>> changed meanwhile"
+)
+
+// merge3 merges two versions of a note, ours and theirs, that were both
+// changed from base. Lines changed on one side only are taken from that
+// side. Where both sides changed the same lines differently, both versions
+// are kept between conflict markers. It returns the merged text and the
+// number of conflicts.
+func merge3(base, ours, theirs string) (string, int) {
+	baseLines := splitLines(base)
+	ourLines := splitLines(ours)
+	theirLines := splitLines(theirs)
+
+	toOurs := matchLines(baseLines, ourLines)
+	toTheirs := matchLines(baseLines, theirLines)
+
+	var merged []string
+	conflicts := 0
+	b, o, t := 0, 0, 0
+	for {
+		// Find the next base line both sides kept; everything before it
+		// is a changed chunk
+		i := b
+		for i < len(baseLines) && (toOurs[i] < 0 || toTheirs[i] < 0) {
+			i++
+		}
+		oi, ti := len(ourLines), len(theirLines)
+		if i < len(baseLines) {
+			oi, ti = toOurs[i], toTheirs[i]
+		}
+
+		baseChunk, ourChunk, theirChunk := baseLines[b:i], ourLines[o:oi], theirLines[t:ti]
+		switch {
+		case slices.Equal(ourChunk, baseChunk):
+			merged = append(merged, theirChunk...)
+		case slices.Equal(theirChunk, baseChunk), slices.Equal(ourChunk, theirChunk):
+			merged = append(merged, ourChunk...)
+		default:
+			conflicts++
+			merged = append(merged, conflictOurs+"\n")
+			merged = append(merged, terminated(ourChunk)...)
+			merged = append(merged, conflictSep+"\n")
+			merged = append(merged, terminated(theirChunk)...)
+			merged = append(merged, conflictTheirs+"\n")
+		}
+
+		if i == len(baseLines) {
+			break
+		}
+		merged = append(merged, baseLines[i])
+		b, o, t = i+1, oi+1, ti+1
+	}
+	return strings.Join(merged, ""), conflicts
+}
+
+// splitLines splits text into lines that keep their line endings
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// terminated makes sure the last line of a conflict chunk ends with a
+// newline so the marker after it starts on its own line
+func terminated(lines []string) []string {
+	if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+		lines = append(append([]string{}, lines[:n-1]...), lines[n-1]+"\n")
+	}
+	return lines
+}
+
+// maxMergeCells bounds the work matchLines does on the lines between the
+// common start and end of two versions, counted as the product of their
+// lengths. Beyond it the lines are not matched, and merge3 keeps them as a
+// conflict.
+const maxMergeCells = 1 << 26
+
+// matchLines returns, for every line of a, the index of the line of b it
+// is kept as in a longest common subsequence of both, or -1 when the line
+// was removed. It uses memory linear in the length of the notes.
+func matchLines(a, b []string) []int {
+	match := make([]int, len(a))
+	for i := range match {
+		match[i] = -1
+	}
+
+	// Edits usually leave most of a note alone, so the common start and end
+	// are matched first
+	start := 0
+	for start < len(a) && start < len(b) && a[start] == b[start] {
+		match[start] = start
+		start++
+	}
+	end := 0
+	for end < len(a)-start && end < len(b)-start && a[len(a)-1-end] == b[len(b)-1-end] {
+		match[len(a)-1-end] = len(b) - 1 - end
+		end++
+	}
+
+	a, b = a[start:len(a)-end], b[start:len(b)-end]
+	if len(a)*len(b) <= maxMergeCells {
+		matchMiddle(a, b, start, start, match)
+	}
+	return match
+}
+
+// matchMiddle matches a against b with Hirschberg's algorithm, recording
+// in match that a[i] is kept as b[j] at match[ai+i] = bj+j
+func matchMiddle(a, b []string, ai, bj int, match []int) {
+	if len(a) == 0 || len(b) == 0 {
+		return
+	}
+	if len(a) == 1 {
+		if j := slices.Index(b, a[0]); j >= 0 {
+			match[ai] = bj + j
+		}
+		return
+	}
+
+	// Split b where a longest common subsequence crosses the middle of a
+	mid := len(a) / 2
+	head := lcsHead(a[:mid], b)
+	tail := lcsTail(a[mid:], b)
+	split := 0
+	for j := range head {
+		if head[j]+tail[j] > head[split]+tail[split] {
+			split = j
+		}
+	}
+	matchMiddle(a[:mid], b[:split], ai, bj, match)
+	matchMiddle(a[mid:], b[split:], ai+mid, bj+split, match)
+}
+
+// lcsHead returns, for every j, the length of the longest common
+// subsequence of a and b[:j]
+func lcsHead(a, b []string) []int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := 1; j <= len(b); j++ {
+			if a[i] == b[j-1] {
+				cur[j] = prev[j-1] + 1
+			} else {
+				cur[j] = max(prev[j], cur[j-1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev
+}
+
+// lcsTail returns, for every j, the length of the longest common
+// subsequence of a and b[j:]
+func lcsTail(a, b []string) []int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				cur[j] = prev[j+1] + 1
+			} else {
+				cur[j] = max(prev[j], cur[j+1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev
+}
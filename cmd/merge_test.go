@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge3(t *testing.T) {
+	base := "# Plan\n\nintro\n\n- one\n- two\n- three\n"
+
+	tests := []struct {
+		name          string
+		ours, theirs  string
+		want          string
+		wantConflicts int
+	}{
+		{
+			name:   "only ours changed",
+			ours:   "# Plan\n\nbetter intro\n\n- one\n- two\n- three\n",
+			theirs: base,
+			want:   "# Plan\n\nbetter intro\n\n- one\n- two\n- three\n",
+		},
+		{
+			name:   "only theirs changed",
+			ours:   base,
+			theirs: base + "- four\n",
+			want:   base + "- four\n",
+		},
+		{
+			name:   "separate changes are combined",
+			ours:   "# Plan\n\nbetter intro\n\n- one\n- two\n- three\n",
+			theirs: "# Plan\n\nintro\n\n- one\n- two\n- three\n- four\n",
+			want:   "# Plan\n\nbetter intro\n\n- one\n- two\n- three\n- four\n",
+		},
+		{
+			name:   "same change on both sides",
+			ours:   "# Plan\n\nintro\n\n- one\n- three\n",
+			theirs: "# Plan\n\nintro\n\n- one\n- three\n",
+			want:   "# Plan\n\nintro\n\n- one\n- three\n",
+		},
+		{
+			name:          "conflicting changes are marked",
+			ours:          "# Plan\n\nintro\n\n- one\n- 2\n- three\n",
+			theirs:        "# Plan\n\nintro\n\n- one\n- deux\n- three\n",
+			want:          "# Plan\n\nintro\n\n- one\n<<<<<<< your edit\n- 2\n=======\n- deux\n>>>>>>> changed meanwhile\n- three\n",
+			wantConflicts: 1,
+		},
+		{
+			name:          "missing final newline",
+			ours:          base + "- mine",
+			theirs:        base + "- theirs",
+			want:          base + "<<<<<<< your edit\n- mine\n=======\n- theirs\n>>>>>>> changed meanwhile\n",
+			wantConflicts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := merge3(base, tt.ours, tt.theirs)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantConflicts, conflicts)
+		})
+	}
+}
+
+func TestMatchLines(t *testing.T) {
+	// The matches are a common subsequence as long as the quadratic
+	// dynamic program finds
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 200; n++ {
+		a := make([]string, rng.Intn(12))
+		b := make([]string, rng.Intn(12))
+		for i := range a {
+			a[i] = string(rune('a' + rng.Intn(4)))
+		}
+		for i := range b {
+			b[i] = string(rune('a' + rng.Intn(4)))
+		}
+
+		match := matchLines(a, b)
+		kept, last := 0, -1
+		for i, j := range match {
+			if j < 0 {
+				continue
+			}
+			if j <= last || a[i] != b[j] {
+				t.Fatalf("matchLines(%q, %q) = %v is not a common subsequence", a, b, match)
+			}
+			kept, last = kept+1, j
+		}
+		if want := lcsLength(a, b); kept != want {
+			t.Errorf("matchLines(%q, %q) kept %d lines, want %d", a, b, kept, want)
+		}
+	}
+
+	t.Run("large notes", func(t *testing.T) {
+		lines := make([]string, 20000)
+		for i := range lines {
+			lines[i] = fmt.Sprintf("line %d\n", i)
+		}
+		base := strings.Join(lines, "")
+		ours := strings.Replace(base, "line 100\n", "line one hundred\n", 1)
+		theirs := strings.Replace(base, "line 19000\n", "", 1)
+		got, conflicts := merge3(base, ours, theirs)
+		assert.Zero(t, conflicts)
+		assert.Equal(t, strings.Replace(ours, "line 19000\n", "", 1), got)
+	})
+
+	t.Run("too large to match is a conflict", func(t *testing.T) {
+		a := make([]string, 10000)
+		b := make([]string, 10000)
+		for i := range a {
+			a[i] = fmt.Sprintf("a %d\n", i)
+			b[i] = fmt.Sprintf("b %d\n", i)
+		}
+		b[5000] = a[5000]
+		for _, j := range matchLines(append([]string{"x\n"}, a...), append([]string{"x\n"}, b...))[1:] {
+			assert.Equal(t, -1, j)
+		}
+	})
+}
+
+// lcsLength is the length of the longest common subsequence of a and b
+func lcsLength(a, b []string) int {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if a[0] == b[0] {
+		return 1 + lcsLength(a[1:], b[1:])
+	}
+	return max(lcsLength(a[1:], b), lcsLength(a, b[1:]))
+}
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,22 +14,45 @@ import (
 )
 
 var (
-	title string
+	title     string
+	newEdit   bool
+	newNoEdit bool
+	newForce  bool
+	newAppend bool
 )
 
+// newResult is the structured output of the new command
+type newResult struct {
+	Note string `json:"note" yaml:"note"`
+	Path string `json:"path" yaml:"path"`
+}
+
 var newCmd = &cobra.Command{
 	Use:   "new [filename]",
 	Short: "Create a new note",
 	Long: `Create a new note with optional filename and title.
 If filename is not provided, an auto-generated name will be used.
 The .md extension is optional and will be added automatically if not provided.
-You can specify subdirectories in the filename.`,
+You can specify subdirectories in the filename.
+
+After creating the note, ned asks whether to open it in the editor. Use
+--edit or --no-edit to decide up front; without a terminal the note is
+not opened unless --edit is given.
+
+An existing note is never overwritten by accident: use --force to replace
+it, or --append to add the piped content to its end.`,
 	Aliases: []string{"n"},
 	RunE:    runNew,
 }
 
 func init() {
 	newCmd.Flags().StringVarP(&title, "title", "t", "", "Title of the note")
+	newCmd.Flags().BoolVar(&newEdit, "edit", false, "Open the new note in the editor without asking")
+	newCmd.Flags().BoolVar(&newNoEdit, "no-edit", false, "Do not offer to open the new note in the editor")
+	newCmd.Flags().BoolVarP(&newForce, "force", "f", false, "Overwrite the note if it already exists")
+	newCmd.Flags().BoolVarP(&newAppend, "append", "a", false, "Append to the note if it already exists")
+	newCmd.MarkFlagsMutuallyExclusive("edit", "no-edit")
+	newCmd.MarkFlagsMutuallyExclusive("force", "append")
 	rootCmd.AddCommand(newCmd)
 }
 
@@ -48,7 +73,7 @@ func runNew(cmd *cobra.Command, args []string) error {
 	// Clean and validate the path
 	cleanPath := filepath.Clean(filename)
 	if filepath.IsAbs(cleanPath) {
-		return fmt.Errorf("absolute paths are not allowed")
+		return newCmdError(codeInvalidPath, "absolute paths are not allowed")
 	}
 
 	// Create full path and verify it's within notes directory
@@ -65,7 +90,7 @@ func runNew(cmd *cobra.Command, args []string) error {
 
 	// Ensure the target path is within the notes directory
 	if !strings.HasPrefix(absPath, absNotesDir) {
-		return fmt.Errorf("path must be within notes directory")
+		return newCmdError(codeInvalidPath, "path must be within notes directory")
 	}
 
 	// Create subdirectories if they don't exist
@@ -85,47 +110,97 @@ func runNew(cmd *cobra.Command, args []string) error {
 		content = builder.String()
 	}
 
-	// Create file with title if specified
-	f, err := os.Create(fullPath)
+	// The lock keeps other ned commands from writing the note between the
+	// check and the write
+	lock, err := lockNote(fullPath, "new")
 	if err != nil {
-		return fmt.Errorf("failed to create note file: %w", err)
+		return err
 	}
-	defer f.Close()
-
-	// Set default title if not specified and filename is provided (without extension)
-	if title == "" && filename != "" {
-		title = strings.TrimSuffix(filepath.Base(filename), ".md")
+	_, statErr := os.Stat(fullPath)
+	exists := statErr == nil
+	if exists && !newForce && !newAppend {
+		lock.unlock()
+		return newCmdError(codeAlreadyExists, "note already exists: %s (use --force to overwrite it or --append to add to it)", filename)
+	}
+	err = writeNewNote(fullPath, filename, content, exists)
+	lock.unlock()
+	if err != nil {
+		return err
 	}
 
-	// Write title if specified
-	if title != "" {
-		if _, err := f.WriteString(fmt.Sprintf("# %s\n\n", title)); err != nil {
-			return fmt.Errorf("failed to write title: %w", err)
+	if structuredOutput() {
+		if err := printResult(newResult{Note: strings.TrimSuffix(filepath.ToSlash(cleanPath), ".md"), Path: absPath}); err != nil {
+			return err
+		}
+	} else {
+		if exists && newAppend {
+			fmt.Printf("Appended to note: %s\n", filename)
+		} else {
+			fmt.Printf("Created new note: %s\n", filename)
 		}
 	}
 
-	// Write content from stdin if available
-	if content != "" {
-		if _, err := f.WriteString(content); err != nil {
-			return fmt.Errorf("failed to write content: %w", err)
+	edit, err := shouldEditNewNote()
+	if err != nil {
+		return err
+	}
+	if edit {
+		if err := editNote(fullPath, 0); err != nil {
+			return fmt.Errorf("failed to edit note: %w", err)
 		}
 	}
 
-	fmt.Printf("Created new note: %s\n", filename)
-
-	if !testMode {
-		// Prompt user to edit the new note
-		var editNote string
-		fmt.Print("Do you want to edit the new note? (y/n): ")
-		fmt.Scanln(&editNote)
+	return nil
+}
 
-		if strings.ToLower(editNote) == "y" {
-			err := runEdit(editCmd, []string{filename})
-			if err != nil {
-				return fmt.Errorf("failed to edit note: %w", err)
-			}
+// writeNewNote writes a new note with its title and content, or with
+// --append adds the content to the existing note
+func writeNewNote(fullPath, filename, content string, exists bool) error {
+	var data string
+	if exists && newAppend {
+		// Only the new content is added; the note already has its title
+		existing, err := os.ReadFile(fullPath)
+		if err != nil {
+			return fmt.Errorf("failed to read note: %w", err)
+		}
+		data = string(existing)
+		if text := strings.TrimRight(content, "\n"); strings.TrimSpace(text) != "" {
+			data, _ = insertText(data, text, "")
+		}
+	} else {
+		// Set default title if not specified and filename is provided (without extension)
+		if title == "" && filename != "" {
+			title = strings.TrimSuffix(filepath.Base(filename), ".md")
+		}
+		if title != "" {
+			data = fmt.Sprintf("# %s\n\n", title)
 		}
+		data += content
 	}
 
+	if err := writeFileAtomic(fullPath, []byte(data), filePerm(fullPath, 0644), exists); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return newCmdError(codeAlreadyExists, "note already exists: %s", filename)
+		}
+		return fmt.Errorf("failed to write note file: %w", err)
+	}
 	return nil
 }
+
+// shouldEditNewNote decides whether to open a new note in the editor. The
+// user is only asked at a terminal, when neither --edit nor --no-edit is
+// given and the result is not printed for a script.
+func shouldEditNewNote() (bool, error) {
+	switch {
+	case newEdit:
+		return true, nil
+	case newNoEdit, structuredOutput(), !interactive():
+		return false, nil
+	}
+
+	edit, err := confirm("Do you want to edit the new note?")
+	if errors.Is(err, errNoInput) {
+		return false, nil
+	}
+	return edit, err
+}
@@ -1,14 +1,18 @@
 package cmd
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 )
 
 func TestNewCmd(t *testing.T) {
-	testMode = true
+	noInput = true
+	defer func() { noInput = false }()
 	tmpDir, cleanup := setupTestEnv(t)
 	defer cleanup()
 
@@ -38,10 +42,10 @@ func TestNewCmd(t *testing.T) {
 		},
 		{
 			name:     "create note without md extension",
-			args:     []string{"test"},
+			args:     []string{"plain"},
 			title:    "No Extension",
 			content:  "Content here\n",
-			wantFile: "test.md",
+			wantFile: "plain.md",
 			wantErr:  false,
 		},
 		{
@@ -124,3 +128,121 @@ func TestNewCmd(t *testing.T) {
 		})
 	}
 }
+
+func TestNewEditPrompt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("editor stub is a shell script")
+	}
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	// The editor stub marks every note it opens
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	if err := os.WriteFile(editor, []byte("#!/bin/sh\necho edited >> \"$1\"\n"), 0755); err != nil {
+		t.Fatalf("failed to write editor stub: %v", err)
+	}
+	t.Setenv("EDITOR", editor)
+
+	tests := []struct {
+		name          string
+		edit, noEdit  bool
+		noInput       bool
+		answers       []bool
+		wantQuestions int
+		wantEdited    bool
+	}{
+		{name: "asks and edits", answers: []bool{true}, wantQuestions: 1, wantEdited: true},
+		{name: "asks and skips", answers: []bool{false}, wantQuestions: 1},
+		{name: "--edit does not ask", edit: true, wantEdited: true},
+		{name: "--no-edit does not ask", noEdit: true},
+		{name: "--no-input does not ask", noInput: true, answers: []bool{true}},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := setupTestPrompter(t, tt.answers...)
+			title = "Prompted"
+			newEdit, newNoEdit, noInput = tt.edit, tt.noEdit, tt.noInput
+			defer func() { newEdit, newNoEdit, noInput = false, false, false }()
+
+			name := fmt.Sprintf("prompt-%d", i)
+			if err := runNew(newCmd, []string{name}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(p.questions) != tt.wantQuestions {
+				t.Errorf("asked %d questions, want %d: %q", len(p.questions), tt.wantQuestions, p.questions)
+			}
+			content, err := os.ReadFile(filepath.Join(tmpDir, name+".md"))
+			if err != nil {
+				t.Fatalf("failed to read note: %v", err)
+			}
+			if edited := strings.Contains(string(content), "edited"); edited != tt.wantEdited {
+				t.Errorf("edited = %v, want %v", edited, tt.wantEdited)
+			}
+		})
+	}
+}
+
+func TestNewExistingNote(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	noInput = true
+	defer func() { noInput = false }()
+
+	notePath := filepath.Join(tmpDir, "exists.md")
+	original := "# Exists\n\nkeep me\n"
+
+	tests := []struct {
+		name        string
+		force       bool
+		appendNote  bool
+		content     string
+		wantCode    string
+		wantContent string
+	}{
+		{name: "refuses without a flag", wantCode: codeAlreadyExists, wantContent: original},
+		{name: "--force overwrites", force: true, content: "replaced\n", wantContent: "# exists\n\nreplaced\n"},
+		{name: "--append adds to the end", appendNote: true, content: "added\n", wantContent: original + "\nadded\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(notePath, []byte(original), 0644); err != nil {
+				t.Fatalf("failed to write note: %v", err)
+			}
+			title = ""
+			newForce, newAppend = tt.force, tt.appendNote
+			defer func() { newForce, newAppend = false, false }()
+
+			if tt.content != "" {
+				r, w, err := os.Pipe()
+				if err != nil {
+					t.Fatalf("failed to create pipe: %v", err)
+				}
+				oldStdin := os.Stdin
+				os.Stdin = r
+				defer func() { os.Stdin = oldStdin }()
+				w.WriteString(tt.content)
+				w.Close()
+			}
+
+			_, err := captureStdout(t, func() error { return runNew(newCmd, []string{"exists"}) })
+			if tt.wantCode != "" {
+				if got := errorCode(err); got != tt.wantCode {
+					t.Errorf("error code = %q, want %q (%v)", got, tt.wantCode, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			content, err := os.ReadFile(notePath)
+			if err != nil {
+				t.Fatalf("failed to read note: %v", err)
+			}
+			if string(content) != tt.wantContent {
+				t.Errorf("content mismatch\nwant: %q\ngot:  %q", tt.wantContent, string(content))
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// noteInfo describes a note file found under the notes directory
+type noteInfo struct {
+	// Name is the note's path relative to the notes directory, using forward
+	// slashes and without the .md extension
+	Name    string
+	Path    string
+	Title   string
+	ModTime time.Time
+	Size    int64
+}
+
+// isHiddenDir reports whether a directory is skipped when scanning notes.
+// This covers ._images_ folders as well as any other dot directory.
+func isHiddenDir(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// scanNotes walks the notes directory once and returns every note in
+// lexical order. Titles are read from each note's front matter or first
+// heading.
+func scanNotes() ([]noteInfo, error) {
+	var notes []noteInfo
+	err := walkNoteFiles(func(note noteInfo, _ []byte) error {
+		notes = append(notes, note)
+		return nil
+	})
+	return notes, err
+}
+
+// walkNoteFiles reads every note in lexical order and calls fn with the
+// note and its content, so callers that need the text read each file once.
+func walkNoteFiles(fn func(note noteInfo, content []byte) error) error {
+	return filepath.WalkDir(notesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != notesDir && isHiddenDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(strings.ToLower(d.Name()), ".md") {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		name, err := noteNameFromPath(path)
+		if err != nil {
+			return err
+		}
+		return fn(noteInfo{
+			Name:    name,
+			Path:    path,
+			Title:   noteTitle(content, filepath.Base(name)),
+			ModTime: info.ModTime(),
+			Size:    info.Size(),
+		}, content)
+	})
+}
+
+// noteNameFromPath converts a note file path to its name relative to the
+// notes directory, e.g. "$root/folder/note.md" -> "folder/note"
+func noteNameFromPath(path string) (string, error) {
+	relPath, err := filepath.Rel(notesDir, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to get relative path: %w", err)
+	}
+	return filepath.ToSlash(strings.TrimSuffix(relPath, filepath.Ext(relPath))), nil
+}
+
+// noteFilePath returns the file path of a note name, e.g. "folder/note" ->
+// "$root/folder/note.md"
+func noteFilePath(name string) string {
+	return filepath.Join(notesDir, filepath.FromSlash(name)+".md")
+}
+
+// parseFrontMatter splits a YAML front matter block delimited by "---" lines
+// from the note body. Notes without front matter, or with front matter that
+// is not valid YAML, return a nil map and the content unchanged.
+func parseFrontMatter(content []byte) (map[string]any, []byte) {
+	if !bytes.HasPrefix(content, []byte("---\n")) && !bytes.HasPrefix(content, []byte("---\r\n")) {
+		return nil, content
+	}
+
+	rest := content[bytes.IndexByte(content, '\n')+1:]
+	offset := 0
+	for offset < len(rest) {
+		end := bytes.IndexByte(rest[offset:], '\n')
+		line := rest[offset:]
+		if end >= 0 {
+			line = rest[offset : offset+end+1]
+		}
+		if trimmed := bytes.TrimRight(line, "\r\n"); string(trimmed) == "---" || string(trimmed) == "..." {
+			meta := map[string]any{}
+			if err := yaml.Unmarshal(rest[:offset], &meta); err != nil {
+				return nil, content
+			}
+			return meta, rest[offset+len(line):]
+		}
+		offset += len(line)
+	}
+	return nil, content
+}
+
+// noteTitle returns the title from the front matter, or else the text of
+// the first markdown heading, or else the fallback.
+func noteTitle(content []byte, fallback string) string {
+	meta, body := parseFrontMatter(content)
+	if title, ok := meta["title"].(string); ok && strings.TrimSpace(title) != "" {
+		return strings.TrimSpace(title)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	inFence := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || !strings.HasPrefix(line, "#") {
+			continue
+		}
+		level := len(line) - len(strings.TrimLeft(line, "#"))
+		if level > 6 || (len(line) > level && line[level] != ' ' && line[level] != '\t') {
+			continue
+		}
+		title := strings.TrimSpace(line[level:])
+		// Drop an optional closing sequence: "## Title ##"
+		if trimmed := strings.TrimRight(title, "#"); trimmed == "" || strings.HasSuffix(trimmed, " ") {
+			title = strings.TrimSpace(trimmed)
+		}
+		if title != "" {
+			return title
+		}
+	}
+	return fallback
+}
+
+// noteTags returns the tags listed in front matter, either as a YAML list or
+// as a comma separated string
+func noteTags(meta map[string]any) []string {
+	var tags []string
+	switch v := meta["tags"].(type) {
+	case []any:
+		for _, tag := range v {
+			if s := strings.TrimPrefix(strings.TrimSpace(fmt.Sprint(tag)), "#"); s != "" {
+				tags = append(tags, s)
+			}
+		}
+	case string:
+		for _, tag := range strings.Split(v, ",") {
+			if s := strings.TrimPrefix(strings.TrimSpace(tag), "#"); s != "" {
+				tags = append(tags, s)
+			}
+		}
+	}
+	return tags
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNoteTitle(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "first heading",
+			content: "intro\n\n## Second level\n# First level\n",
+			want:    "Second level",
+		},
+		{
+			name:    "front matter title wins",
+			content: "---\ntitle: From front matter\ntags: [a]\n---\n# Heading\n",
+			want:    "From front matter",
+		},
+		{
+			name:    "front matter without title",
+			content: "---\ntags: [a]\n---\n# Heading\n",
+			want:    "Heading",
+		},
+		{
+			name:    "closing sequence is dropped",
+			content: "## Title ##\n",
+			want:    "Title",
+		},
+		{
+			name:    "hash inside title is kept",
+			content: "# Learning C#\n",
+			want:    "Learning C#",
+		},
+		{
+			name:    "heading inside code fence is ignored",
+			content: "```\n# comment\n```\n",
+			want:    "fallback",
+		},
+		{
+			name:    "hashtag is not a heading",
+			content: "#tag\n",
+			want:    "fallback",
+		},
+		{
+			name:    "invalid front matter",
+			content: "---\ntitle: [unclosed\n---\n",
+			want:    "fallback",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := noteTitle([]byte(tt.content), "fallback"); got != tt.want {
+				t.Errorf("noteTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	meta, body := parseFrontMatter([]byte("---\ntitle: Test\ntags:\n  - go\n---\nbody\n"))
+	if meta["title"] != "Test" {
+		t.Errorf("title = %v, want Test", meta["title"])
+	}
+	if string(body) != "body\n" {
+		t.Errorf("body = %q, want %q", body, "body\n")
+	}
+
+	meta, body = parseFrontMatter([]byte("# No front matter\n"))
+	if meta != nil || string(body) != "# No front matter\n" {
+		t.Errorf("unexpected result for note without front matter: %v, %q", meta, body)
+	}
+}
+
+func TestScanNotes(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	files := map[string]string{
+		"b.md":                  "# Bee",
+		"folder/a.md":           "no heading",
+		"folder/._images_/x.md": "# hidden",
+		".git/notes.md":         "# hidden",
+		"readme.txt":            "not a note",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	notes, err := scanNotes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []struct{ name, title string }{
+		{"b", "Bee"},
+		{"folder/a", "a"},
+	}
+	if len(notes) != len(want) {
+		t.Fatalf("got %d notes, want %d: %+v", len(notes), len(want), notes)
+	}
+	for i, w := range want {
+		if notes[i].Name != w.name || notes[i].Title != w.title {
+			t.Errorf("note %d = %s (%s), want %s (%s)", i, notes[i].Name, notes[i].Title, w.name, w.title)
+		}
+	}
+}
+
+func TestBuildNoteTree(t *testing.T) {
+	now := time.Now()
+	notes := []noteInfo{
+		{Name: "z", ModTime: now.Add(-time.Hour)},
+		{Name: "a/b/c", ModTime: now},
+		{Name: "a/note", ModTime: now.Add(-2 * time.Hour)},
+	}
+
+	root := buildNoteTree(notes)
+	if len(root.Notes) != 1 || root.Notes[0].Name != "z" {
+		t.Errorf("unexpected root notes: %+v", root.Notes)
+	}
+	if len(root.Folders) != 1 || root.Folders[0].Path != "a" {
+		t.Fatalf("unexpected root folders: %+v", root.Folders)
+	}
+	a := root.Folders[0]
+	if len(a.Notes) != 1 || a.Notes[0].Name != "a/note" {
+		t.Errorf("unexpected notes in a: %+v", a.Notes)
+	}
+	if len(a.Folders) != 1 || a.Folders[0].Path != "a/b" || a.Folders[0].Notes[0].Name != "a/b/c" {
+		t.Errorf("unexpected folders in a: %+v", a.Folders)
+	}
+
+	recent := recentNotes(notes, 2)
+	if len(recent) != 2 || recent[0].Name != "a/b/c" || recent[1].Name != "z" {
+		t.Errorf("unexpected recent notes: %+v", recent)
+	}
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// outputFormat is set by the global --output flag
+var outputFormat string
+
+// Error codes reported in structured output. They are part of ned's
+// scripting interface and must not change.
+const (
+	codeError         = "error"
+	codeUsage         = "usage"
+	codeNotFound      = "not_found"
+	codeAlreadyExists = "already_exists"
+	codeInvalidPath   = "invalid_path"
+	codeFetchFailed   = "fetch_failed"
+	codeConfig        = "config"
+	codeLocked        = "locked"
+)
+
+// exitCodes maps each error code to the process exit status
+var exitCodes = map[string]int{
+	codeError:         1,
+	codeUsage:         2,
+	codeNotFound:      3,
+	codeAlreadyExists: 4,
+	codeInvalidPath:   5,
+	codeFetchFailed:   6,
+	codeConfig:        7,
+	codeLocked:        8,
+}
+
+// cmdError is an error with a stable code for scripts
+type cmdError struct {
+	code string
+	err  error
+}
+
+func (e *cmdError) Error() string {
+	return e.err.Error()
+}
+
+func (e *cmdError) Unwrap() error {
+	return e.err
+}
+
+// newCmdError formats an error like fmt.Errorf and tags it with code
+func newCmdError(code string, format string, a ...any) error {
+	return &cmdError{code: code, err: fmt.Errorf(format, a...)}
+}
+
+// errorCode returns the code of err, or codeError for untagged errors
+func errorCode(err error) string {
+	var ce *cmdError
+	if errors.As(err, &ce) {
+		return ce.code
+	}
+	var ee EmptyError
+	if errors.As(err, &ee) {
+		return codeNotFound
+	}
+	return codeError
+}
+
+// ExitCode returns the process exit status for an error returned by Execute
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return exitCodes[errorCode(err)]
+}
+
+// structuredOutput reports whether --output asks for json or yaml
+func structuredOutput() bool {
+	return outputFormat == "json" || outputFormat == "yaml"
+}
+
+// printResult writes v to stdout in the format selected by --output
+func printResult(v any) error {
+	return encodeOutput(os.Stdout, v)
+}
+
+func encodeOutput(w io.Writer, v any) error {
+	if outputFormat == "yaml" {
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// promptOutput returns where interactive questions are printed. Structured
+// output keeps stdout free for the result.
+func promptOutput() io.Writer {
+	if structuredOutput() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// errorOutput is the structured form of an error
+type errorOutput struct {
+	Error struct {
+		Code    string `json:"code" yaml:"code"`
+		Message string `json:"message" yaml:"message"`
+	} `json:"error" yaml:"error"`
+}
+
+// printError reports err on stderr, as a structured object when --output
+// asks for one
+func printError(err error) {
+	if !structuredOutput() {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return
+	}
+
+	var out errorOutput
+	out.Error.Code = errorCode(err)
+	out.Error.Message = err.Error()
+	if encErr := encodeOutput(os.Stderr, out); encErr != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+}
+
+// tagUsageErrors marks argument validation errors of c and its subcommands
+// with codeUsage
+func tagUsageErrors(c *cobra.Command) {
+	if validate := c.Args; validate != nil {
+		c.Args = func(cmd *cobra.Command, args []string) error {
+			if err := validate(cmd, args); err != nil {
+				return &cmdError{code: codeUsage, err: err}
+			}
+			return nil
+		}
+	}
+	for _, sub := range c.Commands() {
+		tagUsageErrors(sub)
+	}
+}
+
+// outputFormatFromArgs finds the value of --output in raw arguments
+func outputFormatFromArgs(args []string) string {
+	format := "text"
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return format
+		case arg == "-o" || arg == "--output":
+			if i+1 < len(args) {
+				format = args[i+1]
+			}
+		case strings.HasPrefix(arg, "--output="):
+			format = strings.TrimPrefix(arg, "--output=")
+		case strings.HasPrefix(arg, "-o") && !strings.HasPrefix(arg, "--"):
+			format = strings.TrimPrefix(strings.TrimPrefix(arg, "-o"), "=")
+		}
+	}
+	return format
+}
+
+func validateOutputFormat(cmd *cobra.Command, args []string) error {
+	switch outputFormat {
+	case "text", "json", "yaml":
+	default:
+		return newCmdError(codeUsage, "invalid output format %q: must be text, json or yaml", outputFormat)
+	}
+	if structuredOutput() {
+		cmd.SilenceUsage = true
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// captureStdout runs fn and returns what it wrote to stdout
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	oldStdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = oldStdout }()
+
+	outCh := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		outCh <- string(out)
+	}()
+
+	runErr := fn()
+	w.Close()
+	return <-outCh, runErr
+}
+
+// setOutputFormat selects an --output format for the duration of a test
+func setOutputFormat(t *testing.T, format string) {
+	t.Helper()
+	outputFormat = format
+	t.Cleanup(func() { outputFormat = "text" })
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, 0},
+		{"untagged", errors.New("boom"), 1},
+		{"usage", newCmdError(codeUsage, "bad flag"), 2},
+		{"not found", newCmdError(codeNotFound, "note '%s' not found", "x"), 3},
+		{"wrapped", fmt.Errorf("outer: %w", newCmdError(codeInvalidPath, "bad path")), 5},
+		{"empty", EmptyError{}, 3},
+		{"config", newCmdError(codeConfig, "bad config"), 7},
+		{"locked", newCmdError(codeLocked, "busy"), 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ExitCode(tt.err))
+		})
+	}
+}
+
+func TestOutputFormatFromArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"list"}, "text"},
+		{[]string{"list", "-o", "json"}, "json"},
+		{[]string{"-ojson", "list"}, "json"},
+		{[]string{"list", "--output=yaml"}, "yaml"},
+		{[]string{"list", "--output", "yaml", "--bogus"}, "yaml"},
+		{[]string{"new", "--", "-o", "json"}, "text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			assert.Equal(t, tt.want, outputFormatFromArgs(tt.args))
+		})
+	}
+}
+
+func TestStructuredOutput(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	require.NoError(t, os.MkdirAll(tmpDir+"/work", 0755))
+	require.NoError(t, os.WriteFile(tmpDir+"/work/plan.md", []byte("# Plan\n"), 0644))
+	require.NoError(t, os.WriteFile(tmpDir+"/ideas.md", []byte("ideas\n"), 0644))
+
+	t.Run("json", func(t *testing.T) {
+		setOutputFormat(t, "json")
+
+		out, err := captureStdout(t, func() error { return runList(listCmd, nil) })
+		require.NoError(t, err)
+
+		var results []listResult
+		require.NoError(t, json.Unmarshal([]byte(out), &results))
+		require.Len(t, results, 2)
+		assert.Equal(t, "ideas", results[0].Note)
+		assert.Equal(t, "work/plan", results[1].Note)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		setOutputFormat(t, "yaml")
+
+		out, err := captureStdout(t, func() error { return runList(listCmd, nil) })
+		require.NoError(t, err)
+
+		var results []listResult
+		require.NoError(t, yaml.Unmarshal([]byte(out), &results))
+		require.Len(t, results, 2)
+		assert.Equal(t, int64(6), results[0].Size)
+	})
+
+	t.Run("empty list is an empty array", func(t *testing.T) {
+		setOutputFormat(t, "json")
+		listGlobs = []string{"nothing-*"}
+		defer func() { listGlobs = nil }()
+
+		out, err := captureStdout(t, func() error { return runList(listCmd, nil) })
+		require.NoError(t, err)
+		assert.Equal(t, "[]\n", out)
+	})
+
+	t.Run("new prints the created note", func(t *testing.T) {
+		setOutputFormat(t, "json")
+		title = ""
+
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		oldStdin := os.Stdin
+		os.Stdin = r
+		defer func() { os.Stdin = oldStdin }()
+		w.Close()
+
+		out, err := captureStdout(t, func() error { return runNew(newCmd, []string{"work/new"}) })
+		require.NoError(t, err)
+
+		var result newResult
+		require.NoError(t, json.Unmarshal([]byte(out), &result))
+		assert.Equal(t, "work/new", result.Note)
+		assert.FileExists(t, result.Path)
+	})
+}
+
+func TestPrintError(t *testing.T) {
+	setOutputFormat(t, "json")
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	oldStderr := os.Stderr
+	os.Stderr = w
+	printError(newCmdError(codeNotFound, "note '%s' not found", "missing"))
+	os.Stderr = oldStderr
+	w.Close()
+
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	var result errorOutput
+	require.NoError(t, json.Unmarshal(out, &result))
+	assert.Equal(t, "not_found", result.Error.Code)
+	assert.Equal(t, "note 'missing' not found", result.Error.Message)
+}
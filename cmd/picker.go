@@ -0,0 +1,519 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+	"golang.org/x/text/width"
+)
+
+// pickerItem is an entry offered by the fuzzy picker
+type pickerItem struct {
+	// Name is shown in the list and matched against the query
+	Name string
+	// Title is matched as well and shown next to the name when it differs
+	Title   string
+	ModTime time.Time
+	// Path is the file shown in the preview pane
+	Path string
+}
+
+// pickerOptions configure a picker session
+type pickerOptions struct {
+	Prompt string
+	// Multi lets the user select several items with Tab
+	Multi bool
+	// Preview returns the lines shown next to the list for an item
+	Preview func(item pickerItem) []string
+}
+
+// errPickerCancelled is returned when the user leaves the picker with Esc
+// or Ctrl+C
+var errPickerCancelled = errors.New("selection cancelled")
+
+// pickItems lets the user choose among items in a full-screen fuzzy finder
+// drawn on the terminal. Tests replace it with a scripted choice.
+var pickItems = runPicker
+
+// pickNotes offers every note in the picker and returns the chosen note
+// names. It is used by commands whose note argument was left out. A
+// cancelled picker returns no names and no error.
+func pickNotes(prompt string, multi bool) ([]string, error) {
+	if !interactive() {
+		return nil, newCmdError(codeUsage, "a note name is required when not running in a terminal")
+	}
+
+	notes, err := scanNotes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan notes: %w", err)
+	}
+	if len(notes) == 0 {
+		return nil, newCmdError(codeNotFound, "no notes found")
+	}
+
+	items := make([]pickerItem, len(notes))
+	for i, note := range notes {
+		items[i] = pickerItem{Name: note.Name, Title: note.Title, ModTime: note.ModTime, Path: note.Path}
+	}
+
+	picked, err := pickItems(items, pickerOptions{Prompt: prompt, Multi: multi, Preview: previewFile})
+	if errors.Is(err, errPickerCancelled) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(picked))
+	for i, item := range picked {
+		names[i] = item.Name
+	}
+	return names, nil
+}
+
+// previewFile returns the first lines of a text file
+func previewFile(item pickerItem) []string {
+	content, err := os.ReadFile(item.Path)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	lines := strings.Split(strings.ReplaceAll(string(content), "\t", "    "), "\n")
+	if len(lines) > 200 {
+		lines = lines[:200]
+	}
+	return lines
+}
+
+// fuzzyScore matches the runes of pattern in order against text, ignoring
+// case. Runes matched at the start of a word or right after the previous
+// match score higher, so "wp" prefers "work/plan" over "wrap". The best
+// alignment is found by trying every start position.
+func fuzzyScore(pattern, text string) (int, bool) {
+	p := []rune(strings.ToLower(pattern))
+	t := []rune(strings.ToLower(text))
+	if len(p) == 0 {
+		return 0, true
+	}
+
+	best, found := 0, false
+	for start := range t {
+		if t[start] != p[0] {
+			continue
+		}
+		score, pi, last := 0, 0, -1
+		for ti := start; ti < len(t) && pi < len(p); ti++ {
+			if t[ti] != p[pi] {
+				continue
+			}
+			score++
+			switch {
+			case ti == 0 || isWordBoundary(t[ti-1]):
+				score += 6
+			case last == ti-1:
+				score += 4
+			case last >= 0:
+				score--
+			}
+			last = ti
+			pi++
+		}
+		if pi == len(p) && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+	return best, found
+}
+
+func isWordBoundary(r rune) bool {
+	return r == '/' || r == '-' || r == '_' || r == '.' || unicode.IsSpace(r)
+}
+
+// recencyBonus favours recently modified items
+func recencyBonus(modTime, now time.Time) int {
+	switch age := now.Sub(modTime); {
+	case age < 24*time.Hour:
+		return 8
+	case age < 7*24*time.Hour:
+		return 5
+	case age < 30*24*time.Hour:
+		return 2
+	}
+	return 0
+}
+
+// rankItems returns the items matching every word of query, best first.
+// Each word is matched against the name and the title, and recently
+// modified items get a bonus. An empty query lists the most recent first.
+func rankItems(items []pickerItem, query string, now time.Time) []pickerItem {
+	type ranked struct {
+		item  pickerItem
+		score int
+	}
+
+	terms := strings.Fields(query)
+	var matches []ranked
+	for _, item := range items {
+		total, ok := 0, true
+		for _, term := range terms {
+			nameScore, nameOK := fuzzyScore(term, item.Name)
+			titleScore, titleOK := fuzzyScore(term, item.Title)
+			if !nameOK && !titleOK {
+				ok = false
+				break
+			}
+			total += max(nameScore, titleScore)
+		}
+		if ok {
+			matches = append(matches, ranked{item, total + recencyBonus(item.ModTime, now)})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if !a.item.ModTime.Equal(b.item.ModTime) {
+			return a.item.ModTime.After(b.item.ModTime)
+		}
+		return a.item.Name < b.item.Name
+	})
+
+	result := make([]pickerItem, len(matches))
+	for i, m := range matches {
+		result[i] = m.item
+	}
+	return result
+}
+
+type keyKind int
+
+const (
+	keyRune keyKind = iota
+	keyEnter
+	keyBackspace
+	keyClear
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyTab
+	keyCancel
+)
+
+// key is a key press decoded from terminal input
+type key struct {
+	kind keyKind
+	r    rune
+}
+
+// parseKeys decodes raw terminal input. Unknown escape sequences are
+// dropped; a lone Esc cancels.
+func parseKeys(b []byte) []key {
+	var keys []key
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			n := escapeLen(b)
+			switch string(b[:n]) {
+			case "\x1b":
+				keys = append(keys, key{kind: keyCancel})
+			case "\x1b[A", "\x1bOA":
+				keys = append(keys, key{kind: keyUp})
+			case "\x1b[B", "\x1bOB":
+				keys = append(keys, key{kind: keyDown})
+			case "\x1b[5~":
+				keys = append(keys, key{kind: keyPageUp})
+			case "\x1b[6~":
+				keys = append(keys, key{kind: keyPageDown})
+			}
+			b = b[n:]
+			continue
+		case c == '\r' || c == '\n':
+			keys = append(keys, key{kind: keyEnter})
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, key{kind: keyBackspace})
+		case c == 0x15:
+			keys = append(keys, key{kind: keyClear})
+		case c == 0x10:
+			keys = append(keys, key{kind: keyUp})
+		case c == 0x0e:
+			keys = append(keys, key{kind: keyDown})
+		case c == '\t':
+			keys = append(keys, key{kind: keyTab})
+		case c == 0x03 || c == 0x04:
+			keys = append(keys, key{kind: keyCancel})
+		case c < 0x20:
+		default:
+			r, size := utf8.DecodeRune(b)
+			keys = append(keys, key{kind: keyRune, r: r})
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// escapeLen returns the length of the escape sequence at the start of b
+func escapeLen(b []byte) int {
+	if len(b) < 2 {
+		return len(b)
+	}
+	switch b[1] {
+	case '[':
+		// CSI sequences end with a byte in 0x40-0x7e
+		for i := 2; i < len(b); i++ {
+			if b[i] >= 0x40 && b[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(b)
+	case 'O':
+		return min(3, len(b))
+	}
+	return 1
+}
+
+// picker holds the state of a picker session independent of the terminal
+type picker struct {
+	items    []pickerItem
+	opts     pickerOptions
+	now      time.Time
+	query    []rune
+	matches  []pickerItem
+	cursor   int
+	offset   int
+	selected map[string]bool
+	// order keeps selections in the order they were made
+	order    []pickerItem
+	previews map[string][]string
+}
+
+func newPicker(items []pickerItem, opts pickerOptions, now time.Time) *picker {
+	p := &picker{
+		items:    items,
+		opts:     opts,
+		now:      now,
+		selected: make(map[string]bool),
+		previews: make(map[string][]string),
+	}
+	p.filter()
+	return p
+}
+
+func (p *picker) filter() {
+	p.matches = rankItems(p.items, string(p.query), p.now)
+	p.cursor, p.offset = 0, 0
+}
+
+func (p *picker) move(delta int) {
+	p.cursor = max(0, min(len(p.matches)-1, p.cursor+delta))
+}
+
+// handle applies a key press. It reports whether the session is over.
+func (p *picker) handle(k key, pageSize int) (done bool, err error) {
+	switch k.kind {
+	case keyRune:
+		p.query = append(p.query, k.r)
+		p.filter()
+	case keyBackspace:
+		if len(p.query) > 0 {
+			p.query = p.query[:len(p.query)-1]
+			p.filter()
+		}
+	case keyClear:
+		p.query = nil
+		p.filter()
+	case keyUp:
+		p.move(-1)
+	case keyDown:
+		p.move(1)
+	case keyPageUp:
+		p.move(-pageSize)
+	case keyPageDown:
+		p.move(pageSize)
+	case keyTab:
+		if p.opts.Multi && len(p.matches) > 0 {
+			p.toggle(p.matches[p.cursor])
+			p.move(1)
+		}
+	case keyEnter:
+		return len(p.result()) > 0, nil
+	case keyCancel:
+		return true, errPickerCancelled
+	}
+	return false, nil
+}
+
+func (p *picker) toggle(item pickerItem) {
+	if p.selected[item.Name] {
+		delete(p.selected, item.Name)
+		for i, s := range p.order {
+			if s.Name == item.Name {
+				p.order = append(p.order[:i], p.order[i+1:]...)
+				break
+			}
+		}
+		return
+	}
+	p.selected[item.Name] = true
+	p.order = append(p.order, item)
+}
+
+// result returns the selected items, or the item under the cursor when
+// nothing was selected with Tab
+func (p *picker) result() []pickerItem {
+	if len(p.order) > 0 {
+		return p.order
+	}
+	if len(p.matches) == 0 {
+		return nil
+	}
+	return []pickerItem{p.matches[p.cursor]}
+}
+
+func (p *picker) preview(item pickerItem) []string {
+	if p.opts.Preview == nil {
+		return nil
+	}
+	lines, ok := p.previews[item.Path]
+	if !ok {
+		lines = p.opts.Preview(item)
+		p.previews[item.Path] = lines
+	}
+	return lines
+}
+
+// render draws the picker into lines of exactly width columns: the query,
+// a status line, then the list with the preview pane on its right.
+func (p *picker) render(width, height int) []string {
+	listHeight := max(1, height-2)
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	} else if p.cursor >= p.offset+listHeight {
+		p.offset = p.cursor - listHeight + 1
+	}
+
+	status := fmt.Sprintf("  %d/%d", len(p.matches), len(p.items))
+	if p.opts.Multi {
+		status += fmt.Sprintf(" (%d selected, Tab to select)", len(p.order))
+	}
+	lines := []string{
+		fitWidth(p.opts.Prompt+"> "+string(p.query), width),
+		fitWidth(status, width),
+	}
+
+	listWidth := width
+	var preview []string
+	if p.opts.Preview != nil && width >= 40 {
+		listWidth = width * 2 / 5
+		if len(p.matches) > 0 {
+			preview = p.preview(p.matches[p.cursor])
+		}
+	}
+
+	for row := 0; row < listHeight; row++ {
+		var line string
+		if i := p.offset + row; i < len(p.matches) {
+			item := p.matches[i]
+			marker := "  "
+			if p.selected[item.Name] {
+				marker = " *"
+			}
+			label := item.Name
+			if item.Title != "" && item.Title != pathBase(item.Name) {
+				label += "  " + item.Title
+			}
+			line = fitWidth(marker+label, listWidth)
+			if i == p.cursor {
+				line = "\x1b[7m" + line + "\x1b[0m"
+			}
+		} else {
+			line = strings.Repeat(" ", listWidth)
+		}
+
+		if listWidth < width {
+			text := ""
+			if row < len(preview) {
+				text = preview[row]
+			}
+			line += "│ " + fitWidth(text, width-listWidth-2)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func pathBase(name string) string {
+	return name[strings.LastIndex(name, "/")+1:]
+}
+
+// fitWidth truncates or pads s to exactly w terminal columns. East Asian
+// wide characters take two columns; control characters are dropped.
+func fitWidth(s string, w int) string {
+	var b strings.Builder
+	cols := 0
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			continue
+		}
+		rw := 1
+		if k := width.LookupRune(r).Kind(); k == width.EastAsianWide || k == width.EastAsianFullwidth {
+			rw = 2
+		}
+		if cols+rw > w {
+			break
+		}
+		b.WriteRune(r)
+		cols += rw
+	}
+	b.WriteString(strings.Repeat(" ", w-cols))
+	return b.String()
+}
+
+// runPicker runs a picker session on the terminal. Keys are read from stdin
+// in raw mode and the picker is drawn on stderr's alternate screen, so
+// stdout stays free for the command's output.
+func runPicker(items []pickerItem, opts pickerOptions) ([]pickerItem, error) {
+	in := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up terminal: %w", err)
+	}
+	defer term.Restore(in, state)
+
+	out := os.Stderr
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	p := newPicker(items, opts, time.Now())
+	buf := make([]byte, 256)
+	for {
+		w, h, err := term.GetSize(int(out.Fd()))
+		if err != nil || w <= 0 || h <= 0 {
+			w, h = 80, 24
+		}
+		fmt.Fprint(out, "\x1b[H"+strings.Join(p.render(w, h), "\r\n")+"\x1b[J")
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key: %w", err)
+		}
+		for _, k := range parseKeys(buf[:n]) {
+			done, err := p.handle(k, max(1, h-2))
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				return p.result(), nil
+			}
+		}
+	}
+}
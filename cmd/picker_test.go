@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	_, ok := fuzzyScore("pw", "work/plan")
+	assert.False(t, ok, "runes must appear in order")
+
+	_, ok = fuzzyScore("", "anything")
+	assert.True(t, ok)
+
+	boundary, ok := fuzzyScore("wp", "work/plan")
+	require.True(t, ok)
+	inner, ok := fuzzyScore("wp", "swamp")
+	require.True(t, ok)
+	assert.Greater(t, boundary, inner, "word starts should score higher")
+
+	consecutive, _ := fuzzyScore("pla", "plan")
+	scattered, _ := fuzzyScore("pla", "pxlxa")
+	assert.Greater(t, consecutive, scattered)
+
+	upper, ok := fuzzyScore("PLAN", "work/plan")
+	require.True(t, ok)
+	lower, _ := fuzzyScore("plan", "work/plan")
+	assert.Equal(t, lower, upper, "matching ignores case")
+}
+
+func TestRankItems(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	items := []pickerItem{
+		{Name: "archive/old-plan", Title: "Old plan", ModTime: now.AddDate(-1, 0, 0)},
+		{Name: "work/plan", Title: "Q4 roadmap", ModTime: now.AddDate(0, 0, -10)},
+		{Name: "journal/today", Title: "Planning the week", ModTime: now.Add(-time.Hour)},
+		{Name: "recipes/soup", Title: "Soup", ModTime: now.Add(-2 * time.Hour)},
+	}
+
+	names := func(items []pickerItem) []string {
+		var result []string
+		for _, item := range items {
+			result = append(result, item.Name)
+		}
+		return result
+	}
+
+	t.Run("empty query lists recent first", func(t *testing.T) {
+		assert.Equal(t, []string{"journal/today", "recipes/soup", "work/plan", "archive/old-plan"}, names(rankItems(items, "", now)))
+	})
+
+	t.Run("matches titles", func(t *testing.T) {
+		assert.Equal(t, []string{"work/plan"}, names(rankItems(items, "roadmap", now)))
+	})
+
+	t.Run("every word must match", func(t *testing.T) {
+		assert.Equal(t, []string{"work/plan"}, names(rankItems(items, "work plan", now)))
+	})
+
+	t.Run("recency breaks close matches", func(t *testing.T) {
+		ranked := names(rankItems(items, "plan", now))
+		assert.ElementsMatch(t, []string{"journal/today", "work/plan", "archive/old-plan"}, ranked)
+		assert.Equal(t, "archive/old-plan", ranked[len(ranked)-1])
+	})
+}
+
+func TestParseKeys(t *testing.T) {
+	keys := parseKeys([]byte("aé\x1b[A\x1b[B\x1bOA\t\r\x7f\x15\x1b[5~\x1b[1;5C"))
+	want := []key{
+		{kind: keyRune, r: 'a'},
+		{kind: keyRune, r: 'é'},
+		{kind: keyUp},
+		{kind: keyDown},
+		{kind: keyUp},
+		{kind: keyTab},
+		{kind: keyEnter},
+		{kind: keyBackspace},
+		{kind: keyClear},
+		{kind: keyPageUp},
+	}
+	assert.Equal(t, want, keys)
+
+	assert.Equal(t, []key{{kind: keyCancel}}, parseKeys([]byte("\x1b")))
+	assert.Equal(t, []key{{kind: keyCancel}}, parseKeys([]byte{0x03}))
+}
+
+func TestPickerSession(t *testing.T) {
+	now := time.Now()
+	items := []pickerItem{
+		{Name: "alpha", ModTime: now},
+		{Name: "beta", ModTime: now.Add(-time.Minute)},
+		{Name: "gamma", ModTime: now.Add(-2 * time.Minute)},
+	}
+
+	type step struct {
+		keys string
+		done bool
+		err  error
+	}
+	run := func(t *testing.T, p *picker, steps ...step) {
+		t.Helper()
+		for _, s := range steps {
+			var done bool
+			var err error
+			for _, k := range parseKeys([]byte(s.keys)) {
+				if done, err = p.handle(k, 10); done {
+					break
+				}
+			}
+			assert.Equal(t, s.done, done, "keys %q", s.keys)
+			assert.Equal(t, s.err, err, "keys %q", s.keys)
+		}
+	}
+
+	t.Run("enter picks the cursor", func(t *testing.T) {
+		p := newPicker(items, pickerOptions{}, now)
+		run(t, p, step{keys: "\x1b[B"}, step{keys: "\r", done: true})
+		assert.Equal(t, "beta", p.result()[0].Name)
+	})
+
+	t.Run("typing filters", func(t *testing.T) {
+		p := newPicker(items, pickerOptions{}, now)
+		run(t, p, step{keys: "gm"})
+		require.Len(t, p.matches, 1)
+		run(t, p, step{keys: "\x7f\x7f"})
+		assert.Len(t, p.matches, 3)
+	})
+
+	t.Run("enter without matches does nothing", func(t *testing.T) {
+		p := newPicker(items, pickerOptions{}, now)
+		run(t, p, step{keys: "zzz\r"})
+	})
+
+	t.Run("tab selects several", func(t *testing.T) {
+		p := newPicker(items, pickerOptions{Multi: true}, now)
+		run(t, p, step{keys: "\t\x1b[B\t\r", done: true})
+		var picked []string
+		for _, item := range p.result() {
+			picked = append(picked, item.Name)
+		}
+		assert.Equal(t, []string{"alpha", "gamma"}, picked)
+	})
+
+	t.Run("tab is ignored without multi-select", func(t *testing.T) {
+		p := newPicker(items, pickerOptions{}, now)
+		run(t, p, step{keys: "\t"})
+		assert.Empty(t, p.order)
+	})
+
+	t.Run("escape cancels", func(t *testing.T) {
+		p := newPicker(items, pickerOptions{}, now)
+		run(t, p, step{keys: "\x1b", done: true, err: errPickerCancelled})
+	})
+}
+
+func TestPickerRender(t *testing.T) {
+	now := time.Now()
+	items := []pickerItem{
+		{Name: "work/plan", Title: "Roadmap", ModTime: now, Path: "plan"},
+		{Name: "notes", Title: "notes", ModTime: now.Add(-time.Hour), Path: "notes"},
+	}
+	p := newPicker(items, pickerOptions{
+		Prompt: "edit",
+		Preview: func(item pickerItem) []string {
+			return []string{"preview of " + item.Path}
+		},
+	}, now)
+
+	lines := p.render(60, 5)
+	require.Len(t, lines, 5)
+	assert.Equal(t, "edit>", strings.TrimRight(lines[0], " "))
+	assert.Contains(t, lines[1], "2/2")
+	assert.Contains(t, lines[2], "work/plan  Roadmap")
+	assert.Contains(t, lines[2], "│ preview of plan")
+	assert.Contains(t, lines[3], "notes")
+	assert.NotContains(t, lines[3], "notes  notes", "titles equal to the name are not repeated")
+	for _, line := range lines {
+		plain := strings.NewReplacer("\x1b[7m", "", "\x1b[0m", "").Replace(line)
+		assert.Equal(t, 60, len([]rune(plain)), "line %q", plain)
+	}
+}
+
+func TestFitWidth(t *testing.T) {
+	assert.Equal(t, "abc  ", fitWidth("abc", 5))
+	assert.Equal(t, "abcde", fitWidth("abcdefgh", 5))
+	assert.Equal(t, "筆記 ", fitWidth("筆記本", 5), "wide runes take two columns")
+	assert.Equal(t, "ab", fitWidth("a\x1bb", 2))
+}
+
+// stubPicker answers picker sessions with the items named in picks
+func stubPicker(t *testing.T, picks ...string) *[]pickerItem {
+	t.Helper()
+
+	var offered []pickerItem
+	oldPickItems, oldIsTerminal := pickItems, stdinIsTerminal
+	pickItems = func(items []pickerItem, opts pickerOptions) ([]pickerItem, error) {
+		offered = items
+		if len(picks) == 0 {
+			return nil, errPickerCancelled
+		}
+		var picked []pickerItem
+		for _, item := range items {
+			for _, name := range picks {
+				if item.Name == name {
+					picked = append(picked, item)
+				}
+			}
+		}
+		return picked, nil
+	}
+	stdinIsTerminal = func() bool { return true }
+	t.Cleanup(func() {
+		pickItems = oldPickItems
+		stdinIsTerminal = oldIsTerminal
+	})
+	return &offered
+}
+
+func TestPickerCommands(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	stubOpeners(t)
+
+	for _, name := range []string{"a.md", "b.md", "work/c.md"} {
+		path := filepath.Join(tmpDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("# "+name+"\n"), 0644))
+	}
+	imagePath := filepath.Join(tmpDir, "work", "._images_", "chart.png")
+	require.NoError(t, os.MkdirAll(filepath.Dir(imagePath), 0755))
+	require.NoError(t, os.WriteFile(imagePath, []byte("png"), 0644))
+
+	t.Run("delete picks several notes", func(t *testing.T) {
+		offered := stubPicker(t, "a", "work/c")
+		silent = true
+		defer func() { silent = false }()
+
+		require.NoError(t, runDelete(deleteCmd, nil))
+		assert.Len(t, *offered, 3)
+		assert.NoFileExists(t, filepath.Join(tmpDir, "a.md"))
+		assert.NoFileExists(t, filepath.Join(tmpDir, "work", "c.md"))
+		assert.FileExists(t, filepath.Join(tmpDir, "b.md"))
+	})
+
+	t.Run("cancelled picker does nothing", func(t *testing.T) {
+		stubPicker(t)
+		require.NoError(t, runDelete(deleteCmd, nil))
+		assert.FileExists(t, filepath.Join(tmpDir, "b.md"))
+	})
+
+	t.Run("image show offers images of all folders", func(t *testing.T) {
+		offered := stubPicker(t, "work/chart.png")
+		require.NoError(t, runImageShow(imageShowCmd, nil))
+		require.Len(t, *offered, 1)
+		assert.Equal(t, imagePath, (*offered)[0].Path)
+	})
+
+	t.Run("without a terminal a name is required", func(t *testing.T) {
+		err := runEdit(editCmd, nil)
+		require.Error(t, err)
+		assert.Equal(t, codeUsage, errorCode(err))
+	})
+}
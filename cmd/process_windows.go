@@ -0,0 +1,14 @@
+package cmd
+
+import "os"
+
+// processAlive reports whether a process with pid exists. On Windows
+// finding a process opens it, which fails once it has exited.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
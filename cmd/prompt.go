@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// Set by the global --yes and --no-input flags
+var (
+	assumeYes bool
+	noInput   bool
+)
+
+// errNoInput is returned by confirm when a question would need an answer
+// but ned is not allowed to, or cannot, ask for one
+var errNoInput = errors.New("input required but prompts are disabled")
+
+// Prompter asks the user yes/no questions
+type Prompter interface {
+	Confirm(question string) (bool, error)
+}
+
+// prompter answers the questions asked through confirm. Tests replace it
+// with scripted answers.
+var prompter Prompter = terminalPrompter{}
+
+// terminalPrompter reads answers from stdin when it is a terminal
+type terminalPrompter struct{}
+
+func (terminalPrompter) Confirm(question string) (bool, error) {
+	if !stdinIsTerminal() {
+		return false, errNoInput
+	}
+
+	fmt.Fprintf(promptOutput(), "%s [y/N]: ", question)
+	response, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("error reading response: %w", err)
+	}
+	response = strings.ToLower(strings.TrimSpace(response))
+	return response == "y" || response == "yes", nil
+}
+
+// stdinIsTerminal reports whether stdin is attached to a terminal
+var stdinIsTerminal = func() bool {
+	fd := os.Stdin.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// interactive reports whether ned may wait for the user at the terminal
+func interactive() bool {
+	return !noInput && stdinIsTerminal()
+}
+
+// confirm asks a yes/no question. --yes answers every question with yes.
+// With --no-input, or when stdin is not a terminal, it returns errNoInput
+// and the caller decides what an unanswered question means.
+func confirm(question string) (bool, error) {
+	if assumeYes {
+		return true, nil
+	}
+	if noInput {
+		return false, errNoInput
+	}
+	return prompter.Confirm(question)
+}
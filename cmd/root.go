@@ -12,16 +12,46 @@ var rootCmd = &cobra.Command{
 	Short: "A CLI note-taking application",
 	Long: `ned is a command line note-taking application that allows you to
 create, list, and edit notes in markdown format. All notes are stored in
-$HOME/.mynotes directory.`,
-	Aliases: []string{"e", "n", "l", "d", "v", "h"},
+$HOME/.mynotes directory.
+
+ned only asks questions when stdin is a terminal. Use --yes to answer yes
+to every question, or --no-input to fail instead of asking, for scripts
+and cron jobs.
+
+With --output json or --output yaml, commands print structured results on
+stdout and errors as {"error": {"code": ..., "message": ...}} on stderr.
+Exit codes:
+  0  success
+  1  error
+  2  usage        invalid arguments or flags
+  3  not_found    note, folder or image does not exist
+  4  already_exists
+  5  invalid_path path outside the notes directory
+  6  fetch_failed a download failed
+  7  config       configuration could not be read or is invalid`,
+	Aliases:           []string{"e", "n", "l", "d", "v", "h"},
+	SilenceErrors:     true,
+	PersistentPreRunE: validateOutputFormat,
 }
 
 // notesDir is the directory where all notes are stored
 var notesDir string
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// Errors are reported before returning; use ExitCode to pick the exit status.
 func Execute() error {
-	return rootCmd.Execute()
+	tagUsageErrors(rootCmd)
+	// Flag errors are reported before --output is parsed, so look for it
+	// early to keep usage text out of structured output
+	outputFormat = outputFormatFromArgs(os.Args[1:])
+	if structuredOutput() {
+		rootCmd.SilenceUsage = true
+	}
+	err := rootCmd.Execute()
+	if err != nil {
+		printError(err)
+	}
+	return err
 }
 
 func init() {
@@ -35,4 +65,11 @@ func init() {
 	if err := os.MkdirAll(notesDir, 0755); err != nil {
 		panic(err)
 	}
+
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json or yaml")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Answer yes to all questions")
+	rootCmd.PersistentFlags().BoolVar(&noInput, "no-input", false, "Never ask questions; fail when an answer is needed")
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &cmdError{code: codeUsage, err: err}
+	})
 }
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search the text of all notes",
+	Long: `Search the titles and text of all notes. The search is case-insensitive and a
+note matches when it contains every word of the query. Matching lines are
+shown with their line numbers.
+
+Example:
+  ned search binary tree`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSearch,
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+}
+
+// searchMatch is a line of a note that contains at least one query term
+type searchMatch struct {
+	Line int
+	Text string
+}
+
+// searchResult is a note that contains every query term
+type searchResult struct {
+	Note    string
+	Title   string
+	Matches []searchMatch
+}
+
+// searchNotes runs a case-insensitive full-text search over all notes,
+// reading each note once. Notes whose title matches come first, then notes
+// with more matching lines.
+func searchNotes(query string) ([]searchResult, error) {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	var results []searchResult
+	titleHits := make(map[string]bool)
+	err := walkNoteFiles(func(note noteInfo, content []byte) error {
+		haystack := strings.ToLower(note.Name + "\n" + note.Title + "\n" + string(content))
+		if !containsAll(haystack, terms) {
+			return nil
+		}
+
+		result := searchResult{Note: note.Name, Title: note.Title}
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for lineNum := 1; scanner.Scan(); lineNum++ {
+			line := scanner.Text()
+			lower := strings.ToLower(line)
+			for _, term := range terms {
+				if strings.Contains(lower, term) {
+					result.Matches = append(result.Matches, searchMatch{Line: lineNum, Text: strings.TrimSpace(line)})
+					break
+				}
+			}
+		}
+		titleHits[note.Name] = containsAll(strings.ToLower(note.Title), terms)
+		results = append(results, result)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan notes: %w", err)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if titleHits[results[i].Note] != titleHits[results[j].Note] {
+			return titleHits[results[i].Note]
+		}
+		return len(results[i].Matches) > len(results[j].Matches)
+	})
+	return results, nil
+}
+
+// searchHit is the structured output for a matching line. Notes that only
+// match by name or title have one entry without a line.
+type searchHit struct {
+	Note  string `json:"note" yaml:"note"`
+	Title string `json:"title" yaml:"title"`
+	Line  int    `json:"line,omitempty" yaml:"line,omitempty"`
+	Text  string `json:"text,omitempty" yaml:"text,omitempty"`
+}
+
+func containsAll(s string, terms []string) bool {
+	for _, term := range terms {
+		if !strings.Contains(s, term) {
+			return false
+		}
+	}
+	return true
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	query := strings.Join(args, " ")
+	results, err := searchNotes(query)
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		lines := []searchHit{}
+		for _, result := range results {
+			if len(result.Matches) == 0 {
+				lines = append(lines, searchHit{Note: result.Note, Title: result.Title})
+			}
+			for _, match := range result.Matches {
+				lines = append(lines, searchHit{Note: result.Note, Title: result.Title, Line: match.Line, Text: match.Text})
+			}
+		}
+		return printResult(lines)
+	}
+
+	if len(results) == 0 {
+		fmt.Printf("No notes match '%s'\n", query)
+		return nil
+	}
+
+	for _, result := range results {
+		fmt.Printf("%s (%s)\n", result.Note, result.Title)
+		for _, match := range result.Matches {
+			fmt.Printf("  %d: %s\n", match.Line, match.Text)
+		}
+	}
+	return nil
+}
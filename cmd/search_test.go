@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSearchNotes(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	files := map[string]string{
+		"algo/sorting.md": "# Sorting\n\nQuicksort is fast.\nMergesort is stable.\n",
+		"trees.md":        "# Binary Trees\n\nA binary tree can be sorted.\n",
+		"other.md":        "# Other\n\nnothing here\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantNotes []string
+		wantLines map[string][]int
+	}{
+		{
+			name:      "title match ranks first",
+			query:     "sort",
+			wantNotes: []string{"algo/sorting", "trees"},
+			wantLines: map[string][]int{"algo/sorting": {1, 3, 4}, "trees": {3}},
+		},
+		{
+			name:      "all terms must match",
+			query:     "BINARY sorted",
+			wantNotes: []string{"trees"},
+			wantLines: map[string][]int{"trees": {1, 3}},
+		},
+		{
+			name:  "no match",
+			query: "missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := searchNotes(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(results) != len(tt.wantNotes) {
+				t.Fatalf("got %d results, want %d: %+v", len(results), len(tt.wantNotes), results)
+			}
+			for i, note := range tt.wantNotes {
+				if results[i].Note != note {
+					t.Errorf("result %d = %s, want %s", i, results[i].Note, note)
+				}
+				var lines []int
+				for _, m := range results[i].Matches {
+					lines = append(lines, m.Line)
+				}
+				if len(lines) != len(tt.wantLines[note]) {
+					t.Errorf("%s matched lines %v, want %v", note, lines, tt.wantLines[note])
+					continue
+				}
+				for j := range lines {
+					if lines[j] != tt.wantLines[note][j] {
+						t.Errorf("%s matched lines %v, want %v", note, lines, tt.wantLines[note])
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRunSearchOutput(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	setOutputFormat(t, "json")
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "trees.md"), []byte("# Binary Trees\n\nA binary tree.\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "binary.md"), []byte("nothing else\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	out, err := captureStdout(t, func() error { return runSearch(searchCmd, []string{"binary"}) })
+	if err != nil {
+		t.Fatalf("runSearch() error = %v", err)
+	}
+	var lines []searchHit
+	if err := json.Unmarshal([]byte(out), &lines); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	want := []searchHit{
+		{Note: "trees", Title: "Binary Trees", Line: 1, Text: "# Binary Trees"},
+		{Note: "trees", Title: "Binary Trees", Line: 3, Text: "A binary tree."},
+		{Note: "binary", Title: "binary"},
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("got %+v, want %+v", lines, want)
+	}
+
+	out, err = captureStdout(t, func() error { return runSearch(searchCmd, []string{"missing"}) })
+	if err != nil || out != "[]\n" {
+		t.Errorf("no match = %q, %v, want an empty array", out, err)
+	}
+}
@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	tasksAll    bool
+	tasksPerson string
+)
+
+var tasksCmd = &cobra.Command{
+	Use:   "tasks",
+	Short: "List open tasks from all notes",
+	Long: `Collect "- [ ]" checkboxes from all notes and list the open ones with their
+note, line and heading. Tasks can carry annotations in their text:
+
+  due:2026-10-20   due date
+  @person          person the task is assigned to
+  !high            priority: !high, !medium, !low or !1, !2, !3
+
+Tasks with a due date come first, earliest first, then by priority.
+Use "ned tasks done <id>" to tick a task off.`,
+	Args: cobra.NoArgs,
+	RunE: runTasks,
+}
+
+var tasksDoneCmd = &cobra.Command{
+	Use:   "done [id]",
+	Short: "Mark a task as done",
+	Long:  `Tick the checkbox of a task in its note. The id is shown by "ned tasks".`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTasksDone,
+}
+
+func init() {
+	tasksCmd.Flags().BoolVarP(&tasksAll, "all", "a", false, "Include completed tasks")
+	tasksCmd.Flags().StringVarP(&tasksPerson, "person", "p", "", "Only show tasks assigned to this @person")
+	tasksCmd.AddCommand(tasksDoneCmd)
+	rootCmd.AddCommand(tasksCmd)
+}
+
+// taskResult is the structured output for a task
+type taskResult struct {
+	ID       string   `json:"id" yaml:"id"`
+	Note     string   `json:"note" yaml:"note"`
+	Line     int      `json:"line" yaml:"line"`
+	Heading  string   `json:"heading,omitempty" yaml:"heading,omitempty"`
+	Text     string   `json:"text" yaml:"text"`
+	Done     bool     `json:"done" yaml:"done"`
+	Due      string   `json:"due,omitempty" yaml:"due,omitempty"`
+	People   []string `json:"people,omitempty" yaml:"people,omitempty"`
+	Priority string   `json:"priority,omitempty" yaml:"priority,omitempty"`
+}
+
+// result converts a task to its structured output
+func (t task) result() taskResult {
+	r := taskResult{
+		ID:       t.ID,
+		Note:     t.Note,
+		Line:     t.Line,
+		Heading:  t.Heading,
+		Text:     t.Text,
+		Done:     t.Done,
+		People:   t.People,
+		Priority: t.PriorityName(),
+	}
+	if t.HasDue() {
+		r.Due = t.Due.Format("2006-01-02")
+	}
+	return r
+}
+
+// task is a markdown checkbox found in a note
+type task struct {
+	ID       string
+	Note     string
+	Line     int
+	Heading  string
+	Text     string
+	Done     bool
+	Due      time.Time
+	People   []string
+	Priority int
+}
+
+var (
+	taskRe      = regexp.MustCompile(`^\s*[-*+] \[([ xX])\] (.*)$`)
+	headingRe   = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*\s*$`)
+	dueRe       = regexp.MustCompile(`(?:^|\s)due:(\d{4}-\d{2}-\d{2})\b`)
+	personRe    = regexp.MustCompile(`(?:^|\s)@([\w.-]*\w)`)
+	priorityRe  = regexp.MustCompile(`(?:^|\s)!(high|medium|low|[123])\b`)
+	priorityMap = map[string]int{"high": 1, "1": 1, "medium": 2, "2": 2, "low": 3, "3": 3}
+)
+
+// priorityNames maps a task priority back to its annotation
+var priorityNames = map[int]string{1: "high", 2: "medium", 3: "low"}
+
+// HasDue reports whether the task has a due date
+func (t task) HasDue() bool {
+	return !t.Due.IsZero()
+}
+
+// Overdue reports whether an open task's due date has passed
+func (t task) Overdue() bool {
+	return !t.Done && t.HasDue() && t.Due.Before(today())
+}
+
+// PriorityName returns the priority annotation without the "!"
+func (t task) PriorityName() string {
+	return priorityNames[t.Priority]
+}
+
+func today() time.Time {
+	y, m, d := time.Now().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// parseTasks extracts the checkboxes from a note's content. Task ids are
+// derived from the note name and task text, so they survive edits elsewhere
+// in the note and ticking the box.
+func parseTasks(note string, content []byte) []task {
+	var tasks []task
+	seen := make(map[string]int)
+	heading := ""
+	inFence := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if m := headingRe.FindStringSubmatch(line); m != nil {
+			heading = m[1]
+			continue
+		}
+
+		m := taskRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		text := strings.TrimSpace(m[2])
+		t := task{
+			Note:    note,
+			Line:    lineNum,
+			Heading: heading,
+			Text:    text,
+			Done:    m[1] != " ",
+		}
+		if due := dueRe.FindStringSubmatch(text); due != nil {
+			if d, err := time.ParseInLocation("2006-01-02", due[1], time.Local); err == nil {
+				t.Due = d
+			}
+		}
+		for _, person := range personRe.FindAllStringSubmatch(text, -1) {
+			t.People = append(t.People, person[1])
+		}
+		if p := priorityRe.FindStringSubmatch(text); p != nil {
+			t.Priority = priorityMap[p[1]]
+		}
+
+		key := note + "\n" + text
+		sum := sha1.Sum([]byte(fmt.Sprintf("%s\n%d", key, seen[key])))
+		seen[key]++
+		t.ID = hex.EncodeToString(sum[:])[:6]
+
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+// collectTasks gathers the tasks of all notes, reading each note once
+func collectTasks() ([]task, error) {
+	var tasks []task
+	err := walkNoteFiles(func(note noteInfo, content []byte) error {
+		tasks = append(tasks, parseTasks(note.Name, content)...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan notes: %w", err)
+	}
+	return tasks, nil
+}
+
+// sortTasks orders tasks by due date, then priority, then position
+func sortTasks(tasks []task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if a.HasDue() != b.HasDue() {
+			return a.HasDue()
+		}
+		if !a.Due.Equal(b.Due) {
+			return a.Due.Before(b.Due)
+		}
+		if a.Priority != b.Priority {
+			return a.Priority != 0 && (b.Priority == 0 || a.Priority < b.Priority)
+		}
+		if a.Note != b.Note {
+			return a.Note < b.Note
+		}
+		return a.Line < b.Line
+	})
+}
+
+// openTasks returns the sorted open tasks, optionally assigned to person
+func openTasks(all bool, person string) ([]task, error) {
+	tasks, err := collectTasks()
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []task
+	for _, t := range tasks {
+		if t.Done && !all {
+			continue
+		}
+		if person != "" && !hasPerson(t, strings.TrimPrefix(person, "@")) {
+			continue
+		}
+		filtered = append(filtered, t)
+	}
+	sortTasks(filtered)
+	return filtered, nil
+}
+
+func hasPerson(t task, person string) bool {
+	for _, p := range t.People {
+		if strings.EqualFold(p, person) {
+			return true
+		}
+	}
+	return false
+}
+
+func runTasks(cmd *cobra.Command, args []string) error {
+	tasks, err := openTasks(tasksAll, tasksPerson)
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		results := make([]taskResult, 0, len(tasks))
+		for _, t := range tasks {
+			results = append(results, t.result())
+		}
+		return printResult(results)
+	}
+
+	if len(tasks) == 0 {
+		fmt.Println("No open tasks")
+		return nil
+	}
+
+	for _, t := range tasks {
+		box := "[ ]"
+		if t.Done {
+			box = "[x]"
+		}
+		overdue := ""
+		if t.Overdue() {
+			overdue = " (overdue)"
+		}
+		fmt.Printf("%s %s  %s%s\n", box, t.ID, t.Text, overdue)
+
+		location := fmt.Sprintf("%s:%d", t.Note, t.Line)
+		if t.Heading != "" {
+			location += " › " + t.Heading
+		}
+		fmt.Printf("           %s\n", location)
+	}
+	return nil
+}
+
+func runTasksDone(cmd *cobra.Command, args []string) error {
+	id := args[0]
+
+	tasks, err := collectTasks()
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tasks {
+		if t.ID != id {
+			continue
+		}
+		if t.Done {
+			return fmt.Errorf("task %s is already done", id)
+		}
+		if err := completeTask(t); err != nil {
+			return err
+		}
+		if structuredOutput() {
+			t.Done = true
+			return printResult(t.result())
+		}
+		fmt.Printf("Completed task: %s (%s:%d)\n", t.Text, t.Note, t.Line)
+		return nil
+	}
+	return newCmdError(codeNotFound, "task not found: %s", id)
+}
+
+// completeTask ticks the checkbox on the task's line, leaving the rest of
+// the note untouched
+func completeTask(t task) error {
+	notePath := noteFilePath(t.Note)
+	lock, err := lockNote(notePath, "tasks")
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	content, err := os.ReadFile(notePath)
+	if err != nil {
+		return fmt.Errorf("failed to read note: %w", err)
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	if t.Line > len(lines) {
+		return fmt.Errorf("task %s is no longer at %s:%d", t.ID, t.Note, t.Line)
+	}
+	line := lines[t.Line-1]
+	box := strings.Index(line, "[ ]")
+	if m := taskRe.FindStringSubmatch(strings.TrimRight(line, "\r\n")); m == nil || box < 0 || strings.TrimSpace(m[2]) != t.Text {
+		return fmt.Errorf("task %s is no longer at %s:%d", t.ID, t.Note, t.Line)
+	}
+	lines[t.Line-1] = line[:box] + "[x]" + line[box+3:]
+
+	if err := writeFileAtomic(notePath, []byte(strings.Join(lines, "")), filePerm(notePath, 0644), true); err != nil {
+		return fmt.Errorf("failed to write note: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTasks(t *testing.T) {
+	content := `# Project
+
+- [ ] Write report due:2026-10-20 @alice !high
+- [x] Book room @bob
+
+## Later
+
+* [ ] Refactor parser !low
+- [ ] Email me@example.com
+` + "```" + `
+- [ ] not a task
+` + "```" + `
+- [ ] Write report due:2026-10-20 @alice !high
+`
+	tasks := parseTasks("work/plan", []byte(content))
+	if len(tasks) != 5 {
+		t.Fatalf("got %d tasks, want 5: %+v", len(tasks), tasks)
+	}
+
+	first := tasks[0]
+	if first.Line != 3 || first.Heading != "Project" || first.Done {
+		t.Errorf("unexpected first task: %+v", first)
+	}
+	if want := time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local); !first.Due.Equal(want) {
+		t.Errorf("due = %v, want %v", first.Due, want)
+	}
+	if len(first.People) != 1 || first.People[0] != "alice" || first.Priority != 1 {
+		t.Errorf("unexpected annotations: %+v", first)
+	}
+
+	if !tasks[1].Done {
+		t.Error("expected second task to be done")
+	}
+	if tasks[2].Heading != "Later" || tasks[2].Priority != 3 {
+		t.Errorf("unexpected third task: %+v", tasks[2])
+	}
+	if len(tasks[3].People) != 0 {
+		t.Errorf("email address should not be a person: %+v", tasks[3].People)
+	}
+
+	// Duplicated tasks get distinct ids
+	if tasks[4].ID == first.ID {
+		t.Error("duplicated tasks should have distinct ids")
+	}
+
+	// Ids are stable when the box is ticked
+	ticked := parseTasks("work/plan", []byte("- [x] Write report due:2026-10-20 @alice !high\n"))
+	if ticked[0].ID != first.ID {
+		t.Errorf("id changed after ticking: %s != %s", ticked[0].ID, first.ID)
+	}
+}
+
+func TestSortTasks(t *testing.T) {
+	tasks := []task{
+		{Text: "none", Note: "a", Line: 1},
+		{Text: "low", Note: "a", Line: 2, Priority: 3},
+		{Text: "later", Due: time.Date(2026, 12, 1, 0, 0, 0, 0, time.Local)},
+		{Text: "high", Note: "b", Line: 1, Priority: 1},
+		{Text: "soon", Due: time.Date(2026, 11, 1, 0, 0, 0, 0, time.Local)},
+	}
+	sortTasks(tasks)
+
+	want := []string{"soon", "later", "high", "low", "none"}
+	for i, text := range want {
+		if tasks[i].Text != text {
+			t.Errorf("task %d = %s, want %s", i, tasks[i].Text, text)
+		}
+	}
+}
+
+func TestTasksDoneCmd(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	notePath := filepath.Join(tmpDir, "todo.md")
+	original := "# Todo\n\n- [ ] First\n- [ ] Second @carol\n"
+	if err := os.WriteFile(notePath, []byte(original), 0644); err != nil {
+		t.Fatalf("failed to write note: %v", err)
+	}
+
+	tasks, err := openTasks(false, "carol")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].Text != "Second @carol" {
+		t.Fatalf("unexpected tasks: %+v", tasks)
+	}
+
+	if err := runTasksDone(tasksDoneCmd, []string{tasks[0].ID}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(notePath)
+	if err != nil {
+		t.Fatalf("failed to read note: %v", err)
+	}
+	if want := "# Todo\n\n- [ ] First\n- [x] Second @carol\n"; string(content) != want {
+		t.Errorf("content mismatch\nwant: %q\ngot:  %q", want, string(content))
+	}
+
+	if err := runTasksDone(tasksDoneCmd, []string{tasks[0].ID}); err == nil {
+		t.Error("expected error for completed task")
+	}
+	if err := runTasksDone(tasksDoneCmd, []string{"ffffff"}); err == nil {
+		t.Error("expected error for unknown task")
+	}
+}
+
+func TestTasksPage(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "todo.md"), []byte("# Inbox\n- [ ] Call <dentist> due:2000-01-01\n- [x] Done already\n"), 0644); err != nil {
+		t.Fatalf("failed to write note: %v", err)
+	}
+
+	r, err := setupServer("")
+	if err != nil {
+		t.Fatalf("Failed to setup server: %v", err)
+	}
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/tasks")
+	if err != nil {
+		t.Fatalf("Failed to get tasks page: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	htmlContent := string(body)
+
+	for _, want := range []string{"Call &lt;dentist&gt;", `href="/notes/todo"`, "todo:2", "› Inbox", "overdue"} {
+		if !strings.Contains(htmlContent, want) {
+			t.Errorf("Expected tasks page to contain %q", want)
+		}
+	}
+	if strings.Contains(htmlContent, "Done already") {
+		t.Error("Expected completed task to be hidden")
+	}
+}
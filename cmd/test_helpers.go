@@ -2,7 +2,10 @@ package cmd
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/gin-gonic/gin"
 )
 
 // setupTestEnv creates a temporary directory for testing and updates notesDir
@@ -39,3 +42,63 @@ func assertFileExists(t *testing.T, path string) {
 		t.Errorf("file does not exist: %s", path)
 	}
 }
+
+// setupTestConfig points the config file at a temporary home directory and
+// writes the given values to it
+func setupTestConfig(t *testing.T, values map[string]string) string {
+	t.Helper()
+
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+
+	if err := saveConfig(&Config{Values: values}); err != nil {
+		t.Fatalf("failed to save test config: %v", err)
+	}
+
+	return filepath.Join(homeDir, ".config", "ned")
+}
+
+// scriptedPrompter answers questions from a fixed list and records them
+type scriptedPrompter struct {
+	answers   []bool
+	questions []string
+}
+
+func (p *scriptedPrompter) Confirm(question string) (bool, error) {
+	p.questions = append(p.questions, question)
+	if len(p.answers) == 0 {
+		return false, errNoInput
+	}
+	answer := p.answers[0]
+	p.answers = p.answers[1:]
+	return answer, nil
+}
+
+// setupTestPrompter makes ned believe it runs at a terminal and answers its
+// questions from answers
+func setupTestPrompter(t *testing.T, answers ...bool) *scriptedPrompter {
+	t.Helper()
+
+	p := &scriptedPrompter{answers: answers}
+	oldPrompter, oldIsTerminal := prompter, stdinIsTerminal
+	prompter = p
+	stdinIsTerminal = func() bool { return true }
+	t.Cleanup(func() {
+		prompter = oldPrompter
+		stdinIsTerminal = oldIsTerminal
+	})
+	return p
+}
+
+// stubOpeners keeps view and image show from starting a server or viewer
+func stubOpeners(t *testing.T) {
+	t.Helper()
+
+	oldServeView, oldOpenImage := serveView, openImage
+	serveView = func(r *gin.Engine, url string, noteName string) error { return nil }
+	openImage = func(fullPath string) error { return nil }
+	t.Cleanup(func() {
+		serveView = oldServeView
+		openImage = oldOpenImage
+	})
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// pageTheme holds the parsed page templates together with the stylesheet
+// and color scheme shared by every page ned renders.
+type pageTheme struct {
+	templates *template.Template
+	mode      string
+	css       template.CSS
+}
+
+// page carries the theme settings every template receives
+type page struct {
+	Theme string
+	CSS   template.CSS
+}
+
+// notePage is the data passed to note.html
+type notePage struct {
+	page
+	Title   string
+	Content template.HTML
+	// KaTeX is the URL of the directory KaTeX's files are loaded from
+	KaTeX string
+}
+
+// welcomePage is the data passed to welcome.html
+type welcomePage struct {
+	page
+	Tree   *noteFolder
+	Recent []noteInfo
+}
+
+// searchPage is the data passed to search.html
+type searchPage struct {
+	page
+	Query   string
+	Results []searchResult
+}
+
+// tasksPage is the data passed to tasks.html
+type tasksPage struct {
+	page
+	Tasks []task
+}
+
+// loadTheme parses the built-in templates and applies the user's overrides.
+// VIEW_THEME selects auto, light or dark; VIEW_THEME_DIR points at a directory,
+// absolute or relative to ~/.config/ned, whose templates replace the
+// built-in ones and whose style.css is appended to the built-in stylesheet.
+func loadTheme() (*pageTheme, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, newCmdError(codeConfig, "failed to load config: %w", err)
+	}
+
+	theme := &pageTheme{}
+	switch mode := config.Values["VIEW_THEME"]; mode {
+	case "", "auto":
+	case "light", "dark":
+		theme.mode = mode
+	default:
+		return nil, newCmdError(codeConfig, "invalid VIEW_THEME %q: must be auto, light or dark", mode)
+	}
+
+	theme.templates, err = template.ParseFS(templateFS, "templates/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse built-in templates: %w", err)
+	}
+
+	css, err := templateFS.ReadFile("templates/style.css")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read built-in stylesheet: %w", err)
+	}
+
+	if dir := config.Values["VIEW_THEME_DIR"]; dir != "" {
+		if !filepath.IsAbs(dir) {
+			configPath, err := getConfigPath()
+			if err != nil {
+				return nil, err
+			}
+			dir = filepath.Join(filepath.Dir(configPath), dir)
+		}
+
+		if _, err := os.Stat(dir); err != nil {
+			return nil, newCmdError(codeConfig, "theme directory not found: %s", dir)
+		}
+
+		overrides, err := filepath.Glob(filepath.Join(dir, "*.html"))
+		if err != nil {
+			return nil, err
+		}
+		if len(overrides) > 0 {
+			if theme.templates, err = theme.templates.ParseFiles(overrides...); err != nil {
+				return nil, fmt.Errorf("failed to parse theme templates: %w", err)
+			}
+		}
+
+		userCSS, err := os.ReadFile(filepath.Join(dir, "style.css"))
+		if err == nil {
+			css = append(append(css, '\n'), userCSS...)
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read theme stylesheet: %w", err)
+		}
+	}
+
+	// The stylesheet comes from the binary or the user's own config directory
+	theme.css = template.CSS(css)
+	return theme, nil
+}
+
+// page returns the theme settings to embed in a template's data
+func (t *pageTheme) page() page {
+	return page{Theme: t.mode, CSS: t.css}
+}
+
+// render executes the named template
+func (t *pageTheme) render(w io.Writer, name string, data any) error {
+	return t.templates.ExecuteTemplate(w, name, data)
+}
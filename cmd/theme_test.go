@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTheme(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   map[string]string
+		files    map[string]string
+		page     string
+		contains []string
+		excludes []string
+		wantErr  bool
+	}{
+		{
+			name:     "default follows color scheme",
+			page:     "note.html",
+			contains: []string{"prefers-color-scheme: dark", "<p>body</p>"},
+			excludes: []string{"<html data-theme="},
+		},
+		{
+			name:     "forced dark theme",
+			values:   map[string]string{"VIEW_THEME": "dark"},
+			page:     "welcome.html",
+			contains: []string{`<html data-theme="dark">`},
+		},
+		{
+			name:    "invalid theme",
+			values:  map[string]string{"VIEW_THEME": "sepia"},
+			wantErr: true,
+		},
+		{
+			name:    "missing theme directory",
+			values:  map[string]string{"VIEW_THEME_DIR": "missing"},
+			wantErr: true,
+		},
+		{
+			name:   "user stylesheet is appended",
+			values: map[string]string{"VIEW_THEME_DIR": "mytheme"},
+			files: map[string]string{
+				"mytheme/style.css": "body { font-family: serif; }",
+			},
+			page:     "note.html",
+			contains: []string{"prefers-color-scheme", "body { font-family: serif; }"},
+		},
+		{
+			name:   "template override",
+			values: map[string]string{"VIEW_THEME_DIR": "mytheme"},
+			files: map[string]string{
+				"mytheme/note.html": `<main>{{.Title}}: {{.Content}}</main>`,
+			},
+			page:     "note.html",
+			contains: []string{"<main>Test &amp; Title: <p>body</p></main>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configDir := setupTestConfig(t, tt.values)
+			for name, content := range tt.files {
+				path := filepath.Join(configDir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("failed to create directory: %v", err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write %s: %v", name, err)
+				}
+			}
+
+			theme, err := loadTheme()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var data any = notePage{page: theme.page(), Title: "Test & Title", Content: "<p>body</p>"}
+			if tt.page == "welcome.html" {
+				data = welcomePage{page: theme.page(), Tree: &noteFolder{}}
+			}
+
+			var buf bytes.Buffer
+			if err := theme.render(&buf, tt.page, data); err != nil {
+				t.Fatalf("render() error = %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("output missing %q\ngot: %s", want, buf.String())
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(buf.String(), unwanted) {
+					t.Errorf("output should not contain %q", unwanted)
+				}
+			}
+		})
+	}
+}
@@ -1,18 +1,27 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"html/template"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
+	"syscall"
 
 	"bytes"
 
+	"ned/texmath"
+
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/cobra"
 	"github.com/yuin/goldmark"
@@ -22,45 +31,24 @@ var viewCmd = &cobra.Command{
 	Use:   "view [note name]",
 	Short: "View a note or the welcome page in the browser",
 	Long: `Renders a note from markdown to HTML and opens it in the default browser.
-If no note name is provided, opens the welcome page showing all available notes.`,
+If no note name is provided, a fuzzy finder lists all notes to pick from.
+Use --welcome, or run without a terminal, to open the welcome page showing
+all available notes instead.`,
 	Aliases: []string{"v"},
 	Args:    cobra.MaximumNArgs(1),
 	RunE:    runView,
 }
 
+var viewWelcome bool
+
 func init() {
+	viewCmd.Flags().BoolVarP(&viewWelcome, "welcome", "w", false, "Open the welcome page instead of picking a note")
 	rootCmd.AddCommand(viewCmd)
 }
 
-// For testing purposes
-var testMode bool
-
-const htmlTemplate = `<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <script src="https://cdn.jsdelivr.net/npm/mermaid/dist/mermaid.min.js"></script>
-    <script>
-        mermaid.initialize({ startOnLoad: true });
-    </script>
-    <style>
-        body {
-            max-width: 800px;
-            margin: 0 auto;
-            padding: 20px;
-            font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, Helvetica, Arial, sans-serif;
-            line-height: 1.6;
-        }
-        img {
-            max-width: 100%%;
-            height: auto;
-        }
-    </style>
-</head>
-<body>
-%s
-</body>
-</html>`
+// markdown is the goldmark pipeline used to render notes. Math spans and
+// blocks are parsed before emphasis so TeX source survives untouched.
+var markdown = goldmark.New(goldmark.WithExtensions(texmath.Math))
 
 func transformImagePaths(content string, notePath string) string {
 	// Regular expression to match markdown image syntax: ![alt](path)
@@ -76,6 +64,11 @@ func transformImagePaths(content string, notePath string) string {
 		alt := parts[1]
 		imgPath := parts[2]
 
+		// Leave remote and other absolute URLs alone
+		if u, err := url.Parse(imgPath); err == nil && len(u.Scheme) > 1 {
+			return match
+		}
+
 		// Convert backslashes to forward slashes
 		imgPath = strings.ReplaceAll(imgPath, "\\", "/")
 
@@ -103,89 +96,207 @@ func transformImagePaths(content string, notePath string) string {
 	})
 }
 
+// archiveLinkRe matches links to archived copies of clipped pages, which
+// point into the ._archive_ directory next to the note
+var archiveLinkRe = regexp.MustCompile(`\]\(` + regexp.QuoteMeta(archiveDirName) + `/([^)/]+)\)`)
+
+// transformArchiveLinks points links to archived copies at the /archive
+// route, which serves the note folder's ._archive_ directory
+func transformArchiveLinks(content string, notePath string) string {
+	relNoteFolder, err := filepath.Rel(notesDir, filepath.Dir(notePath))
+	if err != nil || relNoteFolder == "." {
+		relNoteFolder = ""
+	} else {
+		relNoteFolder = strings.ReplaceAll(relNoteFolder, "\\", "/") + "/"
+	}
+	return archiveLinkRe.ReplaceAllString(content, "](/archive/"+relNoteFolder+"$1)")
+}
+
+// renderNote converts a note's markdown to HTML, rewriting image paths to
+// the /images route and archive links to the /archive route, and turning
+// mermaid code blocks into diagrams.
+func renderNote(content []byte, notePath string) (template.HTML, error) {
+	// Transform content
+	mdContent := transformImagePaths(string(content), notePath)
+	mdContent = transformArchiveLinks(mdContent, notePath)
+
+	// Replace Mermaid code blocks
+	var inMermaid bool
+	lines := strings.Split(mdContent, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "```mermaid" {
+			lines[i] = "<div class=\"mermaid\">"
+			inMermaid = true
+		} else if inMermaid && trimmed == "```" {
+			lines[i] = "</div>"
+			inMermaid = false
+		}
+	}
+	mdContent = strings.Join(lines, "\n")
+
+	// Convert to HTML using goldmark
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(mdContent), &buf); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
+
+// resolveImagePath maps an /images URL path to the image file in the
+// matching ._images_ directory. Root level images are looked up next to the
+// note being viewed first.
+func resolveImagePath(imgPath string, noteName string) (string, bool) {
+	// Remove leading slash and convert backslashes to forward slashes
+	imgPath = strings.ReplaceAll(strings.TrimPrefix(imgPath, "/"), "\\", "/")
+
+	// Split into directory and filename
+	dir, file := filepath.Split(imgPath)
+	// Remove trailing slash
+	dir = strings.TrimRight(dir, "/")
+
+	// Construct the physical path
+	var physicalPath string
+	if dir == "" {
+		// Root level image - if viewing a note, look in its directory first
+		if noteName != "" {
+			noteFolder := filepath.Dir(filepath.Join(notesDir, noteName+".md"))
+			physicalPath = filepath.Join(noteFolder, "._images_", file)
+			if _, err := os.Stat(physicalPath); err == nil {
+				return physicalPath, true
+			}
+		}
+		// Fall back to the root images directory
+		physicalPath = filepath.Join(notesDir, "._images_", file)
+	} else {
+		// Search for image in the specified directory
+		physicalPath = filepath.Join(notesDir, dir, "._images_", file)
+	}
+
+	// Check if file exists
+	if _, err := os.Stat(physicalPath); os.IsNotExist(err) {
+		return "", false
+	}
+	return physicalPath, true
+}
+
+// recentNotesLimit is the number of notes in the welcome page's recently
+// modified section
+const recentNotesLimit = 10
+
+// noteFolder is a directory in the welcome page's folder tree
+type noteFolder struct {
+	Name    string
+	Path    string
+	Folders []*noteFolder
+	Notes   []noteInfo
+}
+
+// buildNoteTree arranges notes into their folders. Folders and notes are
+// sorted by name within each folder.
+func buildNoteTree(notes []noteInfo) *noteFolder {
+	root := &noteFolder{}
+	folders := map[string]*noteFolder{"": root}
+	for _, note := range notes {
+		parent := root
+		parts := strings.Split(note.Name, "/")
+		for i := range parts[:len(parts)-1] {
+			path := strings.Join(parts[:i+1], "/")
+			folder, ok := folders[path]
+			if !ok {
+				folder = &noteFolder{Name: parts[i], Path: path}
+				folders[path] = folder
+				parent.Folders = append(parent.Folders, folder)
+			}
+			parent = folder
+		}
+		parent.Notes = append(parent.Notes, note)
+	}
+
+	for _, folder := range folders {
+		sort.Slice(folder.Folders, func(i, j int) bool { return folder.Folders[i].Name < folder.Folders[j].Name })
+		sort.Slice(folder.Notes, func(i, j int) bool { return folder.Notes[i].Name < folder.Notes[j].Name })
+	}
+	return root
+}
+
+// recentNotes returns up to limit notes, most recently modified first
+func recentNotes(notes []noteInfo, limit int) []noteInfo {
+	recent := append([]noteInfo(nil), notes...)
+	sort.SliceStable(recent, func(i, j int) bool { return recent[i].ModTime.After(recent[j].ModTime) })
+	if len(recent) > limit {
+		recent = recent[:limit]
+	}
+	return recent
+}
+
 func setupServer(noteName string) (*gin.Engine, error) {
+	theme, err := loadTheme()
+	if err != nil {
+		return nil, err
+	}
+
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
 	r.Use(gin.Recovery())
 
 	// Serve welcome page at root
 	r.GET("/", func(c *gin.Context) {
-		// Get all markdown files in notes directory
-		var notes []string
-		err := filepath.Walk(notesDir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if !info.IsDir() && strings.HasSuffix(strings.ToLower(info.Name()), ".md") {
-				// Get relative path from notes directory
-				relPath, err := filepath.Rel(notesDir, path)
-				if err != nil {
-					return err
-				}
-				// Remove .md extension and convert backslashes to forward slashes
-				noteName := strings.ReplaceAll(strings.TrimSuffix(relPath, ".md"), "\\", "/")
-				notes = append(notes, noteName)
-			}
-			return nil
-		})
+		notes, err := scanNotes()
 		if err != nil {
 			c.String(http.StatusInternalServerError, "Failed to list notes")
 			return
 		}
 
-		// Sort notes alphabetically
-		sort.Strings(notes)
-
-		// Create welcome page HTML
-		welcomeHTML := `<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <title>Notes</title>
-    <style>
-        body {
-            max-width: 800px;
-            margin: 0 auto;
-            padding: 20px;
-            font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, Helvetica, Arial, sans-serif;
-            line-height: 1.6;
-        }
-        h1 {
-            border-bottom: 2px solid #eee;
-            padding-bottom: 10px;
-        }
-        ul {
-            list-style-type: none;
-            padding: 0;
-        }
-        li {
-            margin: 10px 0;
-            padding: 10px;
-            background: #f5f5f5;
-            border-radius: 4px;
-        }
-        a {
-            color: #0366d6;
-            text-decoration: none;
-        }
-        a:hover {
-            text-decoration: underline;
-        }
-    </style>
-</head>
-<body>
-    <h1>Notes</h1>
-    <ul>
-`
-		for _, note := range notes {
-			welcomeHTML += fmt.Sprintf("        <li><a href=\"/notes/%s\">%s</a></li>\n", note, note)
+		data := welcomePage{
+			page:   theme.page(),
+			Tree:   buildNoteTree(notes),
+			Recent: recentNotes(notes, recentNotesLimit),
+		}
+
+		var buf bytes.Buffer
+		if err := theme.render(&buf, "welcome.html", data); err != nil {
+			c.String(http.StatusInternalServerError, "Failed to render welcome page")
+			return
+		}
+
+		c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+	})
+
+	// Serve full-text search results
+	r.GET("/search", func(c *gin.Context) {
+		query := c.Query("q")
+		results, err := searchNotes(query)
+		if err != nil {
+			c.String(http.StatusInternalServerError, "Failed to search notes")
+			return
 		}
-		welcomeHTML += `    </ul>
-</body>
-</html>`
 
-		c.Header("Content-Type", "text/html")
-		c.String(http.StatusOK, welcomeHTML)
+		var buf bytes.Buffer
+		data := searchPage{page: theme.page(), Query: query, Results: results}
+		if err := theme.render(&buf, "search.html", data); err != nil {
+			c.String(http.StatusInternalServerError, "Failed to render search results")
+			return
+		}
+
+		c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+	})
+
+	// Serve open tasks collected from all notes
+	r.GET("/tasks", func(c *gin.Context) {
+		tasks, err := openTasks(c.Query("all") != "", c.Query("person"))
+		if err != nil {
+			c.String(http.StatusInternalServerError, "Failed to collect tasks")
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := theme.render(&buf, "tasks.html", tasksPage{page: theme.page(), Tasks: tasks}); err != nil {
+			c.String(http.StatusInternalServerError, "Failed to render tasks")
+			return
+		}
+
+		c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
 	})
 
 	// Serve notes
@@ -201,34 +312,20 @@ func setupServer(noteName string) (*gin.Engine, error) {
 			return
 		}
 
-		// Transform content
-		mdContent := transformImagePaths(string(content), notePath)
-
-		// Replace Mermaid code blocks
-		var inMermaid bool
-		lines := strings.Split(mdContent, "\n")
-		for i, line := range lines {
-			trimmed := strings.TrimSpace(line)
-			if trimmed == "```mermaid" {
-				lines[i] = "<div class=\"mermaid\">"
-				inMermaid = true
-			} else if inMermaid && trimmed == "```" {
-				lines[i] = "</div>"
-				inMermaid = false
-			}
+		body, err := renderNote(content, notePath)
+		if err != nil {
+			c.String(http.StatusInternalServerError, "Failed to convert markdown")
+			return
 		}
-		mdContent = strings.Join(lines, "\n")
 
-		// Convert to HTML using goldmark
 		var buf bytes.Buffer
-		if err := goldmark.Convert([]byte(mdContent), &buf); err != nil {
-			c.String(http.StatusInternalServerError, "Failed to convert markdown")
+		data := notePage{page: theme.page(), Title: strings.TrimPrefix(path, "/"), Content: body, KaTeX: katexBase()}
+		if err := theme.render(&buf, "note.html", data); err != nil {
+			c.String(http.StatusInternalServerError, "Failed to render note")
 			return
 		}
-		finalHTML := fmt.Sprintf(htmlTemplate, buf.String())
 
-		c.Header("Content-Type", "text/html")
-		c.String(http.StatusOK, finalHTML)
+		c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
 	})
 
 	// Serve images from ._images_ directories under the /images path
@@ -239,35 +336,8 @@ func setupServer(noteName string) (*gin.Engine, error) {
 			c.String(http.StatusNotFound, "Image not found")
 			return
 		}
-		// Remove leading slash and convert backslashes to forward slashes
-		imgPath = strings.ReplaceAll(strings.TrimPrefix(imgPath, "/"), "\\", "/")
-
-		// Split into directory and filename
-		dir, file := filepath.Split(imgPath)
-		// Remove trailing slash
-		dir = strings.TrimRight(dir, "/")
-
-		// Construct the physical path
-		var physicalPath string
-		if dir == "" {
-			// Root level image - if viewing a note, look in its directory first
-			if noteName != "" {
-				noteFolder := filepath.Dir(filepath.Join(notesDir, noteName+".md"))
-				physicalPath = filepath.Join(noteFolder, "._images_", file)
-				if _, err := os.Stat(physicalPath); err == nil {
-					c.File(physicalPath)
-					return
-				}
-			}
-			// Fall back to the root images directory
-			physicalPath = filepath.Join(notesDir, "._images_", file)
-		} else {
-			// Search for image in the specified directory
-			physicalPath = filepath.Join(notesDir, dir, "._images_", file)
-		}
-
-		// Check if file exists
-		if _, err := os.Stat(physicalPath); os.IsNotExist(err) {
+		physicalPath, ok := resolveImagePath(imgPath, noteName)
+		if !ok {
 			c.String(http.StatusNotFound, "Image not found")
 			return
 		}
@@ -276,6 +346,34 @@ func setupServer(noteName string) (*gin.Engine, error) {
 		c.File(physicalPath)
 	})
 
+	// Serve archived copies of clipped pages from ._archive_ directories
+	// under the /archive path
+	r.GET("/archive/*path", func(c *gin.Context) {
+		archivePath := strings.TrimPrefix(strings.ReplaceAll(c.Param("path"), "\\", "/"), "/")
+		dir, file := path.Split(archivePath)
+		if file == "" || slices.Contains(strings.Split(archivePath, "/"), "..") {
+			c.String(http.StatusNotFound, "Archived copy not found")
+			return
+		}
+		physicalPath := filepath.Join(notesDir, filepath.FromSlash(dir), archiveDirName, file)
+		if _, err := os.Stat(physicalPath); err != nil {
+			c.String(http.StatusNotFound, "Archived copy not found")
+			return
+		}
+
+		// Archives have their scripts removed; keep any that slipped
+		// through from running next to the notes
+		c.Header("Content-Security-Policy", "script-src 'none'")
+		c.File(physicalPath)
+	})
+
+	// Serve the vendored KaTeX files that typeset math
+	if files := katexFiles(); files != nil {
+		r.GET("/katex/*path", func(c *gin.Context) {
+			c.FileFromFS(c.Param("path"), http.FS(files))
+		})
+	}
+
 	return r, nil
 }
 
@@ -293,6 +391,14 @@ func openBrowser(url string) error {
 }
 
 func runView(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && !viewWelcome && interactive() {
+		names, err := pickNotes("view", false)
+		if err != nil || names == nil {
+			return err
+		}
+		args = names
+	}
+
 	var noteName string
 	if len(args) > 0 {
 		noteName = args[0]
@@ -301,7 +407,7 @@ func runView(cmd *cobra.Command, args []string) error {
 		// Check if note exists when a specific note is requested
 		notePath := filepath.Join(notesDir, noteName+".md")
 		if _, err := os.Stat(notePath); os.IsNotExist(err) {
-			return fmt.Errorf("note '%s' not found", noteName)
+			return newCmdError(codeNotFound, "note '%s' not found", noteName)
 		}
 	}
 
@@ -311,29 +417,38 @@ func runView(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to setup server: %w", err)
 	}
 
-	if !testMode {
-		// Start server in a goroutine
-		go func() {
-			if err := r.Run(":3000"); err != nil {
-				fmt.Printf("Server error: %v\n", err)
-			}
-		}()
+	// Open browser to either welcome page or specific note
+	url := "http://localhost:3000"
+	if noteName != "" {
+		url = fmt.Sprintf("http://localhost:3000/notes/%s", noteName)
+	}
+	return serveView(r, url, noteName)
+}
 
-		// Open browser to either welcome page or specific note
-		url := "http://localhost:3000"
-		if noteName != "" {
-			url = fmt.Sprintf("http://localhost:3000/notes/%s", noteName)
-		}
-		if err := openBrowser(url); err != nil {
-			return fmt.Errorf("failed to open browser: %w", err)
+// serveView starts the server, opens url in the browser and blocks until
+// the user stops the server. Tests replace it to keep the server down.
+var serveView = func(r *gin.Engine, url string, noteName string) error {
+	go func() {
+		if err := r.Run(":3000"); err != nil {
+			fmt.Printf("Server error: %v\n", err)
 		}
+	}()
 
-		fmt.Printf("Viewing note: %s\nPress Enter to stop the server...\n", noteName)
-		fmt.Scanln()
-	} else {
-		// In test mode, just return without starting the server
+	if err := openBrowser(url); err != nil {
+		return fmt.Errorf("failed to open browser: %w", err)
+	}
+
+	// Without a terminal there is no Enter to wait for, so serve until
+	// interrupted
+	if !interactive() {
+		fmt.Printf("Viewing note: %s\nPress Ctrl+C to stop the server...\n", noteName)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		<-ctx.Done()
 		return nil
 	}
 
+	fmt.Printf("Viewing note: %s\nPress Enter to stop the server...\n", noteName)
+	fmt.Scanln()
 	return nil
 }
@@ -9,12 +9,11 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestWelcomePage(t *testing.T) {
-	// Set test mode to prevent browser from opening
-	testMode = true
-	defer func() { testMode = false }()
+	stubOpeners(t)
 
 	tmpDir, cleanup := setupTestEnv(t)
 	defer cleanup()
@@ -87,12 +86,96 @@ func TestWelcomePage(t *testing.T) {
 			t.Errorf("Expected welcome page to contain note name %s", note)
 		}
 	}
+
+	// Check titles, folders and the search box
+	expectedHTML := []string{
+		">Note 1</a>",
+		"<summary>folder</summary>",
+		"<summary>subfolder</summary>",
+		"Recently modified",
+		`action="/search"`,
+	}
+	for _, want := range expectedHTML {
+		if !strings.Contains(htmlContent, want) {
+			t.Errorf("Expected welcome page to contain %q", want)
+		}
+	}
+}
+
+func TestWelcomePageEscaping(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	name := "<i>italic.md"
+	if err := os.WriteFile(filepath.Join(tmpDir, name), []byte("# <script>alert(1)</script>\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test note: %v", err)
+	}
+
+	r, err := setupServer("")
+	if err != nil {
+		t.Fatalf("Failed to setup server: %v", err)
+	}
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatalf("Failed to get welcome page: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	htmlContent := string(body)
+
+	for _, unwanted := range []string{"<i>italic", "<script>alert(1)</script>"} {
+		if strings.Contains(htmlContent, unwanted) {
+			t.Errorf("Expected %q to be escaped", unwanted)
+		}
+	}
+	if !strings.Contains(htmlContent, "&lt;script&gt;alert(1)&lt;/script&gt;") {
+		t.Error("Expected escaped note title")
+	}
+}
+
+func TestSearchPage(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "graphs.md"), []byte("# Graphs\n\nDijkstra finds shortest paths.\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test note: %v", err)
+	}
+
+	r, err := setupServer("")
+	if err != nil {
+		t.Fatalf("Failed to setup server: %v", err)
+	}
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/search?q=dijkstra")
+	if err != nil {
+		t.Fatalf("Failed to get search page: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	htmlContent := string(body)
+
+	for _, want := range []string{`href="/notes/graphs"`, "Dijkstra finds shortest paths.", `value="dijkstra"`} {
+		if !strings.Contains(htmlContent, want) {
+			t.Errorf("Expected search page to contain %q", want)
+		}
+	}
 }
 
 func TestViewCmd(t *testing.T) {
-	// Set test mode to prevent browser from opening
-	testMode = true
-	defer func() { testMode = false }()
+	stubOpeners(t)
 
 	tmpDir, cleanup := setupTestEnv(t)
 	defer cleanup()
@@ -222,6 +305,66 @@ This is a test note with images:
 	}
 }
 
+func TestViewArchive(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	archiveDir := filepath.Join(tmpDir, "reading", "._archive_")
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(archiveDir, "post.html"), []byte("<h1>Archived</h1>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	note := "# Post\n\nSource: [https://example.com/post](https://example.com/post) ([archived copy](._archive_/post.html))\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "reading", "post.md"), []byte(note), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "secret.html"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := setupServer("")
+	if err != nil {
+		t.Fatalf("Failed to setup server: %v", err)
+	}
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	get := func(path string) (*http.Response, string) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, string(body)
+	}
+
+	_, page := get("/notes/reading/post")
+	if !strings.Contains(page, `<a href="/archive/reading/post.html">archived copy</a>`) {
+		t.Errorf("note does not link to the archive route:\n%s", page)
+	}
+
+	resp, body := get("/archive/reading/post.html")
+	if resp.StatusCode != http.StatusOK || body != "<h1>Archived</h1>" {
+		t.Errorf("archive: got %v %q", resp.Status, body)
+	}
+	if csp := resp.Header.Get("Content-Security-Policy"); csp != "script-src 'none'" {
+		t.Errorf("archive is served without blocking scripts: %q", csp)
+	}
+
+	for _, path := range []string{"/archive/reading/missing.html", "/archive/reading/", "/archive/reading/..%2F..%2Fsecret.html"} {
+		if resp, _ := get(path); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: got %v, want 404", path, resp.Status)
+		}
+	}
+}
+
 func TestTransformImagePaths(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -259,6 +402,12 @@ func TestTransformImagePaths(t *testing.T) {
 			notePath: filepath.Join(notesDir, "folder1/note.md"),
 			expected: "![One](/images/folder1/test1.jpg)\n![Two](/images/folder/test2.png)",
 		},
+		{
+			name:     "remote image",
+			content:  "![Alt](https://example.com/img/test.png)",
+			notePath: filepath.Join(notesDir, "folder1/note.md"),
+			expected: "![Alt](https://example.com/img/test.png)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -270,3 +419,116 @@ func TestTransformImagePaths(t *testing.T) {
 		})
 	}
 }
+
+func TestViewMath(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	noteContent := "# Algorithms\n\nCost is $O(n_1 * n_2)$.\n\n$$\n\\sum_{i=1}^n a_i\n$$\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "algo.md"), []byte(noteContent), 0644); err != nil {
+		t.Fatalf("failed to create note file: %v", err)
+	}
+
+	r, err := setupServer("algo")
+	if err != nil {
+		t.Fatalf("Failed to setup server: %v", err)
+	}
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/notes/algo")
+	if err != nil {
+		t.Fatalf("Failed to get note: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	htmlContent := string(body)
+
+	expected := []string{
+		`<span class="math inline">O(n_1 * n_2)</span>`,
+		`<div class="math display">\sum_{i=1}^n a_i`,
+		"katex.min.js",
+	}
+	for _, want := range expected {
+		if !strings.Contains(htmlContent, want) {
+			t.Errorf("Expected HTML to contain %q", want)
+		}
+	}
+}
+
+func TestViewKaTeX(t *testing.T) {
+	tmpDir, cleanup := setupTestEnv(t)
+	defer cleanup()
+	if err := os.WriteFile(filepath.Join(tmpDir, "algo.md"), []byte("$x^2$\n"), 0644); err != nil {
+		t.Fatalf("failed to create note file: %v", err)
+	}
+
+	get := func(ts *httptest.Server, path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		return resp.StatusCode, string(body)
+	}
+	serve := func() *httptest.Server {
+		t.Helper()
+		r, err := setupServer("algo")
+		if err != nil {
+			t.Fatalf("Failed to setup server: %v", err)
+		}
+		ts := httptest.NewServer(r)
+		t.Cleanup(ts.Close)
+		return ts
+	}
+
+	oldAssets := katexAssets
+	defer func() { katexAssets = oldAssets }()
+
+	t.Run("vendored", func(t *testing.T) {
+		katexAssets = fstest.MapFS{
+			"katex.min.js":                   {Data: []byte("/* katex */")},
+			"katex.min.css":                  {Data: []byte(".katex{}")},
+			"fonts/KaTeX_Main-Regular.woff2": {Data: []byte("font")},
+		}
+		ts := serve()
+		_, page := get(ts, "/notes/algo")
+		for _, want := range []string{`href="/katex/katex.min.css"`, `src="/katex/katex.min.js"`} {
+			if !strings.Contains(page, want) {
+				t.Errorf("note page does not contain %q", want)
+			}
+		}
+		if strings.Contains(page, "cdn.jsdelivr.net/npm/katex") {
+			t.Error("note page loads KaTeX from the CDN")
+		}
+		for path, want := range map[string]string{"/katex/katex.min.js": "/* katex */", "/katex/fonts/KaTeX_Main-Regular.woff2": "font"} {
+			if status, body := get(ts, path); status != http.StatusOK || body != want {
+				t.Errorf("GET %s = %d %q, want %q", path, status, body, want)
+			}
+		}
+		if status, _ := get(ts, "/katex/missing.js"); status != http.StatusNotFound {
+			t.Errorf("GET /katex/missing.js = %d, want 404", status)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		katexAssets = fstest.MapFS{}
+		ts := serve()
+		_, page := get(ts, "/notes/algo")
+		if !strings.Contains(page, `src="https://cdn.jsdelivr.net/npm/katex@`+katexVersion+`/dist/katex.min.js"`) {
+			t.Error("note page does not fall back to the CDN")
+		}
+		if status, _ := get(ts, "/katex/katex.min.js"); status != http.StatusNotFound {
+			t.Errorf("GET /katex/katex.min.js = %d, want 404", status)
+		}
+	})
+}
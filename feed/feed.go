@@ -0,0 +1,262 @@
+// Package feed reads RSS and Atom feeds: RSS 2.0 and the older 0.9x
+// versions, RSS 1.0 (RDF) and Atom 1.0.
+//
+// Only what is needed to follow a feed is kept: its title and the title,
+// link, date and a stable ID of each item.
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// ErrNotFeed is returned by Parse for documents that are not RSS or Atom
+var ErrNotFeed = errors.New("not an RSS or Atom feed")
+
+// Feed is a parsed RSS or Atom feed
+type Feed struct {
+	Title string
+	// Link is the website the feed belongs to
+	Link  string
+	Items []Item
+}
+
+// Item is an entry of a feed
+type Item struct {
+	// ID identifies the item from one fetch of the feed to the next: its
+	// guid or Atom id, or else its link
+	ID    string
+	Title string
+	// Link is the page of the item, made absolute when Parse is given the
+	// URL of the feed
+	Link string
+	// Published is nil when the feed does not date the item
+	Published *time.Time
+}
+
+// link is an RSS link, which has the URL as text, or an Atom link, which
+// has it in href. RSS feeds often contain Atom links to themselves too.
+type link struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+	Text string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title   string `xml:"title"`
+	Links   []link `xml:"link"`
+	GUID    string `xml:"guid"`
+	About   string `xml:"about,attr"`
+	PubDate string `xml:"pubDate"`
+	Date    string `xml:"date"`
+}
+
+type rssChannel struct {
+	Title string    `xml:"title"`
+	Links []link    `xml:"link"`
+	Items []rssItem `xml:"item"`
+}
+
+type rss struct {
+	Channel rssChannel `xml:"channel"`
+	// RSS 1.0 puts the items next to the channel
+	Items []rssItem `xml:"item"`
+}
+
+type atomEntry struct {
+	ID        string `xml:"id"`
+	Title     string `xml:"title"`
+	Links     []link `xml:"link"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+}
+
+type atom struct {
+	Title   string      `xml:"title"`
+	Links   []link      `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// Parse reads an RSS or Atom feed. Relative links are resolved against
+// base, the URL the feed was downloaded from; it may be "".
+func Parse(data []byte, base string) (*Feed, error) {
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil || base == "" {
+		baseURL = nil
+	}
+
+	var feed *Feed
+	switch root {
+	case "rss", "RDF":
+		var doc rss
+		if err := decode(data, &doc); err != nil {
+			return nil, err
+		}
+		feed = doc.feed(baseURL)
+	case "feed":
+		var doc atom
+		if err := decode(data, &doc); err != nil {
+			return nil, err
+		}
+		feed = doc.feed(baseURL)
+	default:
+		return nil, ErrNotFeed
+	}
+	return feed, nil
+}
+
+// rootElement returns the local name of the document element
+func rootElement(data []byte) (string, error) {
+	d := newDecoder(data)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return "", ErrNotFeed
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+func newDecoder(data []byte) *xml.Decoder {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	// Feeds are often not quite well-formed: HTML entities and stray
+	// ampersands are common
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = charset.NewReaderLabel
+	return d
+}
+
+func decode(data []byte, v any) error {
+	if err := newDecoder(data).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse feed: %w", err)
+	}
+	return nil
+}
+
+func (doc *rss) feed(base *url.URL) *Feed {
+	feed := &Feed{
+		Title: clean(doc.Channel.Title),
+		Link:  resolve(base, pageLink(doc.Channel.Links)),
+	}
+	for _, it := range append(doc.Channel.Items, doc.Items...) {
+		item := Item{
+			Title:     clean(it.Title),
+			Link:      resolve(base, firstOf(pageLink(it.Links), it.About)),
+			Published: parseDate(it.PubDate, it.Date),
+		}
+		item.ID = firstOf(it.GUID, it.About, item.Link)
+		if item.ID == "" && item.Title != "" {
+			item.ID = item.Title + " " + strings.TrimSpace(it.PubDate)
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed
+}
+
+func (doc *atom) feed(base *url.URL) *Feed {
+	feed := &Feed{
+		Title: clean(doc.Title),
+		Link:  resolve(base, pageLink(doc.Links)),
+	}
+	for _, entry := range doc.Entries {
+		item := Item{
+			Title:     clean(entry.Title),
+			Link:      resolve(base, pageLink(entry.Links)),
+			Published: parseDate(entry.Published, entry.Updated),
+		}
+		item.ID = firstOf(entry.ID, item.Link)
+		feed.Items = append(feed.Items, item)
+	}
+	return feed
+}
+
+// pageLink picks the link to a web page: the text of an RSS link, or the
+// alternate Atom link
+func pageLink(links []link) string {
+	for _, l := range links {
+		if text := strings.TrimSpace(l.Text); text != "" && l.Href == "" {
+			return text
+		}
+	}
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			if href := strings.TrimSpace(l.Href); href != "" {
+				return href
+			}
+		}
+	}
+	return ""
+}
+
+// resolve makes ref absolute, when there is a base to resolve it against
+func resolve(base *url.URL, ref string) string {
+	if base == nil || ref == "" {
+		return ref
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// clean collapses the whitespace of titles, which feeds often wrap
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// dateLayouts are the date formats found in feeds: RFC 822 dates in RSS,
+// with and without weekday and seconds, and RFC 3339 in Atom and Dublin
+// Core
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseDate returns the first of dates that parses, or nil
+func parseDate(dates ...string) *time.Time {
+	for _, date := range dates {
+		date = strings.TrimSpace(date)
+		if date == "" {
+			continue
+		}
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, date); err == nil {
+				return &t
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package feed
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func parseFile(t *testing.T, name, base string) *Feed {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := Parse(data, base)
+	if err != nil {
+		t.Fatalf("Parse(%s): %v", name, err)
+	}
+	return feed
+}
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func checkItems(t *testing.T, got []Item, want []Item) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d items, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.ID != w.ID || g.Title != w.Title || g.Link != w.Link {
+			t.Errorf("item %d = %q %q %q, want %q %q %q", i, g.ID, g.Title, g.Link, w.ID, w.Title, w.Link)
+		}
+		switch {
+		case w.Published == nil && g.Published != nil:
+			t.Errorf("item %d published %v, want no date", i, g.Published)
+		case w.Published != nil && (g.Published == nil || !g.Published.Equal(*w.Published)):
+			t.Errorf("item %d published %v, want %v", i, g.Published, w.Published)
+		}
+	}
+}
+
+func TestParseRSS(t *testing.T) {
+	feed := parseFile(t, "rss.xml", "https://go.dev/blog/feed.xml")
+	if feed.Title != "The Go Blog" || feed.Link != "https://go.dev/blog/" {
+		t.Errorf("feed = %q %q", feed.Title, feed.Link)
+	}
+	published := date("2024-08-20T09:00:00Z")
+	released := date("2024-08-13T17:00:00Z")
+	checkItems(t, feed.Items, []Item{
+		{ID: "tag:go.dev,2024:range-functions", Title: "Range over function types", Link: "https://go.dev/blog/range-functions", Published: &published},
+		{ID: "https://go.dev/blog/go1.23", Title: "Go 1.23 & beyond", Link: "https://go.dev/blog/go1.23", Published: &released},
+		{ID: "Untitled note — no link not a date", Title: "Untitled note — no link"},
+	})
+}
+
+func TestParseAtom(t *testing.T) {
+	feed := parseFile(t, "atom.xml", "https://example.com/feed.atom")
+	if feed.Title != "Example Engineering" || feed.Link != "https://example.com/" {
+		t.Errorf("feed = %q %q", feed.Title, feed.Link)
+	}
+	published := date("2026-10-12T06:00:00Z")
+	updated := date("2026-10-01T12:00:00Z")
+	checkItems(t, feed.Items, []Item{
+		{ID: "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a", Title: "Scaling the build farm", Link: "https://example.com/posts/build-farm", Published: &published},
+		{ID: "https://example.com/posts/postmortem", Title: "Postmortem", Link: "https://example.com/posts/postmortem", Published: &updated},
+	})
+}
+
+func TestParseRDF(t *testing.T) {
+	feed := parseFile(t, "rdf.xml", "")
+	if feed.Title != "Café News" {
+		t.Errorf("Title = %q, want it decoded from ISO-8859-1", feed.Title)
+	}
+	published := date("2026-10-15T10:00:00Z")
+	checkItems(t, feed.Items, []Item{
+		{ID: "https://news.example.org/2026/menu", Title: "New menu at the café", Link: "https://news.example.org/2026/menu", Published: &published},
+	})
+}
+
+func TestParseNotFeed(t *testing.T) {
+	for _, data := range []string{
+		"<!DOCTYPE html><html><head><title>Blog</title></head><body></body></html>",
+		"{\"items\": []}",
+		"",
+	} {
+		if _, err := Parse([]byte(data), ""); !errors.Is(err, ErrNotFeed) {
+			t.Errorf("Parse(%q) = %v, want ErrNotFeed", data, err)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Package texmath is a goldmark extension that recognizes LaTeX math written
+// as $inline$ or $$display$$ and renders it as elements KaTeX can typeset.
+//
+// The math source is kept verbatim, so underscores, asterisks and other
+// markdown punctuation inside a formula are never treated as emphasis.
+package texmath
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindInlineMath is the NodeKind of InlineMath nodes
+var KindInlineMath = ast.NewNodeKind("InlineMath")
+
+// KindMathBlock is the NodeKind of MathBlock nodes
+var KindMathBlock = ast.NewNodeKind("MathBlock")
+
+// InlineMath is a math span such as $x^2$. Display is true for spans written
+// with double dollars inside a paragraph.
+type InlineMath struct {
+	ast.BaseInline
+	Literal []byte
+	Display bool
+}
+
+// Kind implements ast.Node.Kind
+func (n *InlineMath) Kind() ast.NodeKind {
+	return KindInlineMath
+}
+
+// Dump implements ast.Node.Dump
+func (n *InlineMath) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Literal": string(n.Literal)}, nil)
+}
+
+// MathBlock is a display formula between lines of $$, or a line that is a
+// whole $$...$$ formula.
+type MathBlock struct {
+	ast.BaseBlock
+	closed bool
+}
+
+// Kind implements ast.Node.Kind
+func (n *MathBlock) Kind() ast.NodeKind {
+	return KindMathBlock
+}
+
+// IsRaw implements ast.Node.IsRaw
+func (n *MathBlock) IsRaw() bool {
+	return true
+}
+
+// Dump implements ast.Node.Dump
+func (n *MathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+type inlineParser struct{}
+
+func (p *inlineParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Parse follows the pandoc rules for inline math: the opening $ must not be
+// followed by a space, the closing $ must not be preceded by a space or
+// followed by a digit. This keeps prices such as "$5 and $10" as plain text.
+func (p *inlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	opener := 0
+	for ; opener < len(line) && line[opener] == '$'; opener++ {
+	}
+	if opener > 2 {
+		return nil
+	}
+	start := opener
+	if start >= len(line) || util.IsSpace(line[start]) {
+		return nil
+	}
+
+	for i := start; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '$':
+			closer := 0
+			for ; i+closer < len(line) && line[i+closer] == '$'; closer++ {
+			}
+			if closer != opener {
+				i += closer - 1
+				continue
+			}
+			if util.IsSpace(line[i-1]) {
+				return nil
+			}
+			if end := i + closer; opener == 1 && end < len(line) && line[end] >= '0' && line[end] <= '9' {
+				return nil
+			}
+			node := &InlineMath{
+				Literal: append([]byte(nil), line[start:i]...),
+				Display: opener == 2,
+			}
+			block.Advance(i + closer)
+			return node
+		}
+	}
+	return nil
+}
+
+type blockParser struct{}
+
+func (p *blockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (p *blockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || pos+1 >= len(line) || line[pos] != '$' || line[pos+1] != '$' {
+		return nil, parser.NoChildren
+	}
+
+	node := &MathBlock{}
+	rest := util.TrimRightSpace(line[pos+2:])
+	if len(rest) == 0 {
+		return node, parser.NoChildren
+	}
+
+	// A formula written on a single line: $$ E = mc^2 $$. Other lines that
+	// start with $$, such as "$$a$$ is the area", are left to the inline
+	// parser.
+	if len(rest) < 2 || !bytes.HasSuffix(rest, []byte("$$")) || bytes.Contains(rest[:len(rest)-2], []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+	start := segment.Start + pos + 2
+	node.Lines().Append(text.NewSegment(start, start+len(rest)-2))
+	node.closed = true
+	return node, parser.NoChildren
+}
+
+func (p *blockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	if node.(*MathBlock).closed {
+		return parser.Close
+	}
+
+	line, segment := reader.PeekLine()
+	if line == nil {
+		return parser.Close
+	}
+	body := util.TrimRightSpace(line)
+	if len(body) >= 2 && body[len(body)-2] == '$' && body[len(body)-1] == '$' {
+		// Content written before the closing delimiter belongs to the formula
+		if content := body[:len(body)-2]; !util.IsBlank(content) {
+			node.Lines().Append(text.NewSegment(segment.Start, segment.Start+len(content)))
+		}
+		reader.Advance(segment.Len() - newlineLength(line))
+		return parser.Close
+	}
+
+	node.Lines().Append(segment)
+	reader.Advance(segment.Len() - newlineLength(line))
+	return parser.Continue | parser.NoChildren
+}
+
+func newlineLength(line []byte) int {
+	if len(line) > 0 && line[len(line)-1] == '\n' {
+		return 1
+	}
+	return 0
+}
+
+func (p *blockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *blockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *blockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// HTMLRenderer renders math nodes as elements carrying the escaped TeX
+// source. The page is expected to hand them to KaTeX on load.
+type HTMLRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs
+func (r *HTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindInlineMath, r.renderInlineMath)
+	reg.Register(KindMathBlock, r.renderMathBlock)
+}
+
+func (r *HTMLRenderer) renderInlineMath(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*InlineMath)
+	if n.Display {
+		_, _ = w.WriteString(`<span class="math display">`)
+	} else {
+		_, _ = w.WriteString(`<span class="math inline">`)
+	}
+	html.DefaultWriter.RawWrite(w, n.Literal)
+	_, _ = w.WriteString("</span>")
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *HTMLRenderer) renderMathBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	_, _ = w.WriteString(`<div class="math display">`)
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		html.DefaultWriter.RawWrite(w, seg.Value(source))
+	}
+	_, _ = w.WriteString("</div>\n")
+	return ast.WalkSkipChildren, nil
+}
+
+type extension struct{}
+
+// Math is the goldmark extension that enables $...$ and $$...$$ math.
+var Math = &extension{}
+
+// Extend implements goldmark.Extender
+func (e *extension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&blockParser{}, 150)),
+		parser.WithInlineParsers(util.Prioritized(&inlineParser{}, 150)),
+	)
+	m.Renderer().AddOptions(
+		renderer.WithNodeRenderers(util.Prioritized(&HTMLRenderer{}, 500)),
+	)
+}
@@ -0,0 +1,95 @@
+package texmath
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+)
+
+func TestMath(t *testing.T) {
+	md := goldmark.New(goldmark.WithExtensions(Math))
+
+	tests := []struct {
+		name     string
+		input    string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "inline math keeps underscores and asterisks",
+			input:    "The sum $a_i * b_i * c_i$ is cheap.",
+			contains: []string{`<span class="math inline">a_i * b_i * c_i</span>`},
+			excludes: []string{"<em>"},
+		},
+		{
+			name:     "display block",
+			input:    "Before\n\n$$\n\\sum_{i=1}^n x_i\n$$\n\nAfter",
+			contains: []string{`<div class="math display">\sum_{i=1}^n x_i` + "\n</div>", "<p>After</p>"},
+		},
+		{
+			name:     "single line display block",
+			input:    "$$E = mc^2$$",
+			contains: []string{`<div class="math display">E = mc^2</div>`},
+		},
+		{
+			name:     "display span starting a paragraph",
+			input:    "$$a$$ is the area\n\nNext paragraph",
+			contains: []string{`<p><span class="math display">a</span> is the area</p>`, "<p>Next paragraph</p>"},
+			excludes: []string{"<div"},
+		},
+		{
+			name:     "display span inside paragraph",
+			input:    "where $$x_1$$ holds",
+			contains: []string{`<span class="math display">x_1</span>`},
+		},
+		{
+			name:     "prices are not math",
+			input:    "It costs $5 and $10.",
+			contains: []string{"It costs $5 and $10."},
+			excludes: []string{"math"},
+		},
+		{
+			name:     "space after opener is not math",
+			input:    "$ x$",
+			excludes: []string{"math"},
+		},
+		{
+			name:     "math source is escaped",
+			input:    "$a<b$",
+			contains: []string{`<span class="math inline">a&lt;b</span>`},
+		},
+		{
+			name:     "escaped dollar",
+			input:    `\$x$`,
+			excludes: []string{"math"},
+		},
+		{
+			name:     "code span wins over math",
+			input:    "`$x_1$`",
+			contains: []string{"<code>$x_1$</code>"},
+			excludes: []string{"math"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := md.Convert([]byte(tt.input), &buf); err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+			got := buf.String()
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("output missing %q\ngot: %q", want, got)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(got, unwanted) {
+					t.Errorf("output should not contain %q\ngot: %q", unwanted, got)
+				}
+			}
+		})
+	}
+}